@@ -0,0 +1,94 @@
+package dotmatrix
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// aheadRenderer redraws frames in the background, ahead of when they're needed.
+// Frames may build on one another, as gif frames do, and filters may keep state
+// between frames, as MotionFilter does, so one goroutine gets and filters frames
+// in order, and a pool of workers dithers them concurrently.
+type aheadRenderer struct {
+	frames int
+	source func(i int) image.Image // called in order, from one goroutine at a time
+	c      *Config
+	n      int // how many frames to keep ready
+
+	queue  chan *aheadJob // in frame order
+	next   int            // the frame expected from the head of queue
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type aheadJob struct {
+	img    image.Image // filtered
+	offset image.Point
+	done   chan *image.Paletted
+}
+
+func newAheadRenderer(frames int, source func(i int) image.Image, c *Config, n int) *aheadRenderer {
+	return &aheadRenderer{frames: frames, source: source, c: c, n: n}
+}
+
+// redraw returns frame i redrawn. Frames are rendered ahead in order, looping back
+// to the first after the last, so asking for any other frame restarts the
+// pipeline from it.
+func (r *aheadRenderer) redraw(i int) *image.Paletted {
+	if r.queue == nil || i != r.next {
+		r.Close()
+		r.start(i)
+	}
+	job := <-r.queue
+	r.next = (i + 1) % r.frames
+	return <-job.done
+}
+
+func (r *aheadRenderer) start(from int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.queue = make(chan *aheadJob, r.n)
+	work := make(chan *aheadJob, r.n)
+
+	workers := runtime.NumCPU()
+	if workers > r.n {
+		workers = r.n
+	}
+	r.wg.Add(workers + 1)
+	for j := 0; j < workers; j++ {
+		go func() {
+			defer r.wg.Done()
+			for job := range work {
+				job.done <- ditherInto(nil, job.img, job.offset, r.c)
+			}
+		}()
+	}
+	go func() {
+		defer r.wg.Done()
+		defer close(work)
+		for i := from; ; i = (i + 1) % r.frames {
+			img, offset := filterFrame(r.source(i), r.c)
+			job := &aheadJob{img: img, offset: offset, done: make(chan *image.Paletted, 1)}
+			// The queue is the bound on how far ahead frames are rendered, so the
+			// job is only handed to the workers once it has a place in line.
+			select {
+			case r.queue <- job:
+			case <-ctx.Done():
+				return
+			}
+			work <- job
+		}
+	}()
+}
+
+// Close stops rendering ahead, and waits for the goroutines to exit.
+func (r *aheadRenderer) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+		r.wg.Wait()
+		r.cancel, r.queue = nil, nil
+	}
+	return nil
+}
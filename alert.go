@@ -0,0 +1,129 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/draw"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// AlertOptions configures Alert. Zero values select sensible defaults.
+type AlertOptions struct {
+	// MaxCols and MaxRows cap the size of the image in braille cells, not counting
+	// the border or caption. The default is 40x10.
+	MaxCols, MaxRows int
+	// Caption is drawn beneath the image, inside the border, in the small font of
+	// CaptionFilter. Long captions are wrapped to MaxCols.
+	Caption string
+	// Config is used to render the image. If nil, the default config is used. Its
+	// ByteBudget, if set, caps the size of the whole block, border and all.
+	Config *Config
+}
+
+/*
+Alert prints img as a bordered block with title in the top border and an optional
+caption beneath the image. The image is shrunk to fit within the block's size cap,
+making the output suitable for dropping into logs or chat messages. If the config
+has an AltText hook, the image's description is added to the caption.
+
+The block is printed by a BorderFlusher around the config's flusher, and the
+caption is drawn with a CaptionFilter, so the block is printed, and held to the
+config's ByteBudget, like any other image.
+*/
+func Alert(w io.Writer, title string, img image.Image, opts *AlertOptions) error {
+	if opts == nil {
+		opts = &AlertOptions{}
+	}
+	maxCols, maxRows := opts.MaxCols, opts.MaxRows
+	if maxCols <= 0 {
+		maxCols = 40
+	}
+	if maxRows <= 0 {
+		maxRows = 10
+	}
+
+	c := mergeConfig(opts.Config)
+	caption := opts.Caption
+	if c.AltText != nil {
+		alt, err := c.AltText(img)
+		if err != nil {
+			return err
+		}
+		if alt != "" {
+			caption = strings.TrimPrefix(caption+"\nAlt text: "+alt, "\n")
+		}
+		// The description belongs in the caption, not beneath the art.
+		c.AltText = nil
+	}
+	c.Filter = filters{
+		c.Filter,
+		FitFilter{Cols: maxCols, Rows: maxRows, Scaler: c.Scaler},
+		alertFilter{title: title, caption: caption, cols: maxCols},
+	}
+	c.Flusher = BorderFlusher{Flusher: c.Flusher, Title: title}
+	return NewPrinter(w, &c).Print(img)
+}
+
+// alertFilter lays fitted images out in an alert's block: widened to fit the title
+// in the border, with the caption wrapped to cols cells beneath.
+type alertFilter struct {
+	title, caption string
+	cols           int
+}
+
+func (f alertFilter) Filter(img image.Image) image.Image {
+	bounds := img.Bounds()
+	// Leave room for at least "─ title ─" in the top border.
+	size := image.Pt(bounds.Dx(), bounds.Dy())
+	if w := 2 * (utf8.RuneCountInString(f.title) + 4); w > size.X {
+		size.X = w
+	}
+	var caption string
+	if f.caption != "" {
+		perLine := (2*f.cols - 2*textPad) / basicfont.Face7x13.Advance
+		caption = strings.Join(wrap(f.caption, perLine), "\n")
+		box := textBox(caption)
+		if box.Dx() > size.X {
+			size.X = box.Dx()
+		}
+		size.Y += box.Dy()
+	}
+	if size == bounds.Size() {
+		return img
+	}
+
+	// The image is centered over the caption, with blanks around it, on a cell
+	// boundary so that its columns aren't split between cells.
+	dst := image.NewNRGBA(image.Rectangle{Max: size})
+	at := image.Pt((size.X-bounds.Dx())/4*2, 0)
+	draw.Draw(dst, bounds.Sub(bounds.Min).Add(at), img, bounds.Min, draw.Src)
+	return CaptionFilter{Text: caption, Corner: BottomLeft}.Filter(dst)
+}
+
+// wrap breaks s into lines no longer than width runes, preferring to break on spaces.
+func wrap(s string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		var line []rune
+		for _, word := range strings.Fields(para) {
+			r := []rune(word)
+			if len(line) > 0 && len(line)+1+len(r) > width {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, r...)
+			for len(line) > width {
+				lines = append(lines, string(line[:width]))
+				line = line[width:]
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
@@ -0,0 +1,46 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Alert", func() {
+	It("should widen the block to fit its title", func() {
+		var out bytes.Buffer
+		img := image.NewPaletted(image.Rect(0, 0, 4, 8), color.Palette{color.Black})
+		Expect(dotmatrix.Alert(&out, "title", img, nil)).To(Succeed())
+		Expect(out.String()).To(Equal("" +
+			"┌─ title ─┐\n" +
+			"│⠀⠀⠀⣿⣿⠀⠀⠀⠀│\n" +
+			"│⠀⠀⠀⣿⣿⠀⠀⠀⠀│\n" +
+			"└─────────┘\n"))
+	})
+
+	It("should draw the caption beneath the image and keep to the byte budget", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 200, 200), color.Palette{color.Black})
+		opts := &dotmatrix.AlertOptions{MaxCols: 20, MaxRows: 5, Caption: "91% full"}
+
+		var out bytes.Buffer
+		Expect(dotmatrix.Alert(&out, "disk", img, opts)).To(Succeed())
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		Expect(lines[0]).To(HavePrefix("┌─ disk ─"))
+		// The caption adds a line of 13 pixel tall text beneath the 5 rows of image.
+		Expect(len(lines)).To(BeNumerically(">", 5+2))
+
+		budget := out.Len() / 2
+		opts.Config = &dotmatrix.Config{ByteBudget: budget}
+		out.Reset()
+		Expect(dotmatrix.Alert(&out, "disk", img, opts)).To(Succeed())
+		Expect(out.Len()).To(BeNumerically(">", 0))
+		Expect(out.Len()).To(BeNumerically("<=", budget))
+		Expect(out.String()).To(HavePrefix("┌─ disk ─"))
+	})
+})
@@ -0,0 +1,105 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// AlphaMode enumerates the ways an AlphaPolicy can treat transparency.
+type AlphaMode int
+
+const (
+	// AlphaTransparent maps transparent pixels to the transparent palette entry,
+	// which prints as empty dots. This is the default.
+	AlphaTransparent AlphaMode = iota
+	// AlphaMatte composites pixels over the policy's Matte color.
+	AlphaMatte
+	// AlphaThreshold makes pixels fully opaque if their alpha is at least the
+	// policy's Threshold, and fully transparent otherwise.
+	AlphaThreshold
+)
+
+// AlphaPolicy decides how transparent and translucent pixels are rendered. It is
+// applied after filtering and before the image is drawn in the dotmatrix palette.
+type AlphaPolicy struct {
+	Mode AlphaMode
+	// Matte is the color composited under the image in AlphaMatte mode. If nil,
+	// white is used.
+	Matte color.Color
+	// Threshold is the cutoff alpha in AlphaThreshold mode.
+	Threshold uint8
+}
+
+// MatteAlpha returns a policy that composites images over c. Treating transparency
+// as white or black is a matter of passing color.White or color.Black.
+func MatteAlpha(c color.Color) AlphaPolicy {
+	return AlphaPolicy{Mode: AlphaMatte, Matte: c}
+}
+
+// ThresholdAlpha returns a policy that makes pixels with an alpha of at least t
+// opaque and all others transparent.
+func ThresholdAlpha(t uint8) AlphaPolicy {
+	return AlphaPolicy{Mode: AlphaThreshold, Threshold: t}
+}
+
+func (a AlphaPolicy) apply(img image.Image) image.Image {
+	switch a.Mode {
+	case AlphaMatte:
+		matte := a.Matte
+		if matte == nil {
+			matte = color.White
+		}
+		dst := image.NewRGBA(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(matte), image.ZP, draw.Src)
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+		return dst
+	case AlphaThreshold:
+		bounds := img.Bounds()
+		dst := image.NewNRGBA(bounds)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if c.A >= a.Threshold && c.A > 0 {
+					c.A = 0xff
+				} else {
+					c = color.NRGBA{}
+				}
+				dst.SetNRGBA(x, y, c)
+			}
+		}
+		return dst
+	default:
+		return img
+	}
+}
+
+// alpha returns the config's alpha policy with its Background taken into account.
+func (c *Config) alpha() AlphaPolicy {
+	a := c.Alpha
+	if c.Background == nil {
+		return a
+	}
+	switch a.Mode {
+	case AlphaTransparent:
+		return MatteAlpha(c.Background)
+	case AlphaMatte:
+		if a.Matte == nil {
+			a.Matte = c.Background
+		}
+	}
+	return a
+}
+
+// matte returns the color transparent pixels are composited over, or nil if they
+// are left transparent.
+func (c *Config) matte() color.Color {
+	a := c.alpha()
+	if a.Mode != AlphaMatte {
+		return nil
+	}
+	if a.Matte == nil {
+		return color.White
+	}
+	return a.Matte
+}
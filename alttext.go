@@ -0,0 +1,52 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// AltTextCommand returns a Config.AltText hook that describes images by running a
+// captioning command, such as a script wrapping an image captioning model. The
+// image is written to the command's standard input as a PNG, and the command's
+// standard output, trimmed of surrounding space, is the description.
+func AltTextCommand(name string, args ...string) func(image.Image) (string, error) {
+	return func(img image.Image) (string, error) {
+		var in bytes.Buffer
+		if err := png.Encode(&in, img); err != nil {
+			return "", err
+		}
+		var stderr bytes.Buffer
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = &in
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+			return "", fmt.Errorf("alt text: %v: %s", err, msg)
+		}
+		if err != nil {
+			return "", fmt.Errorf("alt text: %v", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+}
+
+// writeAltText writes a line describing img to w, if the config has an AltText
+// hook.
+func (p *Printer) writeAltText(w io.Writer, img image.Image) error {
+	p.trailing = 0
+	if p.c.AltText == nil {
+		return nil
+	}
+	text, err := p.c.AltText(img)
+	if err != nil || text == "" {
+		return err
+	}
+	p.trailing = 1
+	_, err = fmt.Fprintf(w, "Alt text: %s\n", text)
+	return err
+}
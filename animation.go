@@ -0,0 +1,121 @@
+package dotmatrix
+
+import (
+	"context"
+	"image"
+	"io"
+	"time"
+)
+
+// animation is a sequence of frames composited onto a canvas, such as a gif.
+type animation interface {
+	frames() int
+	// composite returns the canvas with frame i drawn on it, at the animation's
+	// own resolution. The canvas may be drawn over by the next call.
+	composite(i int) *image.RGBA
+	delay(i int) time.Duration
+	// loops returns how many times the animation asks to be played, or 0 to loop
+	// forever.
+	loops() int
+}
+
+// newAnimationTrack returns a track that plays anim as the config asks.
+func newAnimationTrack(c *Config, anim animation) track {
+	if c.Interpolate > 0 {
+		anim = newInterpolatedAnimation(anim, c.Interpolate)
+	}
+	t := &animationTrack{c: c, anim: anim}
+	if c.DecodeAhead > 0 {
+		// The pipeline dithers screens while the next is composited, so each is a copy.
+		t.ahead = newAheadRenderer(anim.frames(), func(i int) image.Image {
+			return copyRGBA(anim.composite(i))
+		}, c, c.DecodeAhead)
+	}
+	if c.Prerender {
+		return &prerenderTrack{t: t, c: c}
+	}
+	return t
+}
+
+// animationTrack redraws the canvas of an animation for each frame.
+type animationTrack struct {
+	c    *Config
+	anim animation
+
+	// The screen is what we flush to the writer on each iteration, and last is the
+	// screen before it, kept to find what changed. Their pixels are swapped from
+	// frame to frame, to spare the garbage collector.
+	screen, last *image.Paletted
+
+	// ahead, if the config asks for it, redraws frames in the background.
+	ahead *aheadRenderer
+}
+
+// loops follows the config's loop count if it has one, or else the animation's.
+func (t *animationTrack) loops() int {
+	if n := t.c.LoopCount; n != 0 {
+		if n < 0 {
+			return 0
+		}
+		return n
+	}
+	return t.anim.loops()
+}
+
+func (t *animationTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	if i >= t.anim.frames() {
+		return nil, image.Rectangle{}, 0, io.EOF
+	}
+
+	// The whole screen is filtered and scaled at once, so that frames covering only
+	// part of it line up with what's under them. Dithering carries errors across
+	// the screen, so what changed is found by comparing with the last screen.
+	if t.ahead != nil {
+		t.last, t.screen = t.screen, t.ahead.redraw(i)
+	} else {
+		t.last = redrawInto(t.last, t.anim.composite(i), t.c)
+		t.last, t.screen = t.screen, t.last
+	}
+	return t.screen, changed(t.last, t.screen), t.anim.delay(i), nil
+}
+
+func (t *animationTrack) length() int {
+	return t.anim.frames()
+}
+
+func (t *animationTrack) delayOf(i int) time.Duration {
+	return t.anim.delay(i)
+}
+
+// Close stops any frames being redrawn in the background.
+func (t *animationTrack) Close() error {
+	if t.ahead != nil {
+		return t.ahead.Close()
+	}
+	return nil
+}
+
+// changed returns the bounds of the pixels that differ between a and b, or all of
+// b if they differ in size.
+func changed(a, b *image.Paletted) image.Rectangle {
+	if a == nil || a.Rect != b.Rect {
+		return b.Bounds()
+	}
+	var r image.Rectangle
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		row := a.Pix[a.PixOffset(b.Rect.Min.X, y):a.PixOffset(b.Rect.Max.X, y)]
+		for x, v := range b.Pix[b.PixOffset(b.Rect.Min.X, y):b.PixOffset(b.Rect.Max.X, y)] {
+			if v != row[x] {
+				r = r.Union(image.Rect(b.Rect.Min.X+x, y, b.Rect.Min.X+x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+// copyRGBA returns a copy of img.
+func copyRGBA(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Rect)
+	copy(dst.Pix, img.Pix)
+	return dst
+}
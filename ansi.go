@@ -0,0 +1,329 @@
+package dotmatrix
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("ansi", "\x1b[", DecodeANSI, DecodeANSIConfig)
+}
+
+// ANSI art is rasterized into cells of this many pixels, matching a braille cell,
+// so that re-rendering ANSI art at its original size preserves its layout.
+const (
+	ansiCellWidth  = 2
+	ansiCellHeight = 4
+)
+
+// ansiCell is one character of ANSI art and the colors it was drawn with.
+type ansiCell struct {
+	r      rune
+	fg, bg color.Color
+}
+
+/*
+DecodeANSI rasterizes text art, optionally colored with ANSI escape codes, into an
+image. Each character becomes a 2x4 pixel block: braille characters map dot for dot,
+block elements fill the part of the block they cover, spaces show the background
+color, and any other character is drawn as a half-tone of its foreground and
+background. Text without an explicit foreground is black and text without an
+explicit background is transparent. Cursor movement and other escapes are ignored.
+
+The "ansi" format is registered with the image package for input beginning with a
+control sequence introducer, so image.Decode will also decode ANSI art.
+*/
+func DecodeANSI(r io.Reader) (image.Image, error) {
+	grid, err := parseANSI(r)
+	if err != nil {
+		return nil, err
+	}
+	cols := 0
+	for _, row := range grid {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, cols*ansiCellWidth, len(grid)*ansiCellHeight))
+	for cy, row := range grid {
+		for cx, cell := range row {
+			for y := 0; y < ansiCellHeight; y++ {
+				for x := 0; x < ansiCellWidth; x++ {
+					c := cell.bg
+					if cell.covers(x, y) {
+						c = cell.fg
+					}
+					img.Set(cx*ansiCellWidth+x, cy*ansiCellHeight+y, c)
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// DecodeANSIConfig returns the dimensions of the image DecodeANSI would produce.
+func DecodeANSIConfig(r io.Reader) (image.Config, error) {
+	grid, err := parseANSI(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	cols := 0
+	for _, row := range grid {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      cols * ansiCellWidth,
+		Height:     len(grid) * ansiCellHeight,
+	}, nil
+}
+
+// covers reports whether the pixel at x,y within the cell is drawn in the
+// foreground color.
+func (c ansiCell) covers(x, y int) bool {
+	switch {
+	case c.r >= '⠀' && c.r <= '⣿':
+		return (c.r-'⠀')&rune(brailleDots[x][y]) != 0
+	case c.r == ' ':
+		return false
+	case c.r == '█':
+		return true
+	case c.r == '▀':
+		return y < ansiCellHeight/2
+	case c.r == '▄':
+		return y >= ansiCellHeight/2
+	case c.r == '▌':
+		return x < ansiCellWidth/2
+	case c.r == '▐':
+		return x >= ansiCellWidth/2
+	case c.r == '░':
+		return (x+y)%4 == 0
+	case c.r == '▓':
+		return (x+y)%4 != 0
+	default:
+		return (x+y)%2 == 0
+	}
+}
+
+// parseANSI splits text into rows of colored cells.
+func parseANSI(r io.Reader) ([][]ansiCell, error) {
+	var (
+		grid    [][]ansiCell
+		row     []ansiCell
+		col     int
+		sgr     = &sgrState{}
+		scanner = bufio.NewReader(r)
+	)
+	put := func(ch rune) {
+		for len(row) <= col {
+			row = append(row, ansiCell{r: ' ', fg: color.Black, bg: color.Transparent})
+		}
+		fg, bg := sgr.colors()
+		row[col] = ansiCell{r: ch, fg: fg, bg: bg}
+		col++
+	}
+	for {
+		ch, _, err := scanner.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch ch {
+		case '\n':
+			grid = append(grid, row)
+			row, col = nil, 0
+		case '\r':
+			col = 0
+		case '\t':
+			col = (col/8 + 1) * 8
+		case '\x1b':
+			if err := sgr.readEscape(scanner); err != nil && err != io.EOF {
+				return nil, err
+			}
+		default:
+			if ch >= ' ' {
+				put(ch)
+			}
+		}
+	}
+	if len(row) > 0 {
+		grid = append(grid, row)
+	}
+	return grid, nil
+}
+
+// sgrState tracks the graphic rendition set by escape codes.
+type sgrState struct {
+	fg, bg  color.Color // nil means the default
+	reverse bool
+}
+
+// colors returns the effective foreground and background colors.
+func (s *sgrState) colors() (color.Color, color.Color) {
+	fg, bg := s.fg, s.bg
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.Transparent
+	}
+	if s.reverse {
+		return bg, fg
+	}
+	return fg, bg
+}
+
+// readEscape consumes an escape sequence, whose leading ESC has already been read.
+// Select graphic rendition sequences update the state; all others are skipped.
+func (s *sgrState) readEscape(r *bufio.Reader) error {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return err
+	}
+	switch ch {
+	case '[': // CSI: parameters, intermediates, then a final byte in @ to ~.
+		var params strings.Builder
+		for {
+			ch, _, err := r.ReadRune()
+			if err != nil {
+				return err
+			}
+			if ch >= '@' && ch <= '~' {
+				if ch == 'm' {
+					s.apply(params.String())
+				}
+				return nil
+			}
+			params.WriteRune(ch)
+		}
+	case ']': // OSC: terminated by BEL or ST.
+		for {
+			ch, _, err := r.ReadRune()
+			if err != nil {
+				return err
+			}
+			if ch == '\a' {
+				return nil
+			}
+			if ch == '\x1b' {
+				_, _, err := r.ReadRune()
+				return err
+			}
+		}
+	default: // Two character sequences, such as ESC 7.
+		return nil
+	}
+}
+
+// apply applies the semicolon separated parameters of an SGR sequence.
+func (s *sgrState) apply(params string) {
+	var codes []int
+	for _, p := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(p) // Empty parameters mean zero.
+		codes = append(codes, n)
+	}
+	for i := 0; i < len(codes); i++ {
+		switch n := codes[i]; {
+		case n == 0:
+			*s = sgrState{}
+		case n == 7:
+			s.reverse = true
+		case n == 27:
+			s.reverse = false
+		case n >= 30 && n <= 37:
+			s.fg = ansi256(n - 30)
+		case n >= 90 && n <= 97:
+			s.fg = ansi256(n - 90 + 8)
+		case n == 39:
+			s.fg = nil
+		case n >= 40 && n <= 47:
+			s.bg = ansi256(n - 40)
+		case n >= 100 && n <= 107:
+			s.bg = ansi256(n - 100 + 8)
+		case n == 49:
+			s.bg = nil
+		case n == 38 || n == 48:
+			var c color.Color
+			c, i = extendedColor(codes, i)
+			if c == nil {
+				continue
+			}
+			if n == 38 {
+				s.fg = c
+			} else {
+				s.bg = c
+			}
+		}
+	}
+}
+
+// extendedColor parses a 256 color (5;n) or truecolor (2;r;g;b) parameter list
+// following codes[i], returning the color and the index of its last parameter.
+func extendedColor(codes []int, i int) (color.Color, int) {
+	if i+1 >= len(codes) {
+		return nil, i
+	}
+	switch codes[i+1] {
+	case 5:
+		if i+2 < len(codes) {
+			return ansi256(codes[i+2]), i + 2
+		}
+	case 2:
+		if i+4 < len(codes) {
+			return color.RGBA{uint8(codes[i+2]), uint8(codes[i+3]), uint8(codes[i+4]), 0xff}, i + 4
+		}
+	}
+	return nil, len(codes)
+}
+
+// ANSIPalette returns the first n colors of the xterm 256 color palette, such as
+// the 16 colors of basic ANSI terminals. n is clamped to between 1 and 256.
+func ANSIPalette(n int) color.Palette {
+	if n < 1 {
+		n = 1
+	}
+	if n > 256 {
+		n = 256
+	}
+	palette := make(color.Palette, n)
+	for i := range palette {
+		palette[i] = ansi256(i)
+	}
+	return palette
+}
+
+// ansi256 returns the standard xterm color for index n of the 256 color palette.
+func ansi256(n int) color.Color {
+	base := [16]color.RGBA{
+		{0x00, 0x00, 0x00, 0xff}, {0xcd, 0x00, 0x00, 0xff}, {0x00, 0xcd, 0x00, 0xff}, {0xcd, 0xcd, 0x00, 0xff},
+		{0x00, 0x00, 0xee, 0xff}, {0xcd, 0x00, 0xcd, 0xff}, {0x00, 0xcd, 0xcd, 0xff}, {0xe5, 0xe5, 0xe5, 0xff},
+		{0x7f, 0x7f, 0x7f, 0xff}, {0xff, 0x00, 0x00, 0xff}, {0x00, 0xff, 0x00, 0xff}, {0xff, 0xff, 0x00, 0xff},
+		{0x5c, 0x5c, 0xff, 0xff}, {0xff, 0x00, 0xff, 0xff}, {0x00, 0xff, 0xff, 0xff}, {0xff, 0xff, 0xff, 0xff},
+	}
+	switch {
+	case n < 0 || n > 255:
+		return color.Black
+	case n < 16:
+		return base[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		return color.RGBA{level(n / 36), level(n / 6 % 6), level(n % 6), 0xff}
+	default:
+		v := uint8(8 + (n-232)*10)
+		return color.RGBA{v, v, v, 0xff}
+	}
+}
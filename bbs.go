@@ -0,0 +1,105 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+)
+
+// Charset is the character set a BBSFlusher draws with.
+type Charset int
+
+const (
+	// CP437 draws with the block and shade characters of IBM code page 437, which
+	// most BBS clients and many MUD clients display.
+	CP437 Charset = iota
+	// ASCII draws with printable ASCII characters of increasing density, for
+	// clients that display nothing else.
+	ASCII
+)
+
+// cp437Blocks are the CP437 characters a BBSFlusher chooses between, keyed by the
+// unicode character whose dots DecodeANSI would fill for them, so that BBS output
+// decodes back into a similar image.
+var cp437Blocks = []struct {
+	r rune
+	b byte
+}{
+	{' ', 0x20}, {'█', 0xdb}, {'▀', 0xdf}, {'▄', 0xdc}, {'▌', 0xdd}, {'▐', 0xde},
+	{'░', 0xb0}, {'▒', 0xb1}, {'▓', 0xb2},
+}
+
+// asciiRamp orders printable characters by how much of a cell they ink.
+const asciiRamp = " .:-=+*#%@"
+
+/*
+BBSFlusher draws images for telnet BBS and MUD clients, which can't display
+braille. Each 2x4 pixel cell is printed as the single byte character that best
+matches it, in code page 437 or ASCII, and lines end in CRLF as telnet requires.
+No colors or other escapes are written, so output is monochrome in the client's
+own colors.
+
+A cell can only take one of a handful of shapes, rather than any of braille's 256,
+so fine detail is lost. Simple, high contrast images work best. See BBSProfile.
+*/
+type BBSFlusher struct {
+	Charset Charset
+}
+
+func (f BBSFlusher) Flush(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	var line bytes.Buffer
+	return ForEachCell(bounds, 2, 4, func(_, _ int, cell image.Rectangle) error {
+		line.WriteByte(f.byteFor(BrailleOf(img, cell)))
+		if cell.Max.X < bounds.Max.X {
+			return nil
+		}
+		line.WriteString("\r\n")
+		_, err := line.WriteTo(w)
+		return err
+	})
+}
+
+// byteFor returns the character that best matches the dots of b.
+func (f BBSFlusher) byteFor(b Braille) byte {
+	if f.Charset == ASCII {
+		var n int
+		for x := range b {
+			for y := range b[x] {
+				n += b[x][y]
+			}
+		}
+		return asciiRamp[n*(len(asciiRamp)-1)/8]
+	}
+
+	best, bestDistance := cp437Blocks[0].b, 9
+	for _, block := range cp437Blocks {
+		cell := ansiCell{r: block.r}
+		var distance int
+		for x := range b {
+			for y := range b[x] {
+				if cell.covers(x, y) != (b[x][y] == 1) {
+					distance++
+				}
+			}
+		}
+		if distance < bestDistance {
+			best, bestDistance = block.b, distance
+		}
+	}
+	return best
+}
+
+// BBSProfile returns a config for serving art to telnet BBS and MUD clients. It
+// flushes with a BBSFlusher and moves the cursor between animated frames with only
+// the basic cursor-up sequence, which ANSI-capable clients all support.
+func BBSProfile(charset Charset) *Config {
+	return &Config{
+		Flusher: BBSFlusher{Charset: charset},
+		Reset: func(w io.Writer, rows int) {
+			// CRLF has already returned the cursor to the first column.
+			fmt.Fprintf(w, "\033[%dA", rows)
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// bbsShapes returns an image of one row of cells, each drawn in one of the shapes
+// BBS charsets have characters for, followed by a row of full cells.
+func bbsShapes() *image.Paletted {
+	shapes := []func(x, y int) bool{
+		func(x, y int) bool { return true },
+		func(x, y int) bool { return y < 2 },
+		func(x, y int) bool { return y >= 2 },
+		func(x, y int) bool { return x < 1 },
+		func(x, y int) bool { return x >= 1 },
+		func(x, y int) bool { return false },
+		func(x, y int) bool { return (x+y)%2 == 0 },
+		func(x, y int) bool { return (x+y)%4 == 0 },
+		func(x, y int) bool { return (x+y)%4 != 0 },
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 2*len(shapes), 8), color.Palette{color.White, color.Black})
+	for i, inked := range shapes {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 2; x++ {
+				if inked(x, y) {
+					img.SetColorIndex(2*i+x, y, 1)
+				}
+				img.SetColorIndex(2*i+x, 4+y, 1)
+			}
+		}
+	}
+	return img
+}
+
+var _ = Describe("BBSProfile", func() {
+	It("should print each cell as the closest code page 437 block", func() {
+		var out bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&out, dotmatrix.BBSProfile(dotmatrix.CP437)).Print(bbsShapes())).To(Succeed())
+		Expect(out.Bytes()).To(Equal([]byte("" +
+			"\xdb\xdf\xdc\xdd\xde \xb1\xb0\xb2\r\n" +
+			"\xdb\xdb\xdb\xdb\xdb\xdb\xdb\xdb\xdb\r\n")))
+	})
+
+	It("should print each cell as the ASCII character of closest density", func() {
+		var out bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&out, dotmatrix.BBSProfile(dotmatrix.ASCII)).Print(bbsShapes())).To(Succeed())
+		Expect(out.Bytes()).To(Equal([]byte("" +
+			"@==== =:*\r\n" +
+			"@@@@@@@@@\r\n")))
+	})
+
+	It("should move back up over animated frames with the basic cursor-up sequence", func() {
+		palette := color.Palette{color.White, color.Black}
+		giff := &gif.GIF{
+			Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 8), palette), image.NewPaletted(image.Rect(0, 0, 2, 8), palette)},
+			Delay:    []int{0, 0},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+			Config:   image.Config{Width: 2, Height: 8},
+		}
+		for i := range giff.Image[1].Pix {
+			giff.Image[1].Pix[i] = 1
+		}
+		c := dotmatrix.BBSProfile(dotmatrix.ASCII)
+		c.LoopCount, c.KeepCursor = 1, true
+		var out bytes.Buffer
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)).To(Succeed())
+		// Every frame, the last included, is followed by moving back up over it.
+		Expect(out.Bytes()).To(Equal([]byte(" \r\n \r\n\033[2A@\r\n@\r\n\033[2A")))
+	})
+})
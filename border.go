@@ -0,0 +1,91 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"strings"
+)
+
+/*
+BorderFlusher frames the output of another flusher in box-drawing characters, with
+an optional title set into the top edge. Eg:
+
+	┌─ Saturn ─┐
+	│⣿⣿⣿⡿⡻⡫⡫⣿⣿⣿│
+	│⣿⣿⢟⢕⣾⣿⡇⣿⣿⣿│
+	└──────────┘
+
+This sets images apart when they're embedded in dashboards, or shown side by side.
+The wrapped flusher must print one line per row of cells, as BrailleFlusher does.
+Printers and players account for the frame when moving the cursor, including for
+partial flushes.
+*/
+type BorderFlusher struct {
+	// Flusher prints the image within the frame. The default is BrailleFlusher.
+	Flusher Flusher
+	// Title, if set, is printed in the top edge, cut short if the image is too
+	// narrow for it.
+	Title string
+}
+
+func (f BorderFlusher) Flush(w io.Writer, img image.Image) error {
+	var inner bytes.Buffer
+	if err := f.inner().Flush(&inner, img); err != nil {
+		return err
+	}
+	cols := (img.Bounds().Dx() + 1) / 2
+
+	var out bytes.Buffer
+	out.WriteString(f.top(cols))
+	for _, line := range bytes.SplitAfter(inner.Bytes(), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		// Line endings, such as the CRLFs of telnet, are kept after the frame.
+		text := bytes.TrimRight(line, "\r\n")
+		out.WriteString("│")
+		out.Write(text)
+		out.WriteString("│")
+		out.Write(line[len(text):])
+	}
+	out.WriteString("└" + strings.Repeat("─", cols) + "┘\n")
+	_, err := out.WriteTo(w)
+	return err
+}
+
+func (f BorderFlusher) inner() Flusher {
+	if f.Flusher == nil {
+		return BrailleFlusher{}
+	}
+	return f.Flusher
+}
+
+// top returns the top edge of the frame around cols cells, with the title set a
+// cell in from the corner.
+func (f BorderFlusher) top(cols int) string {
+	label := []rune(f.Title)
+	if len(label) == 0 || cols < 4 {
+		return "┌" + strings.Repeat("─", cols) + "┐\n"
+	}
+	if len(label) > cols-3 {
+		label = label[:cols-3]
+	}
+	return "┌─ " + string(label) + " " + strings.Repeat("─", cols-3-len(label)) + "┐\n"
+}
+
+// unframe returns the flusher within flusher's frame, if it has one, and how many
+// rows and columns the frame adds above and to the left of the image.
+func unframe(flusher Flusher) (Flusher, int) {
+	if f, ok := flusher.(BorderFlusher); ok {
+		return f.inner(), 1
+	}
+	return flusher, 0
+}
+
+// screenRows returns the number of rows flusher prints an image with the given
+// bounds in, counting any frame.
+func screenRows(bounds image.Rectangle, flusher Flusher) int {
+	_, inset := unframe(flusher)
+	return cellRows(bounds) + 2*inset
+}
@@ -0,0 +1,26 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("BorderFlusher", func() {
+	It("should frame the image, with its title in the top edge", func() {
+		var out bytes.Buffer
+		img := image.NewPaletted(image.Rect(0, 0, 16, 8), color.Palette{color.Black})
+		flusher := dotmatrix.BorderFlusher{Title: "a long title"}
+		Expect(flusher.Flush(&out, img)).To(Succeed())
+		Expect(out.String()).To(Equal("" +
+			"┌─ a lon ┐\n" +
+			"│⣿⣿⣿⣿⣿⣿⣿⣿│\n" +
+			"│⣿⣿⣿⣿⣿⣿⣿⣿│\n" +
+			"└────────┘\n"))
+	})
+})
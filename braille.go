@@ -1,9 +1,12 @@
 package dotmatrix
 
 import (
+	"errors"
 	"image"
 	"image/color"
 	"io"
+	"runtime"
+	"unicode/utf8"
 )
 
 // Braille epresents an 8 dot braille pattern in x,y coordinates space. Eg:
@@ -15,6 +18,10 @@ import (
 //   +----------+
 type Braille [2][4]int
 
+// brailleDots are the bits of a braille rune for each dot of its cell, by x, y.
+// See Rune.
+var brailleDots = [2][4]uint8{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
 // Rune maps each point in braille to a dot identifier and
 // calculates the corresponding unicode symbol.
 //   +------+
@@ -25,12 +32,13 @@ type Braille [2][4]int
 //   +------+
 // See https://en.wikipedia.org/wiki/Braille_Patterns#Identifying.2C_naming_and_ordering)
 func (b Braille) Rune() rune {
-	lowEndian := [8]int{b[0][0], b[0][1], b[0][2], b[1][0], b[1][1], b[1][2], b[0][3], b[1][3]}
-	var v int
-	for i, x := range lowEndian {
-		v += int(x) << uint(i)
+	var v rune
+	for x := range b {
+		for y := range b[x] {
+			v += rune(b[x][y]) * rune(brailleDots[x][y])
+		}
 	}
-	return rune(v) + '\u2800'
+	return v + '\u2800'
 }
 
 // String returns a unicode braille character. One of:
@@ -39,36 +47,210 @@ func (b Braille) String() string {
 	return string(b.Rune())
 }
 
-type BrailleFlusher struct{}
+// Highlight marks a rectangle of braille cells for interactive viewers, e.g. to
+// show a selection. Cell (x, y) covers pixels (2x, 4y) through (2x+1, 4y+3) of the
+// image, so for an image at the origin, cell (0, 0) is the top left character.
+type Highlight struct {
+	Cells image.Rectangle
+	// SGR holds the select graphic rendition parameters that highlighted cells are
+	// printed with, e.g. "7" for reverse video or "30;43" for black on yellow. If
+	// empty, highlighted cells are inverted by flipping their dots instead, which
+	// works on any terminal.
+	SGR string
+}
+
+type BrailleFlusher struct {
+	// Highlights marks regions of cells to print highlighted. Where highlights
+	// overlap, the first one wins.
+	Highlights []Highlight
+	// Blank, if set, is printed for cells without any dots in place of the blank
+	// braille pattern, U+2800. See Config.Blank.
+	Blank rune
+}
+
+// parallelCells is how many cells an image must have for its rows to be encoded in
+// parallel. Smaller images are encoded faster than goroutines can be started.
+const parallelCells = 16384
 
-func (BrailleFlusher) Flush(w io.Writer, img image.Image) error {
-	// An image's bounds do not necessarily start at (0, 0), so the two loops start
-	// at bounds.Min.Y and bounds.Min.X.
-	// Looping over Y first and X second is more likely to result in better memory
-	// access patterns than X first and Y second.
+func (f BrailleFlusher) Flush(w io.Writer, img image.Image) error {
+	// An image's bounds do not necessarily start at (0, 0), so cells are relative
+	// to bounds.Min.
 	bounds := img.Bounds()
-	for py := bounds.Min.Y; py < bounds.Max.Y; py += 4 {
-		for px := bounds.Min.X; px < bounds.Max.X; px += 2 {
-			var b Braille
-			// Draw left-right, top-bottom.
-			for y := 0; y < 4; y++ {
-				for x := 0; x < 2; x++ {
-					if px+x >= bounds.Max.X || py+y >= bounds.Max.Y {
-						continue
-					}
-					// Always bet on black!
-					if img.At(px+x, py+y) == color.Black {
-						b[x][y] = 1
-					}
+	rows, cols := cellRows(bounds), (bounds.Dx()+1)/2
+	if workers := runtime.GOMAXPROCS(0); workers > 1 && rows > 1 && rows*cols >= parallelCells {
+		return f.flushParallel(w, img, workers)
+	}
+	// Each row is built up and written at once, as writes to unbuffered writers,
+	// such as terminals over ssh, are slow. Braille takes 3 bytes a cell.
+	line := make([]byte, 0, cols*utf8.UTFMax+1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 4 {
+		line = f.appendRow(line[:0], img, y)
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushParallel encodes bands of rows of img on workers goroutines, and writes
+// each band in order once it is encoded. Rows are independent, as any highlight
+// is reset at the end of each.
+func (f BrailleFlusher) flushParallel(w io.Writer, img image.Image, workers int) error {
+	bounds := img.Bounds()
+	rows, cols := cellRows(bounds), (bounds.Dx()+1)/2
+	// More bands than workers keeps every worker busy to the end, and lets writing
+	// start before the whole image is encoded.
+	per := (rows + 4*workers - 1) / (4 * workers)
+	bands := make([]chan []byte, (rows+per-1)/per)
+	work := make(chan int, len(bands))
+	for i := range bands {
+		bands[i] = make(chan []byte, 1)
+		work <- i
+	}
+	close(work)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for band := range work {
+				buf := make([]byte, 0, per*(cols*utf8.UTFMax+1))
+				y0 := bounds.Min.Y + band*per*4
+				for y := y0; y < bounds.Max.Y && y < y0+per*4; y += 4 {
+					buf = f.appendRow(buf, img, y)
 				}
+				bands[band] <- buf
 			}
-			if _, err := w.Write([]byte(b.String())); err != nil {
-				return err
-			}
-		}
-		if _, err := w.Write([]byte{'\n'}); err != nil {
+		}()
+	}
+	// Workers run out of work, rather than block, if writing fails.
+	for _, band := range bands {
+		if _, err := w.Write(<-band); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// appendRow appends the row of cells whose top is y, ending with a line feed.
+func (f BrailleFlusher) appendRow(line []byte, img image.Image, y int) []byte {
+	bounds := img.Bounds()
+	var sgr string // the rendition in effect
+	ForEachCell(image.Rect(bounds.Min.X, y, bounds.Max.X, y+4).Intersect(bounds), 2, 4, func(_, _ int, cell image.Rectangle) error {
+		b := BrailleOf(img, cell)
+		var style string
+		if h, ok := f.highlight(cell.Min.X, cell.Min.Y); ok {
+			if h.SGR == "" {
+				for x := range b {
+					for y := range b[x] {
+						b[x][y] ^= 1
+					}
+				}
+			}
+			style = h.SGR
+		}
+		if style != sgr {
+			line = appendSGR(line, sgr, style)
+			sgr = style
+		}
+		r := b.Rune()
+		if r == '\u2800' && f.Blank != 0 {
+			r = f.Blank
+		}
+		line = appendRune(line, r)
+		return nil
+	})
+	if sgr != "" {
+		line = appendSGR(line, sgr, "")
+	}
+	return append(line, '\n')
+}
+
+// highlight returns the highlight of the cell whose top left pixel is px, py.
+func (f BrailleFlusher) highlight(px, py int) (Highlight, bool) {
+	cell := image.Pt(floorDiv(px, 2), floorDiv(py, 4))
+	for _, h := range f.Highlights {
+		if cell.In(h.Cells) {
+			return h, true
+		}
+	}
+	return Highlight{}, false
+}
+
+// appendSGR appends the escapes switching the graphic rendition from one set of
+// parameters to another.
+func appendSGR(b []byte, from, to string) []byte {
+	if from != "" {
+		b = append(b, "\033[0m"...)
+	}
+	if to != "" {
+		b = append(b, "\033["...)
+		b = append(b, to...)
+		b = append(b, 'm')
+	}
+	return b
+}
+
+// appendRune appends the UTF-8 encoding of r.
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	return append(b, buf[:utf8.EncodeRune(buf[:], r)]...)
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return (a - b + 1) / b
+	}
+	return a / b
+}
+
+func init() {
+	// Braille patterns are encoded in UTF-8 as e2 a0 80 through e2 a3 bf.
+	for _, magic := range []string{"\xe2\xa0", "\xe2\xa1", "\xe2\xa2", "\xe2\xa3"} {
+		image.RegisterFormat("braille", magic, Decode, DecodeConfig)
+	}
+}
+
+/*
+Decode turns braille text, such as a Printer prints, back into an image, so that
+renders can be compared, diffed or converted to other formats. The text is
+rasterized as DecodeANSI does, into 2x4 pixel cells, and thresholded as IsDot
+does: pixels that would be printed as dots are black and the rest are white. So
+braille dots in the default colors are black, spaces are blank, each line of text
+is a row of cells, and escape sequences other than colors are skipped. Printing
+the decoded image gives back the text it was decoded from.
+
+The "braille" format is registered with the image package for input beginning
+with a braille character, so image.Decode will also decode braille.
+*/
+func Decode(r io.Reader) (image.Image, error) {
+	src, err := DecodeANSI(r)
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return nil, errNoText
+	}
+	img := image.NewPaletted(bounds, color.Palette{color.White, color.Black})
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if IsDot(src.At(x, y)) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img, nil
+}
+
+// DecodeConfig returns the dimensions of the image Decode would produce.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	config, err := DecodeANSIConfig(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return image.Config{}, errNoText
+	}
+	config.ColorModel = color.Palette{color.White, color.Black}
+	return config, nil
+}
+
+var errNoText = errors.New("dotmatrix: no text to decode")
@@ -0,0 +1,181 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Decode", func() {
+	It("should round-trip printed braille", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 9, 10), color.Palette{color.White, color.Black})
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i*7%3) & 1
+		}
+		var printed bytes.Buffer
+		Expect(dotmatrix.Print(&printed, img)).To(Succeed())
+
+		decoded, err := dotmatrix.Decode(bytes.NewReader(printed.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Bounds()).To(Equal(image.Rect(0, 0, 10, 12)))
+		var reprinted bytes.Buffer
+		Expect(dotmatrix.Print(&reprinted, decoded)).To(Succeed())
+		Expect(reprinted.String()).To(Equal(printed.String()))
+	})
+
+	It("should round-trip highlighted braille, colors and all", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.White, color.Black})
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i*5%7) & 1
+		}
+		flusher := dotmatrix.BrailleFlusher{Highlights: []dotmatrix.Highlight{
+			{Cells: image.Rect(1, 0, 3, 1), SGR: "1;31"},
+			{Cells: image.Rect(3, 1, 4, 2), SGR: "7"},
+			{Cells: image.Rect(0, 1, 2, 2)},
+		}}
+		var printed bytes.Buffer
+		Expect(flusher.Flush(&printed, img)).To(Succeed())
+
+		decoded, err := dotmatrix.DecodeANSI(bytes.NewReader(printed.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Bounds()).To(Equal(img.Bounds()))
+		red := color.NRGBAModel.Convert(dotmatrix.ANSIPalette(2)[1])
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				dot := img.ColorIndexAt(x, y) == 1
+				c := decoded.At(x, y)
+				switch cell := image.Pt(x/2, y/4); {
+				case cell.In(image.Rect(1, 0, 3, 1)) && dot:
+					Expect(c).To(Equal(red), "pixel %d,%d", x, y)
+				case cell.In(image.Rect(3, 1, 4, 2)), cell.In(image.Rect(0, 1, 2, 2)):
+					// Reverse video and inverted dots both read back as flipped.
+					Expect(dotmatrix.IsDot(c)).To(Equal(!dot), "pixel %d,%d", x, y)
+				default:
+					Expect(dotmatrix.IsDot(c)).To(Equal(dot), "pixel %d,%d", x, y)
+				}
+			}
+		}
+
+		// Decode thresholds the colors back to dots.
+		dots, err := dotmatrix.Decode(bytes.NewReader(printed.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				Expect(dotmatrix.IsDot(dots.At(x, y))).To(Equal(dotmatrix.IsDot(decoded.At(x, y))), "pixel %d,%d", x, y)
+			}
+		}
+	})
+
+	It("should decode braille with image.Decode, skipping escapes", func() {
+		img, format, err := image.Decode(strings.NewReader("⠁\033[1;31m⣿\033[0m\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal("braille"))
+		Expect(img.Bounds()).To(Equal(image.Rect(0, 0, 4, 4)))
+		Expect(img.At(0, 0)).To(Equal(color.Black))
+		Expect(img.At(1, 0)).To(Equal(color.White))
+		Expect(img.At(3, 3)).To(Equal(color.Black))
+	})
+})
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(b)
+}
+
+var _ = Describe("BrailleFlusher", func() {
+	It("should write each row at once", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 40, 12), color.Palette{color.White, color.Black})
+		w := &countingWriter{}
+		Expect(dotmatrix.BrailleFlusher{}.Flush(w, img)).To(Succeed())
+		Expect(w.writes).To(Equal(3))
+		Expect(w.String()).To(HavePrefix(strings.Repeat("⠀", 20) + "\n"))
+	})
+
+	It("should wrap highlighted runs of cells in their escapes, resetting after each", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 10, 8), color.Palette{color.White, color.Black})
+		flusher := dotmatrix.BrailleFlusher{Highlights: []dotmatrix.Highlight{
+			{Cells: image.Rect(1, 0, 3, 1), SGR: "7"},
+			// Overlaps the first at cell 2, 0, where the first wins.
+			{Cells: image.Rect(2, 0, 5, 2), SGR: "1;31"},
+			{Cells: image.Rect(0, 1, 1, 2)},
+		}}
+		var out bytes.Buffer
+		Expect(flusher.Flush(&out, img)).To(Succeed())
+		Expect(out.String()).To(Equal("" +
+			"⠀\033[7m⠀⠀\033[0m\033[1;31m⠀⠀\033[0m\n" +
+			// Inverted cells are printed without escapes.
+			"⣿⠀\033[1;31m⠀⠀⠀\033[0m\n"))
+	})
+
+	It("should reprint the cells whose highlighting changes", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 10, 8), color.Palette{color.White, color.Black})
+		var out bytes.Buffer
+		printer := dotmatrix.NewPrinter(&out, nil)
+		Expect(printer.Print(img)).To(Succeed())
+
+		out.Reset()
+		Expect(printer.Highlight(dotmatrix.Highlight{Cells: image.Rect(1, 1, 3, 2), SGR: "7"})).To(Succeed())
+		Expect(out.String()).To(Equal("\033[999D\033[2A\033[1B\033[1C\033[7m⠀⠀\033[0m\n"))
+
+		// Clearing the highlight reprints the same cells plainly.
+		out.Reset()
+		Expect(printer.Highlight()).To(Succeed())
+		Expect(out.String()).To(Equal("\033[999D\033[2A\033[1B\033[1C⠀⠀\n"))
+	})
+
+	It("should encode large images in parallel, in order", func() {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+		img := image.NewPaletted(image.Rect(0, 0, 400, 402), color.Palette{color.White, color.Black})
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i*i/7) & 1
+		}
+		flusher := dotmatrix.BrailleFlusher{Highlights: []dotmatrix.Highlight{{Cells: image.Rect(10, 10, 150, 90), SGR: "7"}}}
+		var whole, rows bytes.Buffer
+		Expect(flusher.Flush(&whole, img)).To(Succeed())
+		for y := 0; y < 402; y += 4 {
+			row := img.SubImage(image.Rect(0, y, 400, y+4))
+			Expect(flusher.Flush(&rows, row)).To(Succeed())
+		}
+		Expect(whole.String()).To(Equal(rows.String()))
+	})
+
+	It("should print the dark pixels of any image as dots", func() {
+		gray := image.NewGray(image.Rect(0, 0, 2, 4))
+		rgba := image.NewRGBA(gray.Rect)
+		for i, v := range []uint8{0x00, 0x7f, 0x80, 0xff} {
+			gray.SetGray(0, i, color.Gray{v})
+			gray.SetGray(1, i, color.Gray{0xff})
+			rgba.Set(0, i, color.Gray{v})
+			// Half transparent black is lighter than mid-gray over white.
+			rgba.Set(1, i, color.RGBA{0, 0, 0, 0x7f})
+		}
+		for _, img := range []image.Image{gray, rgba} {
+			var out bytes.Buffer
+			Expect(dotmatrix.BrailleFlusher{}.Flush(&out, img)).To(Succeed())
+			Expect(out.String()).To(Equal("⠃\n"))
+		}
+		Expect(dotmatrix.IsDot(color.Black)).To(BeTrue())
+		Expect(dotmatrix.IsDot(color.Transparent)).To(BeFalse())
+	})
+
+	It("should print blank cells as the config's blank", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.White, color.Black})
+		img.SetColorIndex(0, 0, 1)
+		var out bytes.Buffer
+		c := &dotmatrix.Config{Blank: ' ', Flusher: dotmatrix.BorderFlusher{}}
+		Expect(dotmatrix.NewPrinter(&out, c).Print(img)).To(Succeed())
+		Expect(strings.Split(out.String(), "\n")[1]).To(Equal("│⠁ │"))
+	})
+})
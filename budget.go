@@ -0,0 +1,60 @@
+package dotmatrix
+
+import (
+	"image"
+	"math"
+)
+
+// BudgetPolicy decides how a frame whose flushed output exceeds Config.ByteBudget
+// is degraded to fit, e.g. for serial links or MUD connections that can only carry
+// so many bytes per frame.
+type BudgetPolicy interface {
+	// Degrade returns a cheaper version of img, whose flushed output took size bytes
+	// against a budget of budget bytes, or nil to skip the frame altogether. img is
+	// drawn in the dotmatrix palette, and so must be the result. Degrade is called
+	// again with its result for as long as the output remains over budget.
+	Degrade(img image.Image, size, budget int) image.Image
+}
+
+// ShrinkPolicy degrades frames by shrinking them, which reduces the number of
+// braille cells to flush. It is the default policy.
+type ShrinkPolicy struct{}
+
+func (ShrinkPolicy) Degrade(img image.Image, size, budget int) image.Image {
+	// Output grows with the area of the image, so shrink both sides by the square
+	// root of the overshoot, and at least a little to guarantee progress.
+	scale := math.Min(math.Sqrt(float64(budget)/float64(size)), 0.9)
+	bounds := img.Bounds()
+	w := int(scale * float64(bounds.Dx()))
+	h := int(scale * float64(bounds.Dy()))
+	if w < 1 || h < 1 {
+		return nil
+	}
+	return subsample(img, w, h)
+}
+
+// SkipPolicy degrades frames by dropping them, so every frame that is printed is
+// printed at full resolution.
+type SkipPolicy struct{}
+
+func (SkipPolicy) Degrade(img image.Image, size, budget int) image.Image {
+	return nil
+}
+
+// subsample resizes img to w by h pixels by nearest neighbor sampling. Unlike most
+// resizing, it keeps the dotmatrix palette intact.
+func subsample(img image.Image, w, h int) *image.Paletted {
+	bounds := img.Bounds()
+	palette := defaultPalette
+	if p, ok := img.(*image.Paletted); ok {
+		palette = p.Palette
+	}
+	dst := image.NewPaletted(image.Rect(0, 0, w, h).Add(bounds.Min), palette)
+	for y := 0; y < h; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/h
+		for x := 0; x < w; x++ {
+			dst.Set(bounds.Min.X+x, bounds.Min.Y+y, img.At(bounds.Min.X+x*bounds.Dx()/w, sy))
+		}
+	}
+	return dst
+}
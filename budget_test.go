@@ -0,0 +1,85 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// halvingPolicy halves images until they fit, recording the sizes it was asked to
+// degrade from.
+type halvingPolicy struct {
+	sizes *[]int
+}
+
+func (p halvingPolicy) Degrade(img image.Image, size, budget int) image.Image {
+	*p.sizes = append(*p.sizes, size)
+	bounds := img.Bounds()
+	if bounds.Dx() < 2 || bounds.Dy() < 2 {
+		return nil
+	}
+	return dotmatrix.NearestNeighbor.Scale(img, bounds.Dx()/2, bounds.Dy()/2)
+}
+
+var _ = Describe("ByteBudget", func() {
+	// A 20x10 cell pattern, which prints in 610 bytes.
+	img := image.NewPaletted(image.Rect(0, 0, 40, 40), color.Palette{color.White, color.Black})
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i % 3 & 1)
+	}
+	printWith := func(c *dotmatrix.Config) string {
+		var out bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&out, c).Print(img)).To(Succeed())
+		return out.String()
+	}
+
+	It("should shrink images until their output fits the budget", func() {
+		full := printWith(nil)
+		Expect(full).To(HaveLen(610))
+		Expect(printWith(&dotmatrix.Config{ByteBudget: 610})).To(Equal(full))
+		for _, budget := range []int{609, 400, 150, 40, 4} {
+			out := printWith(&dotmatrix.Config{ByteBudget: budget})
+			Expect(len(out)).To(BeNumerically("<=", budget), "budget %d", budget)
+			// Shrunk, not cropped: every row is as long as the others.
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			Expect(lines).NotTo(BeEmpty(), "budget %d", budget)
+			for _, line := range lines {
+				Expect(line).To(HaveLen(len(lines[0])), "budget %d", budget)
+			}
+			Expect(len(lines)).To(BeNumerically("<", 10), "budget %d", budget)
+		}
+	})
+
+	It("should print nothing once the image can't shrink any further", func() {
+		// A single cell takes 4 bytes, braille and line feed.
+		Expect(printWith(&dotmatrix.Config{ByteBudget: 3})).To(BeEmpty())
+	})
+
+	It("should count synchronized update escapes against the budget", func() {
+		out := printWith(&dotmatrix.Config{ByteBudget: 200, SynchronizedOutput: true})
+		Expect(out).To(HavePrefix("\033[?2026h"))
+		Expect(len(out)).To(BeNumerically("<=", 200))
+		// The escapes alone are over a budget this small.
+		Expect(printWith(&dotmatrix.Config{ByteBudget: 10, SynchronizedOutput: true})).To(BeEmpty())
+	})
+
+	It("should skip frames over budget with SkipPolicy", func() {
+		full := printWith(nil)
+		Expect(printWith(&dotmatrix.Config{ByteBudget: 609, BudgetPolicy: dotmatrix.SkipPolicy{}})).To(BeEmpty())
+		Expect(printWith(&dotmatrix.Config{ByteBudget: 610, BudgetPolicy: dotmatrix.SkipPolicy{}})).To(Equal(full))
+	})
+
+	It("should degrade with the policy for as long as the output is over budget", func() {
+		var sizes []int
+		out := printWith(&dotmatrix.Config{ByteBudget: 100, BudgetPolicy: halvingPolicy{&sizes}})
+		// 20x10 cells, then 10x5 and 5x3, which fits.
+		Expect(sizes).To(Equal([]int{610, 155}))
+		Expect(out).To(HaveLen(48))
+	})
+})
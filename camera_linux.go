@@ -0,0 +1,399 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// V4L2 ioctls, structures and constants, from linux/videodev2.h.
+const (
+	v4l2BufTypeVideoCapture = 1
+	v4l2MemoryMMAP          = 1
+	v4l2FieldAny            = 0
+	v4l2CapVideoCapture     = 0x00000001
+	v4l2CapStreaming        = 0x04000000
+	v4l2CapDeviceCaps       = 0x80000000
+)
+
+var (
+	v4l2PixFmtMJPEG = fourcc("MJPG")
+	v4l2PixFmtYUYV  = fourcc("YUYV")
+)
+
+func fourcc(s string) uint32 {
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
+
+type v4l2Capability struct {
+	driver       [16]uint8
+	card         [32]uint8
+	busInfo      [32]uint8
+	version      uint32
+	capabilities uint32
+	deviceCaps   uint32
+	reserved     [3]uint32
+}
+
+type v4l2PixFormat struct {
+	width        uint32
+	height       uint32
+	pixelformat  uint32
+	field        uint32
+	bytesperline uint32
+	sizeimage    uint32
+	colorspace   uint32
+	priv         uint32
+	flags        uint32
+	ycbcrEnc     uint32
+	quantization uint32
+	xferFunc     uint32
+}
+
+type v4l2Format struct {
+	typ uint32
+	// fmt is a union of 200 bytes, aligned for the pointers in some of its members.
+	fmt struct {
+		_   [0]uintptr
+		pix v4l2PixFormat
+		_   [200 - unsafe.Sizeof(v4l2PixFormat{})]byte
+	}
+}
+
+type v4l2RequestBuffers struct {
+	count        uint32
+	typ          uint32
+	memory       uint32
+	capabilities uint32
+	flags        uint8
+	reserved     [3]uint8
+}
+
+type v4l2Timecode struct {
+	typ      uint32
+	flags    uint32
+	frames   uint8
+	seconds  uint8
+	minutes  uint8
+	hours    uint8
+	userbits [4]uint8
+}
+
+type v4l2Buffer struct {
+	index     uint32
+	typ       uint32
+	bytesused uint32
+	flags     uint32
+	field     uint32
+	timestamp syscall.Timeval
+	timecode  v4l2Timecode
+	sequence  uint32
+	memory    uint32
+	// m is a union of a pointer and smaller members, including the offset of MMAP
+	// buffers.
+	m         uintptr
+	length    uint32
+	reserved2 uint32
+	requestFD int32
+}
+
+// offset returns the m.offset member of the buffer.
+func (b *v4l2Buffer) offset() int64 {
+	return int64(*(*uint32)(unsafe.Pointer(&b.m)))
+}
+
+// ioctl request numbers, encoded as on most architectures: direction, size, type
+// and number.
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'V'<<8 | nr
+}
+
+const (
+	iocWrite = 1
+	iocRead  = 2
+)
+
+var (
+	vidiocQuerycap  = ioc(iocRead, 0, unsafe.Sizeof(v4l2Capability{}))
+	vidiocSFmt      = ioc(iocRead|iocWrite, 5, unsafe.Sizeof(v4l2Format{}))
+	vidiocReqbufs   = ioc(iocRead|iocWrite, 8, unsafe.Sizeof(v4l2RequestBuffers{}))
+	vidiocQuerybuf  = ioc(iocRead|iocWrite, 9, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocQbuf      = ioc(iocRead|iocWrite, 15, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocDqbuf     = ioc(iocRead|iocWrite, 17, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocStreamon  = ioc(iocWrite, 18, unsafe.Sizeof(int32(0)))
+	vidiocStreamoff = ioc(iocWrite, 19, unsafe.Sizeof(int32(0)))
+)
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		default:
+			return errno
+		}
+	}
+}
+
+// cameraBuffers is how many buffers the camera captures into while frames are
+// printed.
+const cameraBuffers = 4
+
+// maxCorruptCameraFrames is how many corrupt frames in a row are skipped.
+const maxCorruptCameraFrames = 10
+
+// cameraTimeout is how long a camera may go without sending a frame before it is
+// given up on.
+const cameraTimeout = 10 * time.Second
+
+/*
+Camera captures frames from a webcam or other V4L2 video capture device, in
+MJPEG or YUYV, whichever the device offers. It is a FrameSource, so cameras play
+with a VideoPrinter or NewVideoPlayer. Frames are shown as they are captured, and
+when they are captured faster than they are printed, the latest is shown.
+
+Cameras are only supported on Linux.
+*/
+type Camera struct {
+	fd      uintptr
+	format  v4l2PixFormat
+	buffers [][]byte
+
+	img     image.Image
+	dht     []byte // the standard Huffman tables, for MJPEG frames that omit them
+	closed  bool
+	started bool
+}
+
+// OpenCamera opens a V4L2 device, such as /dev/video0, and asks it to capture at
+// the given size, which it adjusts to the nearest it supports.
+func OpenCamera(device string, width, height int) (*Camera, error) {
+	// The device is read without blocking, so waits for frames can time out.
+	fd, err := syscall.Open(device, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: device, Err: err}
+	}
+	c := &Camera{fd: uintptr(fd)}
+	if err := c.init(width, height); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("dotmatrix: %s: %v", device, err)
+	}
+	return c, nil
+}
+
+func (c *Camera) init(width, height int) error {
+	fd := c.fd
+
+	var capability v4l2Capability
+	if err := ioctl(fd, vidiocQuerycap, unsafe.Pointer(&capability)); err != nil {
+		return err
+	}
+	caps := capability.capabilities
+	if caps&v4l2CapDeviceCaps != 0 {
+		caps = capability.deviceCaps
+	}
+	if caps&v4l2CapVideoCapture == 0 || caps&v4l2CapStreaming == 0 {
+		return errors.New("not a video capture device that can stream")
+	}
+
+	// MJPEG is preferred, as cameras can send it faster at larger sizes.
+	for _, pixelformat := range []uint32{v4l2PixFmtMJPEG, v4l2PixFmtYUYV} {
+		format := v4l2Format{typ: v4l2BufTypeVideoCapture}
+		format.fmt.pix = v4l2PixFormat{
+			width:       uint32(width),
+			height:      uint32(height),
+			pixelformat: pixelformat,
+			field:       v4l2FieldAny,
+		}
+		if err := ioctl(fd, vidiocSFmt, unsafe.Pointer(&format)); err != nil {
+			return err
+		}
+		if format.fmt.pix.pixelformat == pixelformat {
+			c.format = format.fmt.pix
+			break
+		}
+	}
+	switch c.format.pixelformat {
+	case v4l2PixFmtMJPEG:
+		c.dht = standardDHT()
+	case v4l2PixFmtYUYV:
+		if c.format.bytesperline < c.format.width*2 {
+			c.format.bytesperline = c.format.width * 2
+		}
+	default:
+		return errors.New("captures neither MJPEG nor YUYV")
+	}
+
+	req := v4l2RequestBuffers{count: cameraBuffers, typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+	if err := ioctl(fd, vidiocReqbufs, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	if req.count == 0 {
+		return errors.New("no capture buffers")
+	}
+	for i := uint32(0); i < req.count; i++ {
+		buf := v4l2Buffer{index: i, typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+		if err := ioctl(fd, vidiocQuerybuf, unsafe.Pointer(&buf)); err != nil {
+			return err
+		}
+		data, err := syscall.Mmap(int(fd), buf.offset(), int(buf.length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+		if err != nil {
+			return err
+		}
+		c.buffers = append(c.buffers, data)
+		if err := ioctl(fd, vidiocQbuf, unsafe.Pointer(&buf)); err != nil {
+			return err
+		}
+	}
+
+	typ := int32(v4l2BufTypeVideoCapture)
+	if err := ioctl(fd, vidiocStreamon, unsafe.Pointer(&typ)); err != nil {
+		return err
+	}
+	c.started = true
+	return nil
+}
+
+// NextFrame waits for the next frame to be captured. Any frames captured since
+// the last call but the latest are skipped.
+func (c *Camera) NextFrame() (image.Image, time.Duration, error) {
+	if c.closed {
+		return nil, 0, os.ErrClosed
+	}
+	// Cameras sometimes send corrupt frames, especially as they start, so a few in
+	// a row are skipped before giving up.
+	for skipped := 0; ; skipped++ {
+		buf, err := c.dequeue()
+		if err != nil {
+			return nil, 0, err
+		}
+		data := c.buffers[buf.index][:buf.bytesused]
+		if c.format.pixelformat != v4l2PixFmtMJPEG {
+			img := c.yuyv(data)
+			return img, 0, ioctl(c.fd, vidiocQbuf, unsafe.Pointer(&buf))
+		}
+		img, err := jpeg.Decode(bytes.NewReader(c.withDHT(data)))
+		if qerr := ioctl(c.fd, vidiocQbuf, unsafe.Pointer(&buf)); qerr != nil {
+			return nil, 0, qerr
+		}
+		if err == nil {
+			return img, 0, nil
+		}
+		if skipped == maxCorruptCameraFrames {
+			return nil, 0, err
+		}
+	}
+}
+
+// dequeue waits for a captured buffer, then takes any others already captured,
+// returning the latest and giving the rest back to the camera.
+func (c *Camera) dequeue() (v4l2Buffer, error) {
+	fd := c.fd
+	deadline := time.Now().Add(cameraTimeout)
+	var latest v4l2Buffer
+	have := false
+	for {
+		buf := v4l2Buffer{typ: v4l2BufTypeVideoCapture, memory: v4l2MemoryMMAP}
+		err := ioctl(fd, vidiocDqbuf, unsafe.Pointer(&buf))
+		if err == nil {
+			if have {
+				ioctl(fd, vidiocQbuf, unsafe.Pointer(&latest))
+			}
+			latest, have = buf, true
+			continue
+		}
+		if err != syscall.EAGAIN {
+			return latest, err
+		}
+		if have {
+			return latest, nil
+		}
+		if time.Now().After(deadline) {
+			return latest, errors.New("dotmatrix: camera stopped sending frames")
+		}
+		// Wait for the camera to capture a frame.
+		var fds syscall.FdSet
+		bits := unsafe.Sizeof(fds.Bits[0]) * 8
+		fds.Bits[fd/bits] |= 1 << (fd % bits)
+		timeout := syscall.NsecToTimeval(int64(100 * time.Millisecond))
+		if _, err := syscall.Select(int(fd)+1, &fds, nil, nil, &timeout); err != nil && err != syscall.EINTR {
+			return latest, err
+		}
+	}
+}
+
+// withDHT returns an MJPEG frame with the standard Huffman tables, which many
+// cameras leave out, inserted before its first scan if it has none.
+func (c *Camera) withDHT(data []byte) []byte {
+	if bytes.Contains(data, []byte{0xff, 0xc4}) {
+		return data
+	}
+	sos := bytes.Index(data, []byte{0xff, 0xda})
+	if sos < 0 {
+		return data
+	}
+	frame := make([]byte, 0, len(data)+len(c.dht))
+	frame = append(frame, data[:sos]...)
+	frame = append(frame, c.dht...)
+	return append(frame, data[sos:]...)
+}
+
+// yuyv converts a YUYV frame, of two pixels in each Y, U, Y, V, to an image.
+func (c *Camera) yuyv(data []byte) image.Image {
+	w, h, stride := int(c.format.width), int(c.format.height), int(c.format.bytesperline)
+	img, ok := c.img.(*image.YCbCr)
+	if !ok {
+		img = image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio422)
+		c.img = img
+	}
+	for y := 0; y < h && (y+1)*stride <= len(data); y++ {
+		row := data[y*stride:]
+		for x := 0; x+1 < w; x += 2 {
+			i := x * 2
+			img.Y[y*img.YStride+x] = row[i]
+			img.Y[y*img.YStride+x+1] = row[i+2]
+			img.Cb[y*img.CStride+x/2] = row[i+1]
+			img.Cr[y*img.CStride+x/2] = row[i+3]
+		}
+	}
+	return img
+}
+
+// Close stops capturing and closes the device.
+func (c *Camera) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.started {
+		typ := int32(v4l2BufTypeVideoCapture)
+		ioctl(c.fd, vidiocStreamoff, unsafe.Pointer(&typ))
+	}
+	for _, data := range c.buffers {
+		syscall.Munmap(data)
+	}
+	return syscall.Close(int(c.fd))
+}
+
+// standardDHT returns a DHT segment of the standard Huffman tables, from the jpeg
+// encoder, which always uses them.
+func standardDHT() []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio420), nil)
+	data := buf.Bytes()
+	i := bytes.Index(data, []byte{0xff, 0xc4})
+	if i < 0 || i+4 > len(data) {
+		return nil
+	}
+	n := int(data[i+2])<<8 | int(data[i+3])
+	return data[i : i+2+n]
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+// +build !linux
+
+package dotmatrix
+
+import (
+	"errors"
+	"image"
+	"time"
+)
+
+var errNoCameras = errors.New("dotmatrix: cameras are only supported on Linux")
+
+// Camera captures frames from a video capture device. Cameras are only supported
+// on Linux.
+type Camera struct{}
+
+// OpenCamera fails, as cameras are only supported on Linux.
+func OpenCamera(device string, width, height int) (*Camera, error) {
+	return nil, errNoCameras
+}
+
+func (c *Camera) NextFrame() (image.Image, time.Duration, error) {
+	return nil, 0, errNoCameras
+}
+
+func (c *Camera) Close() error {
+	return nil
+}
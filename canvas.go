@@ -0,0 +1,119 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"unicode/utf8"
+)
+
+/*
+Canvas is a grid of dots to draw on directly, like Python's drawille, for charts,
+games and other drawings that aren't images to begin with. Each braille cell holds
+2x4 dots, which are set, unset and toggled by their x, y coordinates, counting from
+the top left, or drawn in shapes with Line, Rect, FillRect, Circle, Ellipse and
+Polygon. Dots outside the canvas are ignored. Eg:
+
+	canvas := dotmatrix.NewCanvas(80, 40)
+	for x := 0; x < 80; x++ {
+		canvas.Set(x, 20+int(15*math.Sin(float64(x)/8)))
+	}
+	canvas.Flush(os.Stdout)
+
+A Canvas is also an image, with black dots on white, so it can be printed, scaled
+and filtered like any other.
+*/
+type Canvas struct {
+	width, height int
+	cols          int
+	cells         []uint8 // the dots of each cell, as the bits of its braille rune
+}
+
+// NewCanvas returns a blank canvas width dots wide and height dots tall. Negative
+// sizes are treated as 0.
+func NewCanvas(width, height int) *Canvas {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+	cols, rows := (width+1)/2, (height+3)/4
+	return &Canvas{width: width, height: height, cols: cols, cells: make([]uint8, cols*rows)}
+}
+
+// cell returns the index of the cell holding the dot at x, y, and the dot's bit,
+// or false if the dot is outside the canvas.
+func (c *Canvas) cell(x, y int) (int, uint8, bool) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return 0, 0, false
+	}
+	return y/4*c.cols + x/2, brailleDots[x%2][y%4], true
+}
+
+// Set raises the dot at x, y.
+func (c *Canvas) Set(x, y int) {
+	if i, bit, ok := c.cell(x, y); ok {
+		c.cells[i] |= bit
+	}
+}
+
+// Unset lowers the dot at x, y.
+func (c *Canvas) Unset(x, y int) {
+	if i, bit, ok := c.cell(x, y); ok {
+		c.cells[i] &^= bit
+	}
+}
+
+// Toggle raises the dot at x, y if it is lowered, and lowers it if it is raised.
+func (c *Canvas) Toggle(x, y int) {
+	if i, bit, ok := c.cell(x, y); ok {
+		c.cells[i] ^= bit
+	}
+}
+
+// Get reports whether the dot at x, y is raised.
+func (c *Canvas) Get(x, y int) bool {
+	i, bit, ok := c.cell(x, y)
+	return ok && c.cells[i]&bit != 0
+}
+
+// Clear lowers every dot.
+func (c *Canvas) Clear() {
+	for i := range c.cells {
+		c.cells[i] = 0
+	}
+}
+
+// Flush writes the canvas to w in braille, a row of cells to a line, as a
+// BrailleFlusher prints it.
+func (c *Canvas) Flush(w io.Writer) error {
+	line := make([]byte, 0, c.cols*utf8.UTFMax+1)
+	for i := 0; i < len(c.cells); i += c.cols {
+		line = line[:0]
+		for _, dots := range c.cells[i : i+c.cols] {
+			line = appendRune(line, '⠀'+rune(dots))
+		}
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Canvas) ColorModel() color.Model {
+	return color.Palette(defaultPalette)
+}
+
+func (c *Canvas) Bounds() image.Rectangle {
+	return image.Rect(0, 0, c.width, c.height)
+}
+
+// At returns black for raised dots and white for the rest.
+func (c *Canvas) At(x, y int) color.Color {
+	if c.Get(x, y) {
+		return color.Black
+	}
+	return color.White
+}
@@ -0,0 +1,48 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Canvas", func() {
+	It("should set, unset and toggle dots", func() {
+		canvas := dotmatrix.NewCanvas(3, 5)
+		canvas.Set(0, 0)
+		canvas.Set(1, 3)
+		canvas.Set(2, 4)
+		canvas.Set(1, 1)
+		canvas.Unset(1, 1)
+		canvas.Toggle(0, 1)
+		canvas.Toggle(0, 0)
+		canvas.Set(3, 0) // outside the canvas
+		Expect(canvas.Get(0, 1)).To(BeTrue())
+		Expect(canvas.Get(0, 0)).To(BeFalse())
+
+		var out bytes.Buffer
+		Expect(canvas.Flush(&out)).To(Succeed())
+		Expect(out.String()).To(Equal("⢂⠀\n⠀⠁\n"))
+
+		var printed bytes.Buffer
+		Expect(dotmatrix.Print(&printed, canvas)).To(Succeed())
+		Expect(printed.String()).To(Equal(out.String()))
+
+		canvas.Clear()
+		out.Reset()
+		Expect(canvas.Flush(&out)).To(Succeed())
+		Expect(out.String()).To(Equal("⠀⠀\n⠀⠀\n"))
+	})
+
+	It("should treat negative sizes as empty", func() {
+		canvas := dotmatrix.NewCanvas(-5, 4)
+		canvas.Set(0, 0)
+		Expect(canvas.Bounds().Empty()).To(BeTrue())
+		var out bytes.Buffer
+		Expect(canvas.Flush(&out)).To(Succeed())
+		Expect(out.String()).To(BeEmpty())
+	})
+})
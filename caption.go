@@ -0,0 +1,89 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Corner identifies a corner of an image.
+type Corner int
+
+const (
+	TopLeft Corner = iota
+	TopRight
+	BottomLeft
+	BottomRight
+)
+
+// place moves r, whose minimum point is the origin, into this corner of bounds.
+func (c Corner) place(r, bounds image.Rectangle) image.Rectangle {
+	switch c {
+	case TopRight:
+		return r.Add(image.Pt(bounds.Max.X-r.Dx(), bounds.Min.Y))
+	case BottomLeft:
+		return r.Add(image.Pt(bounds.Min.X, bounds.Max.Y-r.Dy()))
+	case BottomRight:
+		return r.Add(bounds.Max.Sub(r.Max))
+	default:
+		return r.Add(bounds.Min)
+	}
+}
+
+// CaptionFilter draws a line of text in one corner of the image, in black on a white
+// box, using a small built-in 7x13 pixel font. It's useful for labeling streams and
+// screenshots. The text is drawn at the image's current resolution, so the filter
+// should be applied after any resizing. Text that doesn't fit is clipped.
+type CaptionFilter struct {
+	// Text may span several lines, separated by line feeds.
+	Text   string
+	Corner Corner
+}
+
+func (f CaptionFilter) Filter(img image.Image) image.Image {
+	if f.Text == "" {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	drawText(dst, f.Text, f.Corner)
+	return dst
+}
+
+// drawText draws text into a corner of dst, in black on a white box, and returns the
+// box's bounds.
+func drawText(dst draw.Image, text string, corner Corner) image.Rectangle {
+	face := basicfont.Face7x13
+	lines := strings.Split(text, "\n")
+	lineHeight := face.Metrics().Height.Ceil()
+	box := corner.place(textBox(text), dst.Bounds())
+
+	draw.Draw(dst, box, image.White, image.ZP, draw.Src)
+	d := font.Drawer{Dst: dst, Src: image.Black, Face: face}
+	for i, line := range lines {
+		d.Dot = fixed.P(box.Min.X+textPad, box.Min.Y+textPad+i*lineHeight+face.Metrics().Ascent.Ceil())
+		d.DrawString(line)
+	}
+	return box.Intersect(dst.Bounds())
+}
+
+// Text is padded by a pixel so it stands apart from the image.
+const textPad = 1
+
+// textBox returns the size of the box that drawText draws text in, at the origin.
+func textBox(text string) image.Rectangle {
+	face := basicfont.Face7x13
+	lines := strings.Split(text, "\n")
+	var width int
+	for _, line := range lines {
+		if w := font.MeasureString(face, line).Ceil(); w > width {
+			width = w
+		}
+	}
+	return image.Rect(0, 0, width+2*textPad, len(lines)*face.Metrics().Height.Ceil()+2*textPad)
+}
@@ -0,0 +1,51 @@
+package dotmatrix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// castHeader is the first line of an asciinema v2 cast.
+type castHeader struct {
+	Version int `json:"version"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+}
+
+/*
+WriteCast writes a recording as an asciinema v2 cast, which can be published on
+asciinema.org or embedded with asciinema-player. Each frame is an output event,
+timed by the delays of the frames before it, that moves the cursor back up over
+the last frame with the config's Reset and prints over it.
+
+The terminal is a row taller than the first frame, so that printing the frame's
+last line doesn't scroll its first out of reach.
+*/
+func WriteCast(w io.Writer, rec *Recording, c *Config) error {
+	config := mergeConfig(c)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(castHeader{Version: 2, Width: rec.Cols, Height: rec.Rows + 1}); err != nil {
+		return err
+	}
+
+	var at time.Duration
+	var out bytes.Buffer
+	for i, f := range rec.Frames {
+		out.Reset()
+		if i > 0 {
+			config.Reset(&out, bytes.Count(rec.Frames[i-1].Text, []byte("\n")))
+		}
+		// Casts are replayed without the tty translating newlines, so each line
+		// returns the cursor itself.
+		out.Write(bytes.Replace(f.Text, []byte("\n"), []byte("\r\n"), -1))
+		if err := enc.Encode([]interface{}{at.Seconds(), "o", out.String()}); err != nil {
+			return err
+		}
+		at += f.Delay
+	}
+	return bw.Flush()
+}
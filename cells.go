@@ -0,0 +1,85 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+)
+
+/*
+ForEachCell calls fn for each cellW by cellH pixel cell of bounds, left to right and
+top to bottom, starting from bounds.Min. col and row count cells from zero. Cells on
+the right and bottom edges are clipped to bounds, so the last cell of each row is
+the one whose Max.X is bounds.Max.X. Iteration stops at the first error fn returns,
+which ForEachCell returns.
+
+Flushers can use it to walk an image one character at a time. Eg:
+
+	err := dotmatrix.ForEachCell(img.Bounds(), 2, 4, func(col, row int, cell image.Rectangle) error {
+		fmt.Fprint(w, dotmatrix.BrailleOf(img, cell))
+		if cell.Max.X == img.Bounds().Max.X {
+			fmt.Fprintln(w)
+		}
+		return nil
+	})
+*/
+func ForEachCell(bounds image.Rectangle, cellW, cellH int, fn func(col, row int, cell image.Rectangle) error) error {
+	// Looping over Y first and X second is more likely to result in better memory
+	// access patterns than X first and Y second.
+	for row, y := 0, bounds.Min.Y; y < bounds.Max.Y; row, y = row+1, y+cellH {
+		for col, x := 0, bounds.Min.X; x < bounds.Max.X; col, x = col+1, x+cellW {
+			cell := image.Rect(x, y, x+cellW, y+cellH).Intersect(bounds)
+			if err := fn(col, row, cell); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BrailleOf returns the braille pattern of the dark pixels in a cell of img, whose
+// top left pixel is the pattern's (0,0). Pixels beyond the pattern's 2x4 dots, or
+// outside the cell, are ignored. See IsDot.
+func BrailleOf(img image.Image, cell image.Rectangle) Braille {
+	switch p := img.(type) {
+	case *image.Paletted:
+		if b, ok := palettedBraille(p, cell); ok {
+			return b
+		}
+	case *image.Gray:
+		if b, ok := grayBraille(p, cell); ok {
+			return b
+		}
+	}
+	var b Braille
+	for y := cell.Min.Y; y < cell.Max.Y && y-cell.Min.Y < 4; y++ {
+		for x := cell.Min.X; x < cell.Max.X && x-cell.Min.X < 2; x++ {
+			if IsDot(img.At(x, y)) {
+				b[x-cell.Min.X][y-cell.Min.Y] = 1
+			}
+		}
+	}
+	return b
+}
+
+// IsDot reports whether c is printed as a dot: whether it is darker than mid-gray
+// once composited over white. Black is a dot, and white and transparent aren't, so
+// images drawn in the dotmatrix palette print as drawn, but any image can be
+// flushed, eg: a gray or RGBA image thresholded by a custom Drawer.
+func IsDot(c color.Color) bool {
+	// Always bet on black!
+	switch c {
+	case color.Black:
+		return true
+	case color.White, color.Transparent:
+		return false
+	}
+	return luminance(c) < 0x80
+}
+
+// luminance returns the gray level of c once composited over white, weighing the
+// channels as color.GrayModel does, so that transparent pixels count as blank.
+func luminance(c color.Color) uint8 {
+	r, g, b, a := c.RGBA()
+	r, g, b = r+0xffff-a, g+0xffff-a, b+0xffff-a
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
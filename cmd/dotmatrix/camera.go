@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func cameraCommand() cli.Command {
+	return cli.Command{
+		Name:  "camera",
+		Usage: "Play a webcam, captured directly with V4L2 on Linux.",
+		Description: "Captures MJPEG or YUYV frames from the device, whichever it offers, and shows the latest\n" +
+			"   as fast as the terminal keeps up. The rendering flags apply as usual.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "device",
+				Usage: "The video capture device.",
+				Value: "/dev/video0",
+			},
+			cli.StringFlag{
+				Name:  "size",
+				Usage: "The size to capture at, as WxH, which the device adjusts to the nearest it supports.",
+				Value: "640x480",
+			},
+		},
+		Action: cameraAction,
+	}
+}
+
+func cameraAction(c *cli.Context) error {
+	var width, height int
+	if _, err := fmt.Sscanf(c.String("size"), "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid size: %q", c.String("size"))
+	}
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+	cfg.Name = c.String("device")
+	camera, err := dotmatrix.OpenCamera(c.String("device"), width, height)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleInterrupt(cancel)
+
+	// The player closes the camera when it stops.
+	return play(ctx, cfg, dotmatrix.NewVideoPlayer(os.Stdout, cfg, camera))
+}
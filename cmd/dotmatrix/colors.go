@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func colorsCommand() cli.Command {
+	return cli.Command{
+		Name:      "colors",
+		Usage:     "Compare how a frame's colors survive truecolor, 256 and 16 color escape codes, eg: to pick a color mode or tune a theme.",
+		ArgsUsage: "<image>",
+		Description: "Averages the color of each braille cell of the frame, prints the cells as backgrounds in each\n" +
+			"   mode's escape codes, decodes what was printed as a terminal would show it, and reports the\n" +
+			"   error per mode, as the distance between the original and shown colors in RGB. Only cell\n" +
+			"   backgrounds are measured: the braille itself is printed in the terminal's own colors.",
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "frame",
+				Usage: "The frame of a gif to compare.",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the color and error of every cell as JSON instead.",
+			},
+			cli.BoolFlag{
+				Name:  "preview",
+				Usage: "Also print the cells as each mode would color them.",
+			},
+		},
+		Action: colorsAction,
+	}
+}
+
+// colorMode quantizes colors for a class of terminal.
+type colorMode struct {
+	Name    string
+	palette color.Palette // nil for truecolor
+}
+
+var colorModes = []colorMode{
+	{Name: "truecolor"},
+	{Name: "256", palette: dotmatrix.ANSIPalette(256)},
+	{Name: "16", palette: dotmatrix.ANSIPalette(16)},
+}
+
+// quantize returns the color the mode shows for c, and its palette index.
+func (m colorMode) quantize(c color.RGBA) (color.RGBA, int) {
+	if m.palette == nil {
+		return c, -1
+	}
+	i := m.palette.Index(c)
+	return color.RGBAModel.Convert(m.palette[i]).(color.RGBA), i
+}
+
+// sgr returns the escape code that sets the background to c, which is palette
+// index i of the mode.
+func (m colorMode) sgr(c color.RGBA, i int) string {
+	switch {
+	case m.palette == nil:
+		return fmt.Sprintf("\033[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	case len(m.palette) > 16:
+		return fmt.Sprintf("\033[48;5;%dm", i)
+	case i < 8:
+		return fmt.Sprintf("\033[%dm", 40+i)
+	default:
+		return fmt.Sprintf("\033[%dm", 100+i-8)
+	}
+}
+
+type cellColor struct {
+	Color string  `json:"color"`
+	Error float64 `json:"error"`
+}
+
+// modeReport is the result of quantizing every cell for a color mode. Cells that
+// are entirely transparent are nil.
+type modeReport struct {
+	Mode  string         `json:"mode"`
+	Mean  float64        `json:"mean"`
+	P95   float64        `json:"p95"`
+	Max   float64        `json:"max"`
+	Cells [][]*cellColor `json:"cells"`
+
+	mode colorMode
+}
+
+func colorsAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.ShowCommandHelp(c, "colors")
+	}
+	frames, err := sequence(c.Args().First())
+	if err != nil {
+		return err
+	}
+	n := c.Int("frame")
+	if n < 0 || n >= len(frames) {
+		return fmt.Errorf("no frame %d: there are %d frames", n, len(frames))
+	}
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+
+	cells := averageCells(cfg.Filter.Filter(frames[n]))
+	var reports []*modeReport
+	for _, mode := range colorModes {
+		report, err := compareColors(cells, mode)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	fmt.Fprintf(os.Stdout, "%-10s %8s %8s %8s\n", "mode", "mean", "p95", "max")
+	for _, r := range reports {
+		fmt.Fprintf(os.Stdout, "%-10s %8.2f %8.2f %8.2f\n", r.Mode, r.Mean, r.P95, r.Max)
+	}
+	if c.Bool("preview") {
+		for _, r := range reports {
+			fmt.Fprintf(os.Stdout, "\n%s:\n", r.Mode)
+			previewColors(os.Stdout, cells, r.mode)
+		}
+	}
+	return nil
+}
+
+// averageCells returns the average color of the opaque pixels of each braille
+// cell of img, in rows. Cells without opaque pixels are nil.
+func averageCells(img image.Image) [][]*color.RGBA {
+	var cells [][]*color.RGBA
+	dotmatrix.ForEachCell(img.Bounds(), 2, 4, func(col, row int, cell image.Rectangle) error {
+		if col == 0 {
+			cells = append(cells, nil)
+		}
+		var r, g, b, n uint32
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			for x := cell.Min.X; x < cell.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if c.A < 0x80 {
+					continue
+				}
+				r, g, b, n = r+uint32(c.R), g+uint32(c.G), b+uint32(c.B), n+1
+			}
+		}
+		var avg *color.RGBA
+		if n > 0 {
+			avg = &color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xff}
+		}
+		cells[row] = append(cells[row], avg)
+		return nil
+	})
+	return cells
+}
+
+// compareColors prints each cell in mode's escape codes and summarizes the errors
+// of the colors they decode to, so that what is measured is what was printed.
+func compareColors(cells [][]*color.RGBA, mode colorMode) (*modeReport, error) {
+	var printed bytes.Buffer
+	previewColors(&printed, cells, mode)
+	shown, err := dotmatrix.DecodeANSI(&printed)
+	if err != nil {
+		return nil, err
+	}
+	// DecodeANSI draws each character as a block of pixels.
+	var cellWidth, cellHeight int
+	if len(cells) > 0 && len(cells[0]) > 0 {
+		cellWidth, cellHeight = shown.Bounds().Dx()/len(cells[0]), shown.Bounds().Dy()/len(cells)
+	}
+
+	report := &modeReport{Mode: mode.Name, mode: mode}
+	var errs []float64
+	for y, row := range cells {
+		out := make([]*cellColor, len(row))
+		for x, c := range row {
+			if c == nil {
+				continue
+			}
+			q := color.RGBAModel.Convert(shown.At(x*cellWidth, y*cellHeight)).(color.RGBA)
+			dr, dg, db := float64(c.R)-float64(q.R), float64(c.G)-float64(q.G), float64(c.B)-float64(q.B)
+			e := math.Sqrt(dr*dr + dg*dg + db*db)
+			out[x] = &cellColor{Color: fmt.Sprintf("#%02x%02x%02x", q.R, q.G, q.B), Error: e}
+			errs = append(errs, e)
+		}
+		report.Cells = append(report.Cells, out)
+	}
+	if len(errs) == 0 {
+		return report, nil
+	}
+	sort.Float64s(errs)
+	var sum float64
+	for _, e := range errs {
+		sum += e
+	}
+	report.Mean = sum / float64(len(errs))
+	report.P95 = errs[(len(errs)*95-1)/100]
+	report.Max = errs[len(errs)-1]
+	return report, nil
+}
+
+// previewColors prints a space for each cell, with the background color mode
+// would show it in.
+func previewColors(w io.Writer, cells [][]*color.RGBA, mode colorMode) {
+	for _, row := range cells {
+		var line strings.Builder
+		for _, c := range row {
+			if c == nil {
+				line.WriteString("\033[0m ")
+				continue
+			}
+			q, i := mode.quantize(*c)
+			line.WriteString(mode.sgr(q, i) + " ")
+		}
+		line.WriteString("\033[0m")
+		fmt.Fprintln(w, line.String())
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("compareColors", func() {
+	It("should measure the colors each mode's escapes decode to", func() {
+		cells := [][]*color.RGBA{{
+			{R: 0xff, A: 0xff},
+			{R: 0x12, G: 0x34, B: 0x56, A: 0xff},
+			nil,
+			// Bright white, printed with the aixterm codes in 16 color mode.
+			{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		}}
+		var errs []float64
+		for _, mode := range colorModes {
+			report, err := compareColors(cells, mode)
+			Expect(err).NotTo(HaveOccurred())
+			row := report.Cells[0]
+			Expect(row).To(HaveLen(4))
+			Expect(row[0]).To(Equal(&cellColor{Color: "#ff0000", Error: 0}), mode.Name)
+			Expect(row[2]).To(BeNil(), mode.Name)
+			Expect(row[3]).To(Equal(&cellColor{Color: "#ffffff", Error: 0}), mode.Name)
+			Expect(report.Max).To(Equal(row[1].Error), mode.Name)
+			errs = append(errs, row[1].Error)
+		}
+		// Truecolor shows the color exactly, and fewer colors show it worse.
+		Expect(errs[0]).To(BeZero())
+		Expect(errs[1]).To(BeNumerically(">", 0))
+		Expect(errs[2]).To(BeNumerically(">", errs[1]))
+	})
+})
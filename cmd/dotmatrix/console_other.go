@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// interruptSignals are the signals that stop playback.
+var interruptSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL}
+
+// reraise delivers s to the process again, once it has been handled, so that the
+// process exits as the signal would have it.
+func reraise(s os.Signal) {
+	// All Signals returned by the signal package should be of type syscall.Signal
+	if signum, ok := s.(syscall.Signal); ok {
+		// Calling os.Exit here would be a bad idea if there are other goroutines
+		// waiting to catch the same signal.
+		syscall.Kill(syscall.Getpid(), signum)
+	} else {
+		panic(fmt.Sprintf("unexpected signal: %v", s))
+	}
+}
+
+// enableVirtualTerminal does nothing, as terminals here process ANSI escapes
+// already.
+func enableVirtualTerminal() {}
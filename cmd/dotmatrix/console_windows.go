@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+const (
+	enableVirtualTerminalProcessing = 0x0004
+	// statusControlCExit is the exit status of console programs ended by Ctrl-C.
+	statusControlCExit = 0xc000013a
+)
+
+// interruptSignals are the signals that stop playback. Ctrl-C and closing the
+// console both arrive as os.Interrupt.
+var interruptSignals = []os.Signal{os.Interrupt}
+
+// reraise exits as if interrupted, as signals can't be delivered again here.
+func reraise(s os.Signal) {
+	os.Exit(statusControlCExit)
+}
+
+// enableVirtualTerminal turns on the processing of ANSI escapes in the consoles
+// that stdout and stderr write to, which Windows leaves off by default. Consoles
+// too old to process them print the escapes as they are.
+func enableVirtualTerminal() {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		var mode uint32
+		if r, _, _ := procGetConsoleMode.Call(f.Fd(), uintptr(unsafe.Pointer(&mode))); r == 0 {
+			continue
+		}
+		procSetConsoleMode.Call(f.Fd(), uintptr(mode|enableVirtualTerminalProcessing))
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/nfnt/resize"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func diffCommand() cli.Command {
+	return cli.Command{
+		Name:      "diff",
+		Usage:     "Compare two images or gifs frame by frame, eg: to review visual regression artifacts.",
+		ArgsUsage: "<expected> <actual>",
+		Description: "Renders each pair of frames and counts the braille cells that differ, then shows the\n" +
+			"   worst frames side by side with the changed cells highlighted. Exits non-zero if any differ.",
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "worst",
+				Usage: "Number of the most changed frames to show side by side.",
+				Value: 3,
+			},
+		},
+		Action: diffAction,
+	}
+}
+
+func diffAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.ShowCommandHelp(c, "diff")
+	}
+	expected, err := sequence(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	actual, err := sequence(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+	// Frames are printed side by side, and only the art is compared.
+	cols, rows := terminalDimensions()
+	filter := cfg.Filter.(*Filter)
+	filter.Cols, filter.Rows = (cols-3)/2, rows
+	cfg.Stamp, cfg.AltText = dotmatrix.Stamp{}, nil
+
+	diffs, err := diffSequences(cfg, expected, actual)
+	if err != nil {
+		return err
+	}
+	return printDiffs(os.Stdout, diffs, len(expected), len(actual), c.Int("worst"))
+}
+
+// frameDiff compares the cells of a pair of rendered frames.
+type frameDiff struct {
+	frame    int
+	a, b     [][]rune
+	changed  [][]bool
+	count    int // of changed cells
+	total    int // of cells
+	unpaired bool
+}
+
+func diffSequences(cfg *dotmatrix.Config, expected, actual []image.Image) ([]frameDiff, error) {
+	n := len(expected)
+	if len(actual) > n {
+		n = len(actual)
+	}
+	size := expected[0].Bounds().Size()
+
+	var diffs []frameDiff
+	for i := 0; i < n; i++ {
+		var a, b [][]rune
+		var err error
+		if i < len(expected) {
+			if a, err = renderCells(cfg, expected[i]); err != nil {
+				return nil, err
+			}
+		}
+		if i < len(actual) {
+			img := actual[i]
+			// Compare like with like: scale the actual frames to the expected size.
+			if img.Bounds().Size() != size {
+				img = resize.Resize(uint(size.X), uint(size.Y), img, resize.Bilinear)
+			}
+			if b, err = renderCells(cfg, img); err != nil {
+				return nil, err
+			}
+		}
+
+		d := frameDiff{frame: i, a: a, b: b, unpaired: a == nil || b == nil}
+		rows := len(a)
+		if len(b) > rows {
+			rows = len(b)
+		}
+		d.changed = make([][]bool, rows)
+		for y := 0; y < rows; y++ {
+			var ra, rb []rune
+			if y < len(a) {
+				ra = a[y]
+			}
+			if y < len(b) {
+				rb = b[y]
+			}
+			cols := len(ra)
+			if len(rb) > cols {
+				cols = len(rb)
+			}
+			d.changed[y] = make([]bool, cols)
+			for x := 0; x < cols; x++ {
+				if x >= len(ra) || x >= len(rb) || ra[x] != rb[x] {
+					d.changed[y][x] = true
+					d.count++
+				}
+			}
+			d.total += cols
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// printDiffs prints a summary of every frame, then the worst frames side by side.
+func printDiffs(w io.Writer, diffs []frameDiff, expected, actual, worst int) error {
+	fmt.Fprintf(w, "frames: %d expected, %d actual\n", expected, actual)
+	fmt.Fprintf(w, "%6s %16s\n", "frame", "changed cells")
+	var changedFrames, changedCells int
+	for _, d := range diffs {
+		note := ""
+		if d.unpaired {
+			note = " (missing)"
+		}
+		fmt.Fprintf(w, "%6d %9d/%-6d%s\n", d.frame, d.count, d.total, note)
+		if d.count > 0 {
+			changedFrames++
+			changedCells += d.count
+		}
+	}
+	fmt.Fprintf(w, "%d of %d frames changed, %d cells in total\n", changedFrames, len(diffs), changedCells)
+	if changedFrames == 0 {
+		return nil
+	}
+
+	sorted := append([]frameDiff(nil), diffs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].count > sorted[j].count })
+	for _, d := range sorted {
+		if worst <= 0 || d.count == 0 {
+			break
+		}
+		worst--
+		fmt.Fprintf(w, "\nframe %d: %d of %d cells changed\n", d.frame, d.count, d.total)
+		width := 0
+		for _, row := range append(d.a, d.b...) {
+			if len(row) > width {
+				width = len(row)
+			}
+		}
+		for y := range d.changed {
+			fmt.Fprintf(w, "%s │ %s\n", highlightRow(d.a, d.changed, y, width), highlightRow(d.b, d.changed, y, 0))
+		}
+	}
+	return fmt.Errorf("%d of %d frames differ", changedFrames, len(diffs))
+}
+
+// highlightRow returns row y of cells, padded to width, with changed cells in
+// reverse video.
+func highlightRow(cells [][]rune, changed [][]bool, y, width int) string {
+	var row []rune
+	if y < len(cells) {
+		row = cells[y]
+	}
+	var s strings.Builder
+	var reversed bool
+	for x, r := range row {
+		if changed[y][x] != reversed {
+			reversed = changed[y][x]
+			if reversed {
+				s.WriteString("\033[7m")
+			} else {
+				s.WriteString("\033[0m")
+			}
+		}
+		s.WriteRune(r)
+	}
+	if reversed {
+		s.WriteString("\033[0m")
+	}
+	if n := width - len(row); n > 0 {
+		s.WriteString(strings.Repeat(" ", n))
+	}
+	return s.String()
+}
+
+// renderCells prints img and splits the output into rows of braille cells.
+func renderCells(cfg *dotmatrix.Config, img image.Image) ([][]rune, error) {
+	var buf bytes.Buffer
+	if err := dotmatrix.NewPrinter(&buf, cfg).Print(img); err != nil {
+		return nil, err
+	}
+	var cells [][]rune
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		cells = append(cells, []rune(line))
+	}
+	return cells, nil
+}
+
+// sequence decodes input as a sequence of frames: the composited frames of a gif,
+// or the single frame of any other image.
+func sequence(input string) ([]image.Image, error) {
+	reader, mimeType, err := decodeReader(input)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "image/gif" {
+		giff, err := gif.DecodeAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", input, err)
+		}
+		return dotmatrix.GIFFrames(giff), nil
+	}
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", input, err)
+	}
+	return []image.Image{img}, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// ffmpegSource plays what an ffmpeg command decodes, which it writes to its
+// stdout as y4m.
+type ffmpegSource struct {
+	*dotmatrix.Y4MSource
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+}
+
+// startFFmpeg runs ffmpeg with the given input arguments, converting whatever it
+// decodes to gray y4m, which is all that dithering needs. If stdin is set, it is
+// piped to ffmpeg.
+func startFFmpeg(stdin io.Reader, input ...string) (*ffmpegSource, error) {
+	args := append([]string{"-loglevel", "error"}, input...)
+	args = append(args, "-f", "yuv4mpegpipe", "-pix_fmt", "gray", "-")
+	s := &ffmpegSource{cmd: exec.Command("ffmpeg", args...)}
+	s.cmd.Stdin = stdin
+	s.cmd.Stderr = &s.stderr
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.stdout = stdout
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v", err)
+	}
+	if s.Y4MSource, err = dotmatrix.NewY4MSource(stdout); err != nil {
+		return nil, s.fail(err)
+	}
+	return s, nil
+}
+
+// NextFrame reads the next frame, reporting why ffmpeg failed if it did.
+func (s *ffmpegSource) NextFrame() (image.Image, time.Duration, error) {
+	img, delay, err := s.Y4MSource.NextFrame()
+	if err == io.EOF {
+		if err := s.cmd.Wait(); err != nil {
+			return nil, 0, s.fail(err)
+		}
+		return nil, 0, io.EOF
+	}
+	return img, delay, err
+}
+
+// fail stops ffmpeg, and explains err with what ffmpeg said, if anything.
+func (s *ffmpegSource) fail(err error) error {
+	s.Close()
+	if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+		return fmt.Errorf("ffmpeg: %s", msg)
+	}
+	return fmt.Errorf("ffmpeg: %v", err)
+}
+
+// Close stops ffmpeg.
+func (s *ffmpegSource) Close() error {
+	if s.cmd.ProcessState == nil {
+		s.cmd.Process.Kill()
+		s.stdout.Close()
+		s.cmd.Wait()
+	}
+	return nil
+}
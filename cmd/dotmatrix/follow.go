@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// followPoll is how often a followed file is checked for more to read.
+const followPoll = 100 * time.Millisecond
+
+// follower reads a file that is still being written, like tail -f: at the end of
+// the file, it waits for more to be appended instead of ending, until ctx is done.
+// Files truncated while being followed, as by a recorder that starts over, are
+// read again from the start.
+type follower struct {
+	ctx  context.Context
+	file *os.File
+}
+
+// decodeFollowing opens the file at path like decodeReader, but follows it as it
+// grows.
+func decodeFollowing(ctx context.Context, path string) (io.Reader, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	f := &follower{ctx: ctx, file: file}
+
+	// A follower never ends, so waiting for the 512 bytes sniff peeks at would hang
+	// on shorter files. Whatever has been written so far is sniffed instead, once
+	// anything has.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err == io.EOF && n == 0 {
+		n, err = f.Read(head)
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, "", err
+	}
+	head = head[:n]
+	return io.MultiReader(bytes.NewReader(head), f), contentType(head), nil
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := f.file.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if err := f.rewindIfTruncated(); err != nil {
+			return 0, err
+		}
+
+		timer := time.NewTimer(followPoll)
+		select {
+		case <-f.ctx.Done():
+			timer.Stop()
+			return 0, f.ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (f *follower) rewindIfTruncated() error {
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < offset {
+		_, err = f.file.Seek(0, io.SeekStart)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("decodeFollowing", func() {
+	It("should sniff files shorter than a sniff, and follow what's appended", func() {
+		dir, err := ioutil.TempDir("", "follow")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "art.txt")
+		Expect(ioutil.WriteFile(path, []byte("⣿⣿\n"), 0644)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		r, mimeType, err := decodeFollowing(ctx, path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mimeType).To(HavePrefix("text/plain"))
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteString("⠁⠁\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		got := make([]byte, len("⣿⣿\n⠁⠁\n"))
+		_, err = io.ReadFull(r, got)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(got)).To(Equal("⣿⣿\n⠁⠁\n"))
+	})
+})
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// imageExtensions are the file types offered by the interactive file picker.
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".webp": true, ".mjpeg": true, ".mjpg": true, ".y4m": true,
+	".mp4": true, ".webm": true, ".mkv": true, ".mov": true,
+}
+
+// resolveInput returns the input named on the command line. If there is none and
+// stdin is a terminal, reading stdin would hang waiting for an image that is never
+// coming, so the user is shown the help and asked to pick an input instead. An
+// empty result means stdin should be read.
+func resolveInput(c *cli.Context) (string, error) {
+	if input := c.Args().First(); input != "" {
+		return input, nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+
+	cli.ShowAppHelp(c)
+	fmt.Fprintln(os.Stdout)
+	return promptInput(os.Stdout, os.Stdin, ".")
+}
+
+// promptInput offers the images found in dir and reads a choice, file path or url
+// from r.
+func promptInput(w io.Writer, r io.Reader, dir string) (string, error) {
+	var files []string
+	if infos, err := ioutil.ReadDir(dir); err == nil {
+		for _, info := range infos {
+			if !info.IsDir() && imageExtensions[strings.ToLower(filepath.Ext(info.Name()))] {
+				files = append(files, filepath.Join(dir, info.Name()))
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		fmt.Fprintln(w, "Images in the current directory:")
+		for i, file := range files {
+			fmt.Fprintf(w, "  %2d) %s\n", i+1, file)
+		}
+		fmt.Fprintf(w, "Choose an image [1-%d], or enter a file or url: ", len(files))
+	} else {
+		fmt.Fprint(w, "Enter an image file or url: ")
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", fmt.Errorf("no input given")
+	}
+	if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(files) {
+		return files[n-1], nil
+	}
+	return line, nil
+}
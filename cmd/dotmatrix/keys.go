@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/kevin-cantwell/dotmatrix"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Playback speeds are doubled and halved from the keyboard within these limits.
+const (
+	minSpeed = 1.0 / 16
+	maxSpeed = 16
+)
+
+var (
+	// controls is the terminal that playback is controlled from, if any.
+	controls io.Reader
+	// onClick, if set, is called with each mouse button pressed while playing.
+	onClick func(dotmatrix.MouseEvent)
+)
+
+// openControls has the keyboard control playback, when both the keys and the
+// output are on a terminal. Playback isn't controlled otherwise, such as when
+// stdin is piped from another program; nor is it an error.
+func openControls(imageOnStdin bool) {
+	if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	if !imageOnStdin && !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+	if f, err := openKeys(imageOnStdin); err == nil && sttyState != "" {
+		controls = f
+	}
+}
+
+// printClicks returns an onClick that prints the pixel of the image under each
+// left click to stderr. The image is taken to be printed from where the cursor is
+// now, unless printing it scrolls the terminal, so the terminal is asked where
+// that is before its keys are read.
+func printClicks(cfg *dotmatrix.Config) func(dotmatrix.MouseEvent) {
+	filter, ok := cfg.Filter.(*Filter)
+	if !ok {
+		return nil
+	}
+	cursor, _ := cursorPosition()
+	termRows := askTerminal().rows
+	return func(e dotmatrix.MouseEvent) {
+		if e.Button != 0 || e.Released {
+			return
+		}
+		origin := cursor
+		layout := filter.layout(origin)
+		// The frame, if any, and the lines below the image, which include the
+		// cursor's and the status line's, push it up if they don't fit below it.
+		rows := (layout.Printed.Dy() + 3) / 4
+		if filter.Frame {
+			rows += 2
+		}
+		below := 1
+		if cfg.StatusLine || cfg.ShowStats || cfg.Step != nil {
+			below++
+		}
+		if termRows > 0 && origin.Y+rows+below > termRows {
+			origin.Y = termRows - rows - below
+			if origin.Y < 0 {
+				origin.Y = 0
+			}
+			layout = filter.layout(origin)
+		}
+		if px, ok := layout.Pixel(e.Col, e.Row); ok {
+			fmt.Fprintf(os.Stderr, "%d,%d\n", px.X, px.Y)
+		}
+	}
+}
+
+// control plays player under the control of keys read from r until quit: space
+// pauses and resumes, the left and right arrows step back and forward a frame,
+// + and - double and halve the speed, and q quits. Mouse buttons are passed to
+// onClick.
+func control(player *dotmatrix.Player, r io.Reader, quit context.CancelFunc) {
+	var (
+		mu     sync.Mutex
+		status = dotmatrix.PlayerStatus{Speed: 1}
+	)
+	player.OnStatus = func(s dotmatrix.PlayerStatus) {
+		mu.Lock()
+		status = s
+		mu.Unlock()
+	}
+
+	keys := bufio.NewReader(r)
+	for {
+		key, err := keys.ReadByte()
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		s := status
+		mu.Unlock()
+
+		switch key {
+		case ' ':
+			if s.State == dotmatrix.Paused {
+				player.Resume()
+			} else {
+				player.Pause()
+			}
+		case '+', '=':
+			if s.Speed < maxSpeed {
+				player.SetSpeed(s.Speed * 2)
+			}
+		case '-', '_':
+			if s.Speed > minSpeed {
+				player.SetSpeed(s.Speed / 2)
+			}
+		case 'q', 'Q':
+			quit()
+			return
+		case '\033':
+			// Arrow keys are sent as CSI or SS3 sequences, depending on the
+			// terminal's cursor key mode, and mouse buttons as SGR reports.
+			if next, _ := keys.ReadByte(); next != '[' && next != 'O' {
+				continue
+			}
+			switch final, _ := keys.ReadByte(); final {
+			case 'C':
+				player.Seek(s.Frame + 1)
+			case 'D':
+				player.Seek(s.Frame - 1)
+			case '<':
+				if e, ok := readMouseEvent(keys); ok && onClick != nil {
+					onClick(e)
+				}
+			}
+		}
+	}
+}
+
+// readMouseEvent reads the rest of an SGR mouse report, after its "\033[<".
+func readMouseEvent(keys *bufio.Reader) (dotmatrix.MouseEvent, bool) {
+	report := []byte("\033[<")
+	for len(report) < 32 {
+		b, err := keys.ReadByte()
+		if err != nil {
+			break
+		}
+		report = append(report, b)
+		if b == 'M' || b == 'm' {
+			break
+		}
+	}
+	e, _, ok := dotmatrix.ParseMouseEvent(report)
+	return e, ok
+}
+
+// sttyState is the terminal's settings before openKeys changed them, or empty
+// if they weren't changed.
+var sttyState string
+
+// openKeys returns the keys that step through frames or control playback. They
+// are read from stdin, or from the controlling terminal if the image itself is read
+// from stdin. A terminal is taken out of line buffered mode, so that each keypress
+// acts without waiting for enter; restoreKeys puts it back. Unlike raw mode, this
+// leaves ctrl-c interrupting and output translating newlines.
+func openKeys(imageOnStdin bool) (*os.File, error) {
+	f := os.Stdin
+	if imageOnStdin {
+		var err error
+		if f, err = os.Open("/dev/tty"); err != nil {
+			return nil, err
+		}
+	}
+	if !terminal.IsTerminal(int(f.Fd())) {
+		return f, nil
+	}
+
+	state, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(f, "-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	sttyState = state
+	return f, nil
+}
+
+// restoreKeys restores the terminal settings changed by openKeys.
+func restoreKeys() {
+	if sttyState == "" {
+		return
+	}
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+	stty(tty, sttyState)
+	sttyState = ""
+}
+
+// stty runs stty on the terminal f and returns its output.
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"image"
@@ -11,19 +12,23 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"math"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 
 	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
 
 	"github.com/codegangsta/cli"
 	"github.com/disintegration/imaging"
 	"github.com/llgcode/draw2d/draw2dimg"
 	"github.com/llgcode/draw2d/draw2dkit"
-	"github.com/nfnt/resize"
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/kevin-cantwell/dotmatrix"
@@ -36,6 +41,7 @@ func main() {
 			panic(r)
 		}
 	}()
+	enableVirtualTerminal()
 
 	// print a gopher after the help menu
 	defaultHelpPrinter := cli.HelpPrinter
@@ -59,7 +65,11 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
 			Name:  "invert,i",
-			Usage: "Inverts image color. Useful for black background terminals",
+			Usage: "Inverts image color. Useful for black background terminals. By default, images are inverted if the terminal says its background is dark.",
+		},
+		cli.BoolFlag{
+			Name:  "no-invert",
+			Usage: "Never invert image color, whatever the terminal's background.",
 		},
 		cli.Float64Flag{
 			Name:  "gamma,g",
@@ -80,6 +90,10 @@ func main() {
 			Usage: "SHARPEN greater than 0 sharpens the image.",
 			Value: 0.0,
 		},
+		cli.BoolFlag{
+			Name:  "auto-levels",
+			Usage: "Set the black and white points of each frame from its contents, so animations stay legible as scenes fade to dark or bright.",
+		},
 		cli.BoolFlag{
 			Name:  "mirror,m",
 			Usage: "Mirrors the image.",
@@ -88,48 +102,370 @@ func main() {
 			Name:  "mono",
 			Usage: "Images are drawn without Floyd Steinberg diffusion.",
 		},
+		cli.BoolFlag{
+			Name:  "weighted",
+			Usage: "Images are drawn without diffusion by filling the darkest dots of each cell in proportion to its darkness. Smoother than --mono on gradients and thin lines.",
+		},
+		cli.IntFlag{
+			Name:  "threshold",
+			Usage: "Images are drawn without diffusion, with a dot for each pixel darker than THRESHOLD, from 1 to 255. Keeps lines and text crisp.",
+		},
+		cli.IntFlag{
+			Name:  "denoise",
+			Usage: "DENOISE greater than 0 smooths out sensor noise, such as from a webcam in a dark room. Larger values are stronger.",
+		},
+		cli.BoolFlag{
+			Name:  "thin-lines",
+			Usage: "Preserve thin lines when shrinking images. Useful for diagrams, sheet music and wireframes.",
+		},
+		cli.BoolFlag{
+			Name:  "document",
+			Usage: "Preview photographed pages: normalizes contrast, straightens the page and binarizes it.",
+		},
+		cli.BoolFlag{
+			Name:  "contour",
+			Usage: "Trace the outlines of the image as thin lines. Useful for logos and diagrams.",
+		},
+		cli.StringFlag{
+			Name:  "motion-highlight",
+			Usage: "Highlight what moves between frames of a stream: boost fades out still areas, only blanks them.",
+		},
+		cli.Float64Flag{
+			Name:  "motion-sensitivity",
+			Usage: "How little a pixel must change, from 0 to 1, to count as motion for --motion-highlight.",
+			Value: 0.9,
+		},
+		cli.StringFlag{
+			Name:  "overlay",
+			Usage: "Composite an image, such as a logo, over the output, given as file[@corner] (eg: logo.png@top-right). The default corner is bottom-right. The image is drawn one pixel per dot.",
+		},
+		cli.Float64Flag{
+			Name:  "overlay-opacity",
+			Usage: "Opacity of the --overlay image, from 0 to 1.",
+			Value: 1,
+		},
+		cli.StringSliceFlag{
+			Name:  "notify",
+			Usage: "Notify an event with the terminal bell and/or a flash, given as event:bell, event:flash or event:bell+flash. Events are done, motion (see --motion-highlight) and reconnect. May be repeated.",
+		},
+		cli.StringSliceFlag{
+			Name:  "stamp",
+			Usage: "Stamp each frame with the time and/or frame number, for monitoring streams. Accepts time and frame; may be repeated.",
+		},
+		cli.StringFlag{
+			Name:  "stamp-corner",
+			Usage: "Corner to draw the --stamp in: top-left, top-right, bottom-left or bottom-right.",
+			Value: "top-left",
+		},
+		cli.StringFlag{
+			Name:  "alt-text",
+			Usage: "Describe still images in words after the art, for accessibility, using a shell command that reads the image as a PNG on stdin and prints a description.",
+		},
+		cli.StringFlag{
+			Name:  "caption",
+			Usage: "Label the image with text. Useful for streams and screenshots.",
+		},
+		cli.StringFlag{
+			Name:  "caption-corner",
+			Usage: "Corner to draw the caption in: top-left, top-right, bottom-left or bottom-right.",
+			Value: "bottom-left",
+		},
 		cli.BoolFlag{
 			Name:  "motion,mjpeg",
 			Usage: "Interpret input as an mjpeg stream, such as from a webcam.",
 		},
+		cli.StringFlag{
+			Name:  "alpha",
+			Usage: "How transparent pixels are drawn: transparent, white, black, matte:#rrggbb or threshold:N (0-255).",
+			Value: "transparent",
+		},
+		cli.StringFlag{
+			Name:  "background,bg",
+			Usage: "Composite transparent images and gif backgrounds over a color (eg: #ffffff) so they render the same on any terminal theme.",
+		},
+		cli.IntFlag{
+			Name:  "frame",
+			Usage: "Print only this frame of a gif, counting from 0, as a still.",
+			Value: -1,
+		},
+		cli.IntFlag{
+			Name:  "contact-sheet",
+			Usage: "Print every Nth frame of a gif side by side in a grid, labelled with frame indices, instead of animating it.",
+		},
+		cli.StringFlag{
+			Name:  "loop",
+			Usage: "How many times to play gifs: a number, once, or forever. The default is to follow the gif.",
+		},
+		cli.StringFlag{
+			Name:  "profile",
+			Usage: "Restrict output for other clients: bbs draws with CP437 blocks and CRLF line endings for telnet BBS and MUD clients, and bbs-ascii with plain ASCII.",
+		},
+		cli.StringFlag{
+			Name:  "serial",
+			Usage: "Write to a serial device instead of stdout, given as path[@baud] (eg: /dev/ttyUSB0@115200). The device is assumed to be 80x24 unless --width and --height are set.",
+		},
+		cli.BoolFlag{
+			Name:  "flow",
+			Usage: "Use hardware (RTS/CTS) flow control on the --serial device, rather than pacing output to the baud rate.",
+		},
+		cli.BoolFlag{
+			Name:  "prerender",
+			Usage: "Render every frame of a gif before playing it, for smooth playback on slow machines.",
+		},
+		cli.StringFlag{
+			Name:  "record",
+			Usage: "Record the frames printed to the file RECORD, for replaying later without rendering them again.",
+		},
+		cli.StringFlag{
+			Name:  "export-gif",
+			Usage: "Write a gif input, rendered in dots, to the file EXPORT-GIF instead of playing it. Each dot becomes a pixel.",
+		},
+		cli.StringFlag{
+			Name:  "export-cast",
+			Usage: "Write a gif or animated webp input to the file EXPORT-CAST as an asciinema v2 cast instead of playing it.",
+		},
+		cli.IntFlag{
+			Name:  "decode-ahead",
+			Usage: "Render up to DECODE-AHEAD frames of a gif in the background while others play, for smooth playback of fast gifs on multicore machines.",
+		},
+		cli.BoolFlag{
+			Name:  "no-keys",
+			Usage: "Don't control playback from the keyboard. Otherwise, when playing to a terminal, space pauses and resumes, the left and right arrows step back and forward a frame, + and - double and halve the speed, and q quits.",
+		},
+		cli.StringFlag{
+			Name:  "pager",
+			Usage: "Page still images too tall for the terminal, prompting with --more-- for each screenful: auto (the default) or never. With always, images are fitted to the terminal's width alone and paged however tall they are.",
+			Value: "auto",
+		},
+		cli.BoolFlag{
+			Name:  "mouse",
+			Usage: "Print the pixel of the image under each left click to stderr while playing, as x,y, eg: to find coordinates within a frame of a stream.",
+		},
+		cli.BoolFlag{
+			Name:  "step",
+			Usage: "Step through animations a frame at a time, advancing on each keypress. The index of each frame is shown below it.",
+		},
+		cli.BoolFlag{
+			Name:  "partial",
+			Usage: "Only redraw the parts of animation frames that change. Useful over slow connections.",
+		},
+		cli.BoolFlag{
+			Name:  "dedupe",
+			Usage: "Skip redrawing animation frames that look identical to the previous frame.",
+		},
+		cli.BoolFlag{
+			Name:  "skip-corrupt",
+			Usage: "Skip mjpeg frames that can't be decoded, reporting them on stderr, instead of stopping.",
+		},
+		cli.StringFlag{
+			Name:  "drop",
+			Usage: "Which mjpeg frames to drop when the terminal can't keep up: oldest, newest or none. The default is oldest, to stay live.",
+		},
+		cli.IntFlag{
+			Name:  "max-bytes",
+			Usage: "Cap the bytes written per frame, for slow links such as serial consoles or MUDs. Zero means no cap.",
+		},
+		cli.StringFlag{
+			Name:  "over-budget",
+			Usage: "What to do with frames over the --max-bytes cap: shrink them or skip them.",
+			Value: "shrink",
+		},
 		cli.IntFlag{
 			Name:  "framerate,fps",
-			Usage: "Force a framerate for mjpeg streams. Default is -1 (ie: no delay between frames).",
+			Usage: "Cap the framerate of mjpeg streams. Zero or less, the default, prints frames as they arrive.",
 			Value: -1,
 		},
+		cli.StringFlag{
+			Name:  "raw",
+			Usage: "Read raw video frames of the given pixel format, gray8 or rgb24, eg: from \"ffmpeg -f rawvideo -pix_fmt gray -\". Requires --size, and plays at --fps if given.",
+		},
+		cli.StringFlag{
+			Name:  "size",
+			Usage: "The size of --raw video frames in pixels, as WxH (eg: 640x360).",
+		},
+		cli.BoolFlag{
+			Name:  "reconnect",
+			Usage: "Reconnect to url inputs that drop, backing off between attempts, eg: for long-running camera displays.",
+		},
+		cli.BoolTFlag{
+			Name:  "sync",
+			Usage: "Wrap each frame in a synchronized update when printing to a terminal, so large frames don't tear mid-refresh. On by default; --sync=false turns it off.",
+		},
+		cli.BoolTFlag{
+			Name:  "cell-size",
+			Usage: "Ask the terminal for the pixel size of its cells, so images keep their proportions in fonts whose dots aren't square. On by default; --cell-size=false turns it off.",
+		},
+		cli.BoolFlag{
+			Name:  "follow",
+			Usage: "Keep reading file inputs as they grow, like tail -f, eg: mjpeg appended to a file by a security camera recorder.",
+		},
+		cli.BoolFlag{
+			Name:  "camera-timing",
+			Usage: "Pace mjpeg frames by the X-Timestamp headers the camera sent with them, eg: to replay a saved stream.",
+		},
+		cli.StringFlag{
+			Name:  "stats",
+			Usage: "Report the frame rate achieved, frames skipped and dropped, and bytes written per second while playing: below the image, or once a second to stderr.",
+		},
+		cli.BoolFlag{
+			Name:  "status",
+			Usage: "Print a status line below animations and streams, with what's playing, the frame, the time into it and the playback speed.",
+		},
+		cli.StringFlag{
+			Name:  "blank",
+			Usage: "Print BLANK for cells without any dots, eg: \" \" for fonts that draw the blank braille pattern with dot outlines or at the wrong width.",
+		},
+		cli.BoolFlag{
+			Name:  "trim",
+			Usage: "Stop each row at its last cell with dots, shrinking the output of mostly dark or letterboxed images, eg: over slow links.",
+		},
+		cli.BoolFlag{
+			Name:  "crlf",
+			Usage: "End each row with CRLF instead of LF, eg: for raw serial lines and Windows pipes.",
+		},
+		cli.IntFlag{
+			Name:  "interpolate",
+			Usage: "Crossfade through INTERPOLATE blended frames between each frame of a gif, to smooth low frame rate animations. Live streams blend each frame into those before it instead.",
+		},
+		cli.BoolFlag{
+			Name:  "border",
+			Usage: "Draw a frame of box-drawing characters around the image, eg: to set it apart in a dashboard.",
+		},
+		cli.StringFlag{
+			Name:  "border-title",
+			Usage: "Draw a frame around the image, as --border does, with BORDER-TITLE set into its top edge.",
+		},
+		cli.IntFlag{
+			Name:  "columns",
+			Usage: "The number of columns to fit images within, instead of the terminal's, eg: when output isn't a terminal. COLUMNS is used if set and output isn't a terminal.",
+		},
+		cli.IntFlag{
+			Name:  "rows",
+			Usage: "The number of rows to fit images within, instead of the terminal's, eg: when output isn't a terminal. LINES is used if set and output isn't a terminal.",
+		},
+		cli.StringFlag{
+			Name:  "width,W",
+			Usage: "Force the output width in braille cells, or in pixels with a \"px\" suffix (eg: 40 or 80px). Bypasses terminal size detection.",
+		},
+		cli.StringFlag{
+			Name:  "height,H",
+			Usage: "Force the output height in braille cells, or in pixels with a \"px\" suffix (eg: 20 or 80px). Bypasses terminal size detection.",
+		},
+		cli.Float64Flag{
+			Name:  "scale",
+			Usage: "Force a scale factor for the image (eg: 0.5). Bypasses terminal size detection and takes precedence over width and height.",
+		},
+		cli.StringFlag{
+			Name:  "fit",
+			Usage: "How images are fit to the terminal: scale shrinks them uniformly, smart squeezes out low-detail areas to preserve subjects.",
+			Value: "scale",
+		},
+		cli.StringFlag{
+			Name:  "scaler",
+			Usage: "How images are resampled: nearest (the default), bilinear or lanczos.",
+		},
+		cli.StringFlag{
+			Name:  "scaler-cmd",
+			Usage: "Resample images with a shell command, which reads a png on stdin and writes the image scaled to {width}x{height} to stdout (eg: \"convert - -resize {width}x{height}! png:-\"). Falls back to --scaler if it fails.",
+		},
 		cli.StringFlag{
 			Name:  "mimeType,mime",
 			Usage: "Force interpretation of a specific mime type (eg: \"image/gif\". Default is to examine the first 512 bytes and make an educated guess.",
 		},
 	}
 	app.Action = func(c *cli.Context) error {
+		input, err := resolveInput(c)
+		if err != nil {
+			return err
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		go handleInterrupt(cancel)
 
-		showCursor(false)
-		defer showCursor(true)
+		var reader io.Reader
+		var mimeType string
+		reconnect := c.GlobalBool("reconnect") && (strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://"))
+		follow := c.GlobalBool("follow") && input != "" && !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://")
+		if reconnect {
+			reader, mimeType, err = decodeReconnecting(ctx, input)
+		} else if follow {
+			reader, mimeType, err = decodeFollowing(ctx, input)
+		} else {
+			reader, mimeType, err = decodeReader(input)
+		}
+		if err != nil {
+			return err
+		}
 
-		reader, mimeType, err := decodeReader(c)
+		cfg, err := config(c)
 		if err != nil {
 			return err
 		}
+		cfg.Name = "stdin"
+		if input != "" {
+			cfg.Name = path.Base(input)
+		}
+		if reconnect {
+			// Streams that start over after reconnecting cut the frame in progress short.
+			cfg.SkipCorruptFrames = true
+		}
+
+		var w io.Writer = os.Stdout
+		if spec := c.GlobalString("serial"); spec != "" {
+			device, err := openSerial(spec, c.GlobalBool("flow"))
+			if err != nil {
+				return err
+			}
+			defer device.Close()
+			w = device
 
-		if mime := c.String("mimeType"); mime != "" {
-			mimeType = mime
+			filter := cfg.Filter.(*Filter)
+			filter.Cols, filter.Rows = serialCols, serialRows-1
+			filter.DotAspect = 1
+			cfg.SynchronizedOutput, cfg.KeepCursor = false, true
 		}
 
-		if c.Bool("motion") {
-			return mjpegAction(ctx, c, reader, c.Int("framerate"))
+		if path := c.GlobalString("record"); path != "" {
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			renderer := c.GlobalString("profile")
+			if renderer == "" {
+				renderer = "braille"
+			}
+			cfg.Recorder = dotmatrix.NewRecorder(file, renderer)
+			defer func() {
+				cfg.Recorder.Close()
+				file.Close()
+			}()
 		}
 
-		switch mimeType {
-		case "video/x-motion-jpeg":
-			return mjpegAction(ctx, c, reader, c.Int("framerate"))
-		case "image/gif":
-			return gifAction(ctx, c, reader)
-		default:
-			return imageAction(c, reader)
+		if c.GlobalBool("step") {
+			if cfg.Step, err = openKeys(input == ""); err != nil {
+				return err
+			}
+			defer restoreKeys()
+		} else if !c.GlobalBool("no-keys") {
+			if c.GlobalBool("mouse") {
+				onClick = printClicks(cfg)
+			}
+			openControls(input == "")
+			defer restoreKeys()
+			cfg.MouseReporting = controls != nil && onClick != nil
 		}
+
+		return render(ctx, c, cfg, w, input, reader, mimeType)
+	}
+	app.Commands = []cli.Command{
+		pushCommand(),
+		serveCommand(),
+		diffCommand(),
+		colorsCommand(),
+		scheduleCommand(),
+		replayCommand(),
+		cameraCommand(),
+		screenCommand(),
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -138,23 +474,19 @@ func main() {
 }
 
 func handleInterrupt(cancel func()) {
-	signals := make(chan os.Signal)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, interruptSignals...)
 	go func() {
 		s := <-signals
 		showCursor(true)
+		if onClick != nil {
+			fmt.Fprint(os.Stdout, "\033[?1006l\033[?1000l")
+		}
+		restoreKeys()
 		// Stop notifying this channel
 		signal.Stop(signals)
 		cancel()
-
-		// All Signals returned by the signal package should be of type syscall.Signal
-		if signum, ok := s.(syscall.Signal); ok {
-			// Calling os.Exit here would be a bad idea if there are other goroutines
-			// waiting to catch the same signal.
-			syscall.Kill(syscall.Getpid(), signum)
-		} else {
-			panic(fmt.Sprintf("unexpected signal: %v", s))
-		}
+		reraise(s)
 	}()
 }
 
@@ -166,50 +498,615 @@ func showCursor(show bool) {
 	}
 }
 
-func config(c *cli.Context) *dotmatrix.Config {
+// render prints the contents of r, read from input, to w, choosing a printer based
+// on mimeType and the global flags. An empty input is stdin.
+func render(ctx context.Context, c *cli.Context, cfg *dotmatrix.Config, w io.Writer, input string, r io.Reader, mimeType string) error {
+	if mime := c.GlobalString("mimeType"); mime != "" {
+		mimeType = mime
+	}
+
+	if format := c.GlobalString("raw"); format != "" {
+		return rawAction(ctx, cfg, w, r, format, c.GlobalString("size"), c.GlobalInt("framerate"))
+	}
+
+	if c.GlobalBool("motion") {
+		return mjpegAction(ctx, cfg, w, r, c.GlobalInt("framerate"))
+	}
+
+	// Still images are paged if they're too tall for the terminal, but animations
+	// are redrawn in place, so never are.
+	mode := c.GlobalString("pager")
+	if mode != "auto" && mode != "always" && mode != "never" {
+		return fmt.Errorf("invalid pager: %q", mode)
+	}
+	still := func() io.Writer {
+		pager := newPager(w, mode, input == "")
+		if pager == nil {
+			return w
+		}
+		// Terminals hold back the prompt within a synchronized update.
+		cfg.SynchronizedOutput = false
+		if mode == "always" {
+			cfg.Filter.(*Filter).FitWidth = true
+		}
+		return pager
+	}
+
+	switch mimeType {
+	case "video/x-motion-jpeg":
+		return mjpegAction(ctx, cfg, w, r, c.GlobalInt("framerate"))
+	case "video/x-yuv4mpeg":
+		return y4mAction(ctx, cfg, w, r)
+	case "video/mp4", "video/webm", "video/x-matroska", "video/quicktime", "video/avi", "video/mpeg":
+		return videoAction(ctx, cfg, w, input, r)
+	case "image/webp":
+		if path := c.GlobalString("export-cast"); path != "" {
+			return exportCastAction(cfg, r, path, mimeType)
+		}
+		return webpAction(ctx, cfg, w, r)
+	case "image/gif":
+		if path := c.GlobalString("export-gif"); path != "" {
+			return exportGIFAction(cfg, r, path)
+		}
+		if path := c.GlobalString("export-cast"); path != "" {
+			return exportCastAction(cfg, r, path, mimeType)
+		}
+		if every := c.GlobalInt("contact-sheet"); every > 0 {
+			return contactSheetAction(cfg, still(), r, every)
+		}
+		if frame := c.GlobalInt("frame"); frame >= 0 {
+			return gifAction(ctx, cfg, still(), r, frame)
+		}
+		return gifAction(ctx, cfg, w, r, -1)
+	case "text/plain; charset=utf-8":
+		return ansiAction(ctx, cfg, still(), r)
+	default:
+		return imageAction(ctx, cfg, still(), r)
+	}
+}
+
+// config builds a printer config from the global flags. Global lookups are used
+// so that subcommands share the same rendering options.
+func config(c *cli.Context) (*dotmatrix.Config, error) {
+	return configFor(c, stdoutTerminal(c))
+}
+
+// outputTerminal is what's known of the terminal that output is shown on.
+type outputTerminal struct {
+	// sync is whether it shows synchronized updates.
+	sync bool
+	// aspect is how many times taller than wide it draws each dot.
+	aspect float64
+	// dark is whether its background is dark, if known.
+	dark, knownBackground bool
+}
+
+// stdoutTerminal describes the terminal on stdout, if it is one, asking it about
+// itself where the flags leave it to.
+func stdoutTerminal(c *cli.Context) outputTerminal {
+	t := outputTerminal{aspect: 1}
+	if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return t
+	}
+	// GNU screen doesn't pass synchronized updates on.
+	t.sync = dotmatrix.DetectMultiplexer() != dotmatrix.Screen
+	// Only terminals that are likely to answer queries are asked anything.
+	if c.GlobalString("profile") != "" {
+		return t
+	}
+	if c.GlobalBoolT("cell-size") {
+		t.aspect = dotAspect()
+	}
+	if !c.GlobalBool("invert") && !c.GlobalBool("no-invert") {
+		t.dark, t.knownBackground = darkBackground()
+	}
+	return t
+}
+
+// configFor builds the config for printing to the terminal out.
+func configFor(c *cli.Context, out outputTerminal) (*dotmatrix.Config, error) {
+	width, err := parseDimension(c.GlobalString("width"), 2)
+	if err != nil {
+		return nil, err
+	}
+	height, err := parseDimension(c.GlobalString("height"), 4)
+	if err != nil {
+		return nil, err
+	}
+	fit := c.GlobalString("fit")
+	if fit != "scale" && fit != "smart" {
+		return nil, fmt.Errorf("invalid fit: %q", fit)
+	}
+	alpha, err := parseAlpha(c.GlobalString("alpha"))
+	if err != nil {
+		return nil, err
+	}
+	corner, err := parseCorner(c.GlobalString("caption-corner"))
+	if err != nil {
+		return nil, err
+	}
+	var motion *dotmatrix.MotionFilter
+	switch highlight := c.GlobalString("motion-highlight"); highlight {
+	case "":
+	case "boost", "only":
+		motion = &dotmatrix.MotionFilter{
+			Sensitivity: c.GlobalFloat64("motion-sensitivity"),
+			Exclusive:   highlight == "only",
+		}
+	default:
+		return nil, fmt.Errorf("invalid motion highlight: %q", highlight)
+	}
+	var watermark dotmatrix.WatermarkFilter
+	if overlay := c.GlobalString("overlay"); overlay != "" {
+		if watermark, err = loadWatermark(overlay); err != nil {
+			return nil, err
+		}
+		watermark.Opacity = c.GlobalFloat64("overlay-opacity")
+	}
+	var drop dotmatrix.DropPolicy
+	switch policy := c.GlobalString("drop"); policy {
+	case "", "oldest":
+		drop = dotmatrix.DropOldest
+	case "newest":
+		drop = dotmatrix.DropNewest
+	case "none":
+		drop = dotmatrix.DropNone
+	default:
+		return nil, fmt.Errorf("invalid drop policy: %q", policy)
+	}
+	var loops int
+	switch loop := c.GlobalString("loop"); loop {
+	case "":
+	case "once":
+		loops = 1
+	case "forever":
+		loops = dotmatrix.LoopForever
+	default:
+		if loops, err = strconv.Atoi(loop); err != nil || loops < 1 {
+			return nil, fmt.Errorf("invalid loop: %q", loop)
+		}
+	}
+	var profile *dotmatrix.Config
+	switch name := c.GlobalString("profile"); name {
+	case "":
+		profile = &dotmatrix.Config{}
+	case "bbs":
+		profile = dotmatrix.BBSProfile(dotmatrix.CP437)
+	case "bbs-ascii":
+		profile = dotmatrix.BBSProfile(dotmatrix.ASCII)
+	default:
+		return nil, fmt.Errorf("invalid profile: %q", name)
+	}
+	var budgetPolicy dotmatrix.BudgetPolicy
+	switch policy := c.GlobalString("over-budget"); policy {
+	case "shrink":
+		budgetPolicy = dotmatrix.ShrinkPolicy{}
+	case "skip":
+		budgetPolicy = dotmatrix.SkipPolicy{}
+	default:
+		return nil, fmt.Errorf("invalid over-budget policy: %q", policy)
+	}
+	stamp := dotmatrix.Stamp{}
+	if stamp.Corner, err = parseCorner(c.GlobalString("stamp-corner")); err != nil {
+		return nil, err
+	}
+	for _, field := range c.GlobalStringSlice("stamp") {
+		switch field {
+		case "time":
+			stamp.TimeLayout = "15:04:05"
+		case "frame":
+			stamp.Frame = true
+		default:
+			return nil, fmt.Errorf("invalid stamp: %q", field)
+		}
+	}
+	var altText func(image.Image) (string, error)
+	if cmd := c.GlobalString("alt-text"); cmd != "" {
+		altText = dotmatrix.AltTextCommand("sh", "-c", cmd)
+	}
+	var scaler dotmatrix.Scaler
+	switch name := c.GlobalString("scaler"); name {
+	case "":
+	case "nearest":
+		scaler = dotmatrix.NearestNeighbor
+	case "bilinear":
+		scaler = dotmatrix.Bilinear
+	case "lanczos":
+		scaler = dotmatrix.Lanczos
+	default:
+		return nil, fmt.Errorf("invalid scaler: %q", name)
+	}
+	if cmd := c.GlobalString("scaler-cmd"); cmd != "" {
+		scaler = dotmatrix.CommandScaler{
+			Name:     "sh",
+			Args:     []string{"-c", cmd},
+			Fallback: scaler,
+			OnError: func(err error) {
+				fmt.Fprintln(os.Stderr, err)
+			},
+		}
+	}
+	notifications := map[dotmatrix.Event]dotmatrix.Notification{}
+	for _, spec := range c.GlobalStringSlice("notify") {
+		event, how, err := parseNotification(spec)
+		if err != nil {
+			return nil, err
+		}
+		notifications[event] |= how
+	}
+	var levels *dotmatrix.LevelsFilter
+	if c.GlobalBool("auto-levels") {
+		levels = &dotmatrix.LevelsFilter{}
+	}
+	var showStats bool
+	var onStats func(dotmatrix.Stats)
+	switch stats := c.GlobalString("stats"); stats {
+	case "":
+	case "below":
+		showStats = true
+	case "stderr":
+		onStats = func(s dotmatrix.Stats) {
+			fmt.Fprintln(os.Stderr, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid stats: %q", stats)
+	}
+	var blank rune
+	if b := []rune(c.GlobalString("blank")); len(b) == 1 {
+		blank = b[0]
+	} else if len(b) > 1 {
+		return nil, fmt.Errorf("invalid blank: %q is more than one character", string(b))
+	}
+	var quantizer dotmatrix.Quantizer
+	if level := c.GlobalInt("threshold"); level != 0 {
+		if level < 1 || level > 255 {
+			return nil, fmt.Errorf("invalid threshold: %d", level)
+		}
+		quantizer = dotmatrix.ThresholdQuantizer{Level: uint8(level)}
+	}
+	var lineEnding string
+	if c.GlobalBool("crlf") {
+		lineEnding = "\r\n"
+	}
+	// Retro terminals, such as those the profiles target, may print the escapes.
+	synchronized := c.GlobalBoolT("sync") && c.GlobalString("profile") == "" && out.sync
+	invert := c.GlobalBool("invert")
+	switch {
+	case invert && c.GlobalBool("no-invert"):
+		return nil, fmt.Errorf("--invert and --no-invert can't be used together")
+	case !invert && !c.GlobalBool("no-invert") && out.knownBackground:
+		invert = out.dark
+	}
+	flusher := profile.Flusher
+	if title := c.GlobalString("border-title"); c.GlobalBool("border") || title != "" {
+		if c.GlobalString("profile") != "" {
+			return nil, fmt.Errorf("--border can't be used with --profile, as BBS clients can't display box-drawing characters")
+		}
+		flusher = dotmatrix.BorderFlusher{Flusher: flusher, Title: title}
+	}
+	var background color.Color
+	if bg := c.GlobalString("background"); bg != "" {
+		if background, err = parseHexColor(bg); err != nil {
+			return nil, err
+		}
+	}
 	return &dotmatrix.Config{
 		Filter: &Filter{
-			Gamma:      c.Float64("gamma"),
-			Brightness: c.Float64("brightness"),
-			Contrast:   c.Float64("contrast"),
-			Sharpen:    c.Float64("sharpen"),
-			Invert:     c.Bool("invert"),
-			Mirror:     c.Bool("mirror"),
+			Levels:     levels,
+			Gamma:      c.GlobalFloat64("gamma"),
+			Brightness: c.GlobalFloat64("brightness"),
+			Contrast:   c.GlobalFloat64("contrast"),
+			Sharpen:    c.GlobalFloat64("sharpen"),
+			Invert:     invert,
+			Mirror:     c.GlobalBool("mirror"),
+			Cols:       c.GlobalInt("columns"),
+			Frame:      c.GlobalBool("border") || c.GlobalString("border-title") != "",
+			Rows:       c.GlobalInt("rows"),
+			Width:      width,
+			Height:     height,
+			Scale:      c.GlobalFloat64("scale"),
+			Scaler:     scaler,
+			DotAspect:  out.aspect,
+			Smart:      fit == "smart",
+			ThinLines:  c.GlobalBool("thin-lines"),
+			Denoise:    c.GlobalInt("denoise"),
+			Motion:     motion,
+			Document:   c.GlobalBool("document"),
+			Contour:    c.GlobalBool("contour"),
+			Watermark:  watermark,
+			Caption:    dotmatrix.CaptionFilter{Text: c.GlobalString("caption"), Corner: corner},
 		},
+		Flusher:   flusher,
+		Quantizer: quantizer,
+		Reset:     profile.Reset,
 		Drawer: func() draw.Drawer {
-			if c.Bool("mono") {
+			if c.GlobalBool("weighted") {
+				return dotmatrix.WeightedDrawer{}
+			}
+			if c.GlobalBool("mono") {
 				return draw.Src
 			}
 			return draw.FloydSteinberg
 		}(),
-	}
+		Scaler:             scaler,
+		Alpha:              alpha,
+		Background:         background,
+		PartialFlush:       c.GlobalBool("partial"),
+		SkipIdentical:      c.GlobalBool("dedupe"),
+		SkipCorruptFrames:  c.GlobalBool("skip-corrupt"),
+		DropPolicy:         drop,
+		CameraTiming:       c.GlobalBool("camera-timing"),
+		Interpolate:        c.GlobalInt("interpolate"),
+		StatusLine:         c.GlobalBool("status"),
+		LineEnding:         lineEnding,
+		Blank:              blank,
+		TrimTrailingCells:  c.GlobalBool("trim"),
+		ShowStats:          showStats,
+		OnStats:            onStats,
+		SynchronizedOutput: synchronized,
+		// Players hide the cursor, but retro terminals may print the escapes.
+		KeepCursor: c.GlobalString("profile") != "",
+		OnCorruptFrame: func(err error) {
+			fmt.Fprintln(os.Stderr, "skipped frame:", err)
+		},
+		ByteBudget:    c.GlobalInt("max-bytes"),
+		BudgetPolicy:  budgetPolicy,
+		LoopCount:     loops,
+		Prerender:     c.GlobalBool("prerender"),
+		DecodeAhead:   c.GlobalInt("decode-ahead"),
+		Notifications: notifications,
+		Stamp:         stamp,
+		AltText:       altText,
+	}, nil
 }
 
-func imageAction(c *cli.Context, r io.Reader) error {
+func imageAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader) error {
 	img, _, err := image.Decode(r)
 	if err != nil {
 		return err
 	}
-	return dotmatrix.NewPrinter(os.Stdout, config(c)).Print(img)
+	return dotmatrix.NewPrinter(w, cfg).PrintContext(ctx, img)
+}
+
+// ansiAction re-renders text art, such as previously printed braille or ANSI colored art.
+func ansiAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader) error {
+	img, err := dotmatrix.DecodeANSI(r)
+	if err != nil {
+		return err
+	}
+	return dotmatrix.NewPrinter(w, cfg).PrintContext(ctx, img)
+}
+
+// gifAction animates a gif, or prints only the given frame if it isn't negative.
+func gifAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader, frame int) error {
+	giff, err := gif.DecodeAll(r)
+	if err != nil {
+		return err
+	}
+	if frame >= 0 {
+		img := dotmatrix.GIFFrame(giff, frame)
+		if img == nil {
+			return fmt.Errorf("no frame %d: the gif has %d frames", frame, len(giff.Image))
+		}
+		return dotmatrix.NewPrinter(w, cfg).PrintContext(ctx, img)
+	}
+	return play(ctx, cfg, dotmatrix.NewGIFPlayer(w, cfg, giff))
+}
+
+// exportGIFAction writes a gif, rendered in dots, to path instead of playing it.
+func exportGIFAction(cfg *dotmatrix.Config, r io.Reader, path string) error {
+	giff, err := gif.DecodeAll(r)
+	if err != nil {
+		return err
+	}
+	converted, err := dotmatrix.ConvertGIF(giff, cfg)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, converted); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// exportCastAction renders a gif or animated webp to an asciinema cast.
+func exportCastAction(cfg *dotmatrix.Config, r io.Reader, path, mimeType string) error {
+	var rec *dotmatrix.Recording
+	if mimeType == "image/webp" {
+		anim, err := dotmatrix.DecodeWebP(r)
+		if err != nil {
+			return err
+		}
+		if rec, err = dotmatrix.RecordWebP(anim, cfg); err != nil {
+			return err
+		}
+	} else {
+		giff, err := gif.DecodeAll(r)
+		if err != nil {
+			return err
+		}
+		if rec, err = dotmatrix.RecordGIF(giff, cfg); err != nil {
+			return err
+		}
+	}
+	return writeCast(cfg, rec, path)
+}
+
+// writeCast writes rec to the file at path as an asciinema cast.
+func writeCast(cfg *dotmatrix.Config, rec *dotmatrix.Recording, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := dotmatrix.WriteCast(file, rec, cfg); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// webpAction animates an animated webp, or prints a still one.
+func webpAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader) error {
+	anim, err := dotmatrix.DecodeWebP(r)
+	if err != nil {
+		return err
+	}
+	if len(anim.Frames) == 1 {
+		return dotmatrix.NewPrinter(w, cfg).Print(anim.Frames[0].Image)
+	}
+	return play(ctx, cfg, dotmatrix.NewWebPPlayer(w, cfg, anim))
 }
 
-func gifAction(ctx context.Context, c *cli.Context, r io.Reader) error {
+// contactSheetAction prints every nth frame of a gif in a grid that fills the
+// width of the terminal.
+func contactSheetAction(cfg *dotmatrix.Config, w io.Writer, r io.Reader, every int) error {
 	giff, err := gif.DecodeAll(r)
 	if err != nil {
 		return err
 	}
-	return dotmatrix.NewGIFPrinter(os.Stdout, config(c)).Print(ctx, giff)
+	// The sheet fits each frame to its tile instead.
+	cfg.Filter.(*Filter).Scale = 1
+
+	const tileCols, tileRows = 20, 5
+	cols, _ := terminalDimensions()
+	return dotmatrix.ContactSheet(w, dotmatrix.GIFFrames(giff), &dotmatrix.ContactSheetOptions{
+		Columns:  (cols + 2) / (tileCols + 2),
+		TileCols: tileCols,
+		TileRows: tileRows,
+		Every:    every,
+		Config:   cfg,
+	})
+}
+
+// rawAction plays raw video frames of the given format and WxH size.
+func rawAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader, format, size string, fps int) error {
+	var rawFormat dotmatrix.RawFormat
+	switch format {
+	case "gray8", "gray":
+		rawFormat = dotmatrix.RawGray8
+	case "rgb24":
+		rawFormat = dotmatrix.RawRGB24
+	default:
+		return fmt.Errorf("invalid raw format: %q", format)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid size: %q", size)
+	}
+	src, err := dotmatrix.NewRawSource(r, rawFormat, width, height, fps)
+	if err != nil {
+		return err
+	}
+	return play(ctx, cfg, dotmatrix.NewVideoPlayer(w, cfg, src))
+}
+
+// videoAction plays a video container, such as mp4 or webm, decoded by ffmpeg.
+// Files and urls are passed to ffmpeg by name, as not every container can be
+// decoded as it is read, while stdin is piped through.
+func videoAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, input string, r io.Reader) error {
+	var src *ffmpegSource
+	var err error
+	if input == "" {
+		src, err = startFFmpeg(r, "-i", "pipe:0")
+	} else {
+		src, err = startFFmpeg(nil, "-i", input)
+	}
+	if err != nil {
+		return err
+	}
+	return play(ctx, cfg, dotmatrix.NewVideoPlayer(w, cfg, src))
+}
+
+// y4mAction plays a YUV4MPEG2 stream.
+func y4mAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader) error {
+	src, err := dotmatrix.NewY4MSource(r)
+	if err != nil {
+		return err
+	}
+	return play(ctx, cfg, dotmatrix.NewVideoPlayer(w, cfg, src))
+}
+
+// mjpegAction plays an mjpeg stream, then reports any frames dropped on stderr.
+func mjpegAction(ctx context.Context, cfg *dotmatrix.Config, w io.Writer, r io.Reader, fps int) error {
+	player := dotmatrix.NewMJPEGPlayer(w, cfg, r, fps)
+	player.OnStatus = func(status dotmatrix.PlayerStatus) {
+		if status.State == dotmatrix.Stopped && status.Dropped > 0 {
+			fmt.Fprintf(os.Stderr, "dropped %d frames\n", status.Dropped)
+		}
+	}
+	return play(ctx, cfg, player)
 }
 
-func mjpegAction(ctx context.Context, c *cli.Context, r io.Reader, fps int) error {
-	return dotmatrix.NewMJPEGPrinter(os.Stdout, config(c)).Print(ctx, r, fps)
+// play plays an animation, notifying the player of any motion highlighted.
+func play(ctx context.Context, cfg *dotmatrix.Config, player *dotmatrix.Player) error {
+	var quit context.CancelFunc
+	if controls != nil {
+		ctx, quit = context.WithCancel(ctx)
+		defer quit()
+		go control(player, controls, quit)
+	}
+	if filter, ok := cfg.Filter.(*Filter); ok && filter.Motion != nil {
+		filter.Motion.OnMotion = func(float64) {
+			player.Notify(dotmatrix.MotionDetected)
+		}
+	}
+	if reconnecting != nil {
+		reconnecting.OnReconnect = func() {
+			player.Notify(dotmatrix.StreamReconnected)
+		}
+	}
+	err := player.Play(ctx)
+	if quit != nil && err == context.Canceled {
+		// Quitting from the keyboard isn't an error.
+		return nil
+	}
+	return err
 }
 
-func decodeReader(c *cli.Context) (io.Reader, string, error) {
+// parseNotification parses a notification of the form event:bell, event:flash or
+// event:bell+flash.
+func parseNotification(s string) (dotmatrix.Event, dotmatrix.Notification, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid notification: %q", s)
+	}
+	var event dotmatrix.Event
+	switch s[:i] {
+	case "done":
+		event = dotmatrix.PlaybackDone
+	case "motion":
+		event = dotmatrix.MotionDetected
+	case "reconnect":
+		event = dotmatrix.StreamReconnected
+	default:
+		return 0, 0, fmt.Errorf("invalid notification event: %q", s[:i])
+	}
+	var how dotmatrix.Notification
+	for _, name := range strings.Split(s[i+1:], "+") {
+		switch name {
+		case "bell":
+			how |= dotmatrix.Bell
+		case "flash":
+			how |= dotmatrix.Flash
+		default:
+			return 0, 0, fmt.Errorf("invalid notification: %q", name)
+		}
+	}
+	return event, how, nil
+}
+
+// decodeReader opens input, which may be a file path or a url. If input is empty,
+// stdin is used.
+func decodeReader(input string) (io.Reader, string, error) {
 	var reader io.Reader = os.Stdin
 
 	// Assign to reader
-	if input := c.Args().First(); input != "" {
+	if input != "" {
 		// Is it a file?
 		if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
 			file, err := os.Open(input)
@@ -227,19 +1124,35 @@ func decodeReader(c *cli.Context) (io.Reader, string, error) {
 		}
 	}
 
+	return sniff(reader)
+}
+
+// sniff guesses the mime type of reader's contents from the first 512 bytes,
+// returning a reader that still includes them.
+func sniff(reader io.Reader) (io.Reader, string, error) {
 	bufioReader := bufio.NewReader(reader)
 
+	// Short inputs, such as small text files, are sniffed in their entirety.
 	peeked, err := bufioReader.Peek(512)
-	if err != nil {
+	if err != nil && err != io.EOF {
 		return nil, "", err
 	}
+	return bufioReader, contentType(peeked), nil
+}
 
-	mimeType := http.DetectContentType(peeked)
-
-	return bufioReader, mimeType, nil
+// contentType guesses the mime type of contents beginning with head.
+func contentType(head []byte) string {
+	// y4m headers are text, so they would pass for text art.
+	if bytes.HasPrefix(head, []byte("YUV4MPEG2 ")) {
+		return "video/x-yuv4mpeg"
+	}
+	return http.DetectContentType(head)
 }
 
 type Filter struct {
+	// Levels, if set, stretches each frame between the black and white points
+	// estimated from its contents, before any other adjustment.
+	Levels *dotmatrix.LevelsFilter
 	// Gamma less than 0 darkens the image and GAMMA greater than 0 lightens it.
 	Gamma float64
 	// Brightness = -100 gives solid black image. Brightness = 100 gives solid white image.
@@ -252,11 +1165,62 @@ type Filter struct {
 	Invert bool
 	// Mirror flips the image on it's vertical axis
 	Mirror bool
+	// Cols and Rows are the terminal dimensions the image must fit within. Either
+	// that is zero is taken from stdout.
+	Cols, Rows int
+	// Frame leaves room within Cols and Rows for a frame around the image.
+	Frame bool
+	// FitWidth fits the image to Cols alone, however many rows that takes, as when
+	// the output is paged.
+	FitWidth bool
+	// Width and Height force the output dimensions in pixels, bypassing terminal
+	// detection. If only one is set, the aspect ratio is preserved.
+	Width, Height int
+	// Scale forces a scale factor, bypassing terminal detection. It takes precedence
+	// over Width and Height.
+	Scale float64
+	// Scaler resizes the image. The default is nearest neighbor scaling.
+	Scaler dotmatrix.Scaler
+	// DotAspect is how many times taller than wide the terminal draws each dot.
+	// Images are squashed or stretched vertically to make up for it, so that their
+	// proportions are kept. Zero is taken as square dots.
+	DotAspect float64
+	// Smart fits the image to the output by carving out low-energy seams instead of
+	// scaling uniformly. It is ignored if Scale is set.
+	Smart bool
+	// ThinLines thickens lines before the image is shrunk, so they aren't lost.
+	ThinLines bool
+	// Denoise greater than 0 applies a median filter of that radius after scaling.
+	Denoise int
+	// Motion, if set, highlights what changed since the last frame.
+	Motion *dotmatrix.MotionFilter
+	// Document straightens and binarizes photographed pages.
+	Document bool
+	// Contour traces the image's outlines as thin lines.
+	Contour bool
+	// Watermark and then Caption are drawn over the image once it has been scaled.
+	Watermark dotmatrix.WatermarkFilter
+	Caption   dotmatrix.CaptionFilter
+
+	scaleX, scaleY float64
+	fit            *dotmatrix.FitFilter
 
-	scale float64
+	// mu guards the bounds of the last image filtered, before and after, which
+	// clicks are mapped back through from another goroutine.
+	mu              sync.Mutex
+	source, printed image.Rectangle
 }
 
 func (f *Filter) Filter(img image.Image) image.Image {
+	source := img.Bounds()
+	defer func() {
+		f.mu.Lock()
+		f.source, f.printed = source, img.Bounds()
+		f.mu.Unlock()
+	}()
+	if f.Levels != nil {
+		img = f.Levels.Filter(img)
+	}
 	if f.Gamma != 0 {
 		img = imaging.AdjustGamma(img, f.Gamma+1.0)
 	}
@@ -276,62 +1240,262 @@ func (f *Filter) Filter(img image.Image) image.Image {
 		img = imaging.Invert(img)
 	}
 
-	// Only calculate the scalar values once because gifs
-	if f.scale == 0 {
-		cols, rows := terminalDimensions()
-		dx, dy := img.Bounds().Dx(), img.Bounds().Dy()
-		scale := scalar(dx, dy, cols, rows)
-		if scale >= 1.0 {
-			scale = 1.0
+	if f.Smart && f.Scale == 0 {
+		img = f.carve(img)
+	} else {
+		// Only calculate the scalar values once because gifs
+		if f.scaleX == 0 || f.scaleY == 0 {
+			f.scaleX, f.scaleY = f.scalars(img.Bounds().Dx(), img.Bounds().Dy())
+		}
+
+		// Thicken thin lines by about half the shrinking factor, so they end up
+		// around a dot wide.
+		if scale := math.Min(f.scaleX, f.scaleY); f.ThinLines && scale < 1 {
+			img = dotmatrix.ThickenFilter{Radius: int(math.Ceil(0.5 / scale))}.Filter(img)
 		}
-		f.scale = scale
+
+		if f.sized() {
+			width := int(f.scaleX * float64(img.Bounds().Dx()))
+			height := int(f.scaleY * float64(img.Bounds().Dy()))
+			img = f.scaler().Scale(img, width, height)
+		} else {
+			img = f.fitter().Filter(img)
+		}
+	}
+
+	if f.Denoise > 0 {
+		img = dotmatrix.DenoiseFilter{Strength: f.Denoise}.Filter(img)
 	}
 
-	width := uint(f.scale * float64(img.Bounds().Dx()))
-	height := uint(f.scale * float64(img.Bounds().Dy()))
-	return resize.Resize(width, height, img, resize.NearestNeighbor)
+	if f.Motion != nil {
+		img = f.Motion.Filter(img)
+	}
+
+	if f.Document {
+		img = dotmatrix.DocumentFilter{}.Filter(img)
+	}
+
+	// Contours are traced at the final resolution so lines stay 1 dot wide.
+	if f.Contour {
+		img = dotmatrix.ContourFilter{}.Filter(img)
+	}
+	img = f.Watermark.Filter(img)
+	return f.Caption.Filter(img)
 }
 
-func terminalDimensions() (int, int) {
-	var cols, rows int
+// layout returns where the last image filtered is on screen, given the cell at the
+// top-left of its frame, if it has one.
+func (f *Filter) layout(origin image.Point) dotmatrix.ImageLayout {
+	if f.Frame {
+		origin = origin.Add(image.Pt(1, 1))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return dotmatrix.ImageLayout{Origin: origin, Source: f.source, Printed: f.printed}
+}
 
-	if terminal.IsTerminal(int(os.Stdout.Fd())) {
-		tw, th, err := terminal.GetSize(int(os.Stdout.Fd()))
-		if err == nil {
-			th -= 1 // Accounts for the terminal prompt
-			if cols == 0 {
-				cols = tw
-			}
-			if rows == 0 {
-				rows = th
-			}
-		}
+// FilterBounds returns the bounds that images with bounds r are scaled or carved
+// to, without filtering one.
+func (f *Filter) FilterBounds(r image.Rectangle) image.Rectangle {
+	if f.Smart && f.Scale == 0 {
+		boxW, boxH := f.carveBox()
+		return dotmatrix.SeamCarveFilter{Width: boxW, Height: boxH}.FilterBounds(r)
 	}
+	scaleX, scaleY := f.scaleX, f.scaleY
+	if scaleX == 0 || scaleY == 0 {
+		scaleX, scaleY = f.scalars(r.Dx(), r.Dy())
+	}
+	return image.Rect(0, 0, int(scaleX*float64(r.Dx())), int(scaleY*float64(r.Dy())))
+}
 
-	// Small, but fairly standard defaults
-	if cols == 0 {
-		cols = 80
+// carveBox returns the size in pixels that images are carved to fit.
+func (f *Filter) carveBox() (int, int) {
+	if f.Width == 0 || f.Height == 0 {
+		cols, rows := f.fitDimensions()
+		return cols * 2, rows * 4
 	}
-	if rows == 0 {
-		rows = 25
+	return f.Width, f.Height
+}
+
+// carve fits img to the output by scaling it just enough to cover the output, and
+// then carving away low-energy seams from whichever dimension overflows.
+func (f *Filter) carve(img image.Image) image.Image {
+	boxW, boxH := f.carveBox()
+
+	dx, dy := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := math.Max(float64(boxW)/float64(dx), float64(boxH)/float64(dy))
+	if scale < 1.0 {
+		img = f.scaler().Scale(img, int(scale*float64(dx)), int(scale*float64(dy)))
 	}
+	return dotmatrix.SeamCarve(img, boxW, boxH)
+}
 
+// fitDimensions returns the size to fit the image within, in cells. Either of
+// Cols and Rows that is zero is taken from stdout.
+func (f *Filter) fitDimensions() (int, int) {
+	cols, rows := f.Cols, f.Rows
+	if cols == 0 || rows == 0 {
+		termCols, termRows := terminalDimensions()
+		if cols == 0 {
+			cols = termCols
+		}
+		if rows == 0 {
+			rows = termRows
+		}
+	}
+	if f.Frame {
+		cols, rows = cols-2, rows-2
+	}
 	return cols, rows
 }
 
-func scalar(dx, dy int, cols, rows int) float64 {
-	scale := float64(1.0)
-	scaleX := float64(cols*2) / float64(dx)
-	scaleY := float64(rows*4) / float64(dy)
+// scaler returns the Scaler to resize images with. Documents are averaged down by
+// default, so that small text turns gray instead of disappearing between sampled
+// pixels.
+func (f *Filter) scaler() dotmatrix.Scaler {
+	switch {
+	case f.Scaler != nil:
+		return f.Scaler
+	case f.Document:
+		return dotmatrix.Bilinear
+	default:
+		return dotmatrix.NearestNeighbor
+	}
+}
+
+// sized reports whether the image is scaled to explicit dimensions, rather than
+// fit to the terminal.
+func (f *Filter) sized() bool {
+	return f.Scale > 0 || f.Width > 0 || f.Height > 0
+}
+
+// fitter returns the filter that fits images to the terminal, or to Cols and Rows.
+// The terminal is only measured once, so that every frame is fit alike.
+func (f *Filter) fitter() *dotmatrix.FitFilter {
+	if f.fit == nil {
+		cols, rows := f.fitDimensions()
+		if f.FitWidth {
+			// Any number of rows will do.
+			rows = 0
+		}
+		f.fit = &dotmatrix.FitFilter{Cols: cols, Rows: rows, DotAspect: f.DotAspect, Scaler: f.scaler()}
+	}
+	return f.fit
+}
+
+// scalars returns the horizontal and vertical scale factors for an image of
+// size dx by dy. Explicit dimensions may enlarge the image; fitting to the
+// terminal never does.
+func (f *Filter) scalars(dx, dy int) (float64, float64) {
+	aspect := f.DotAspect
+	if aspect <= 0 {
+		aspect = 1
+	}
+	switch {
+	case f.Scale > 0:
+		return f.Scale, f.Scale / aspect
+	case f.Width > 0 && f.Height > 0:
+		return float64(f.Width) / float64(dx), float64(f.Height) / float64(dy)
+	case f.Width > 0:
+		scale := float64(f.Width) / float64(dx)
+		return scale, scale / aspect
+	case f.Height > 0:
+		scale := float64(f.Height) / float64(dy)
+		return scale * aspect, scale
+	}
+	return f.fitter().Scale(dx, dy)
+}
+
+// parseDimension parses a dimension given either in braille cells (eg: "40")
+// or in pixels (eg: "80px") and returns it in pixels. Each cell spans
+// cellPixels pixels along the dimension's axis.
+func parseDimension(s string, cellPixels int) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	px := strings.HasSuffix(s, "px")
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "px"))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid dimension: %q", s)
+	}
+	if px {
+		return n, nil
+	}
+	return n * cellPixels, nil
+}
+
+// parseAlpha parses an alpha policy of the form transparent, white, black,
+// matte:#rrggbb or threshold:N.
+func parseAlpha(s string) (dotmatrix.AlphaPolicy, error) {
+	mode, arg := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		mode, arg = s[:i], s[i+1:]
+	}
+	switch mode {
+	case "", "transparent":
+		return dotmatrix.AlphaPolicy{}, nil
+	case "white":
+		return dotmatrix.MatteAlpha(color.White), nil
+	case "black":
+		return dotmatrix.MatteAlpha(color.Black), nil
+	case "matte":
+		c, err := parseHexColor(arg)
+		if err != nil {
+			return dotmatrix.AlphaPolicy{}, err
+		}
+		return dotmatrix.MatteAlpha(c), nil
+	case "threshold":
+		t, err := strconv.ParseUint(arg, 10, 8)
+		if err != nil {
+			return dotmatrix.AlphaPolicy{}, fmt.Errorf("invalid alpha threshold: %q", arg)
+		}
+		return dotmatrix.ThresholdAlpha(uint8(t)), nil
+	}
+	return dotmatrix.AlphaPolicy{}, fmt.Errorf("invalid alpha policy: %q", s)
+}
 
-	if scaleX < scale {
-		scale = scaleX
+// parseHexColor parses a color of the form #rrggbb or #rgb.
+func parseHexColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
 	}
-	if scaleY < scale {
-		scale = scaleY
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return nil, fmt.Errorf("invalid color: %q", s)
 	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
 
-	return scale
+// tmuxPaneSize asks tmux for the size of the current pane.
+func tmuxPaneSize() (int, int, error) {
+	out, err := exec.Command("tmux", "display-message", "-p", "#{pane_width} #{pane_height}").Output()
+	if err != nil {
+		return 0, 0, err
+	}
+	var cols, rows int
+	_, err = fmt.Sscanf(string(out), "%d %d", &cols, &rows)
+	return cols, rows, err
+}
+
+// terminalDimensions returns the size of stdout to fit images to, in cells. It is
+// the size of the terminal, less a row for the prompt, or else the size given by
+// COLUMNS and LINES, or else the size of the tmux pane, or else 80x25.
+func terminalDimensions() (int, int) {
+	hint := dotmatrix.SizeHintOf(os.Stdout)
+	switch hint.Source {
+	case dotmatrix.SizeFromTerminal:
+		hint.Rows -= 1 // Accounts for the terminal prompt
+	case dotmatrix.SizeDefault:
+		// Output piped elsewhere in tmux, eg: through a pager, is still likely to be
+		// shown in the current pane.
+		if dotmatrix.DetectMultiplexer() == dotmatrix.Tmux {
+			if tw, th, err := tmuxPaneSize(); err == nil && tw > 0 && th > 1 {
+				hint.Cols, hint.Rows = tw, th-1
+			}
+		}
+	}
+	return hint.Cols, hint.Rows
 }
 
 func exit(msg string, code int) {
@@ -465,3 +1629,40 @@ func gopher() image.Image {
 
 	return dest
 }
+
+// parseCorner parses a corner name, such as top-left.
+func parseCorner(s string) (dotmatrix.Corner, error) {
+	switch s {
+	case "top-left":
+		return dotmatrix.TopLeft, nil
+	case "top-right":
+		return dotmatrix.TopRight, nil
+	case "bottom-left":
+		return dotmatrix.BottomLeft, nil
+	case "bottom-right":
+		return dotmatrix.BottomRight, nil
+	}
+	return 0, fmt.Errorf("invalid corner: %q", s)
+}
+
+// loadWatermark decodes the image of an --overlay given as file[@corner].
+func loadWatermark(spec string) (dotmatrix.WatermarkFilter, error) {
+	path, corner := spec, dotmatrix.BottomRight
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		var err error
+		if corner, err = parseCorner(spec[i+1:]); err != nil {
+			return dotmatrix.WatermarkFilter{}, err
+		}
+		path = spec[:i]
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return dotmatrix.WatermarkFilter{}, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return dotmatrix.WatermarkFilter{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return dotmatrix.WatermarkFilter{Image: img, Corner: corner}, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/kevin-cantwell/dotmatrix"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// morePrompt is shown in reverse video on the last line of the terminal while the
+// pager waits for a key.
+const morePrompt = "\033[7m--more--\033[0m"
+
+// pager writes output too tall for the terminal a screenful at a time, like more:
+// once the terminal is full, it prompts and waits for a key. Space shows the next
+// screenful, enter the next line, and q quits, discarding the rest of the output.
+type pager struct {
+	w            io.Writer
+	rows         int  // lines per screenful, leaving the last for the prompt
+	left         int  // lines left before prompting
+	imageOnStdin bool // whether keys must be read from the controlling terminal
+	quit         bool
+}
+
+// newPager returns a pager for still images written to w, as mode asks: auto and
+// always page when w is stdout and a terminal, and never doesn't. It returns nil
+// if output isn't paged.
+func newPager(w io.Writer, mode string, imageOnStdin bool) *pager {
+	if mode == "never" || w != os.Stdout || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+	// Keys piped to stdin along with something other than the image can't be
+	// waited for.
+	if !imageOnStdin && !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	hint := dotmatrix.SizeHintOf(os.Stdout)
+	if hint.Rows < 2 {
+		return nil
+	}
+	return &pager{w: w, rows: hint.Rows - 1, left: hint.Rows - 1, imageOnStdin: imageOnStdin}
+}
+
+func (p *pager) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 && !p.quit {
+		if p.left == 0 {
+			if err := p.prompt(); err != nil {
+				return n - len(b), err
+			}
+			continue
+		}
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+			p.left--
+		}
+		if _, err := p.w.Write(line); err != nil {
+			return n - len(b), err
+		}
+		b = b[len(line):]
+	}
+	return n, nil
+}
+
+// prompt waits for a key, and sets how many lines to write before prompting again.
+func (p *pager) prompt() error {
+	if _, err := io.WriteString(p.w, morePrompt); err != nil {
+		return err
+	}
+	key, err := p.readKey()
+	if _, err := io.WriteString(p.w, "\r\033[2K"); err != nil {
+		return err
+	}
+	switch {
+	case err != nil || key == 'q' || key == 'Q':
+		p.quit = true
+	case key == '\r' || key == '\n':
+		p.left = 1
+	default:
+		p.left = p.rows
+	}
+	return nil
+}
+
+// readKey reads a keypress, from the keys controlling playback if they are open.
+func (p *pager) readKey() (byte, error) {
+	keys := controls
+	if keys == nil {
+		f, err := openKeys(p.imageOnStdin)
+		if err != nil {
+			return 0, err
+		}
+		defer restoreKeys()
+		if f != os.Stdin {
+			defer f.Close()
+		}
+		keys = f
+	}
+	var key [1]byte
+	_, err := io.ReadFull(keys, key[:])
+	return key[0], err
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func pushCommand() cli.Command {
+	return cli.Command{
+		Name:      "push",
+		Usage:     "Render an image on a remote host's terminal over ssh.",
+		ArgsUsage: "[user@]host[:port] [file|url]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "tty,t",
+				Usage: "Remote terminal device to write to (eg: /dev/pts/1). Default is the remote user's first login terminal.",
+			},
+			cli.StringFlag{
+				Name:  "identity,I",
+				Usage: "Private key file used for authentication. ssh-agent and ~/.ssh/id_rsa are tried by default.",
+			},
+			cli.BoolFlag{
+				Name:  "insecure",
+				Usage: "Skip verification of the remote host key against ~/.ssh/known_hosts.",
+			},
+			cli.BoolFlag{
+				Name:  "remote-sync",
+				Usage: "The remote terminal supports synchronized updates, so frames are wrapped in them. The remote terminal can't be asked, so they are off by default.",
+			},
+			cli.Float64Flag{
+				Name:  "dot-aspect",
+				Usage: "How many times taller than wide the remote terminal draws each dot. The remote terminal can't be asked, so dots are taken to be square by default.",
+				Value: 1,
+			},
+		},
+		Action: pushAction,
+	}
+}
+
+func pushAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return cli.ShowCommandHelp(c, "push")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleInterrupt(cancel)
+
+	client, err := dialSSH(c.Args().First(), c.String("identity"), c.Bool("insecure"))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	tty := c.String("tty")
+	if tty == "" {
+		if tty, err = remoteTTY(client); err != nil {
+			return err
+		}
+	}
+
+	cols, rows, err := remoteSize(client, tty)
+	if err != nil {
+		return err
+	}
+
+	reader, mimeType, err := decodeReader(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start("cat > " + shellQuote(tty)); err != nil {
+		return err
+	}
+
+	// The local terminal says nothing about the remote one, which is only known by
+	// the flags. Its background isn't known either, so it's only inverted by
+	// --invert.
+	cfg, err := configFor(c, outputTerminal{sync: c.Bool("remote-sync"), aspect: c.Float64("dot-aspect")})
+	if err != nil {
+		return err
+	}
+	filter := cfg.Filter.(*Filter)
+	filter.Cols, filter.Rows = cols, rows
+
+	if err := render(ctx, c, cfg, stdin, c.Args().Get(1), reader, mimeType); err != nil {
+		return err
+	}
+	if err := stdin.Close(); err != nil {
+		return err
+	}
+	return session.Wait()
+}
+
+// dialSSH connects to target, which has the form [user@]host[:port].
+func dialSSH(target, identity string, insecure bool) (*ssh.Client, error) {
+	username := os.Getenv("USER")
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		username, target = target[:i], target[i+1:]
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "22")
+	}
+
+	home := homeDir()
+
+	var auths []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auths = append(auths, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if identity == "" {
+		identity = filepath.Join(home, ".ssh", "id_rsa")
+	}
+	if pem, err := ioutil.ReadFile(identity); err == nil {
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", identity, err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !insecure {
+		var err error
+		hostKeyCallback, err = knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ssh.Dial("tcp", target, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+	})
+}
+
+// remoteTTY finds the first terminal the remote user is logged into.
+func remoteTTY(client *ssh.Client) (string, error) {
+	out, err := runRemote(client, `who | awk -v u="$(id -un)" '$1 == u { print "/dev/" $2; exit }'`)
+	if err != nil {
+		return "", err
+	}
+	tty := strings.TrimSpace(out)
+	if tty == "" {
+		return "", fmt.Errorf("no remote terminal found; use --tty")
+	}
+	return tty, nil
+}
+
+// remoteSize queries the dimensions of the remote terminal device.
+func remoteSize(client *ssh.Client, tty string) (int, int, error) {
+	out, err := runRemote(client, "stty size < "+shellQuote(tty))
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected stty output: %q", out)
+	}
+	rows, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	cols, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	// Leave room for the prompt, as we do locally.
+	return cols, rows - 1, nil
+}
+
+func runRemote(client *ssh.Client, cmd string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(cmd); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", cmd, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func homeDir() string {
+	if u, err := user.Current(); err == nil {
+		return u.HomeDir
+	}
+	return os.Getenv("HOME")
+}
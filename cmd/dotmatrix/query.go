@@ -0,0 +1,158 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// queryTimeout is how long to wait for a terminal to answer queries. Most
+// terminals answer the device attributes query sent after the others at once, so
+// this is only waited out by those that answer nothing at all.
+const queryTimeout = 250 * time.Millisecond
+
+// queries asks the terminal for the size of its cells in pixels, the size of its
+// text area in pixels, its background color and the cursor's position, followed
+// by its device attributes, which every terminal answers last.
+const queries = "\033[16t\033[14t\033]11;?\007\033[6n\033[c"
+
+var (
+	// Reports of the cell size in pixels, and of the text area's size in pixels,
+	// as height;width.
+	cellSizeReport = regexp.MustCompile(`\033\[6;(\d+);(\d+)t`)
+	textAreaReport = regexp.MustCompile(`\033\[4;(\d+);(\d+)t`)
+	// The background color report, as rgb:r/g/b with 1 to 4 hex digits each, ended
+	// by a bell or a string terminator.
+	backgroundReport = regexp.MustCompile(`\033\]11;rgb:([[:xdigit:]]{1,4})/([[:xdigit:]]{1,4})/([[:xdigit:]]{1,4})`)
+	// The cursor position report, as row;column counting from 1.
+	cursorReport = regexp.MustCompile(`\033\[(\d+);(\d+)R`)
+	// The device attributes report, which ends the terminal's answers.
+	attributesReport = regexp.MustCompile(`\033\[\?[\d;]*c`)
+)
+
+// terminalAnswers are the terminal's answers to the queries, and its size in
+// cells when asked.
+type terminalAnswers struct {
+	answers    []byte
+	cols, rows int
+}
+
+var (
+	queryOnce     sync.Once
+	cachedAnswers terminalAnswers
+)
+
+// askTerminal returns the controlling terminal's answers to the queries, which
+// are empty if it can't be asked or doesn't answer. The terminal is only asked
+// once.
+func askTerminal() terminalAnswers {
+	queryOnce.Do(func() {
+		cachedAnswers = queryTerminal()
+	})
+	return cachedAnswers
+}
+
+func queryTerminal() terminalAnswers {
+	var t terminalAnswers
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return t
+	}
+	defer tty.Close()
+	// Fd would put the terminal in blocking mode, where reads ignore deadlines.
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return t
+	}
+	var fd int
+	conn.Control(func(d uintptr) { fd = int(d) })
+	if t.cols, t.rows, err = terminal.GetSize(fd); err != nil {
+		return t
+	}
+	// Answers are only readable as they arrive in raw mode, and mustn't be echoed.
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return t
+	}
+	defer terminal.Restore(fd, state)
+	if err := tty.SetReadDeadline(time.Now().Add(queryTimeout)); err != nil {
+		return t
+	}
+	if _, err := tty.WriteString(queries); err != nil {
+		return t
+	}
+
+	buf := make([]byte, 256)
+	for !attributesReport.Match(t.answers) {
+		n, err := tty.Read(buf)
+		t.answers = append(t.answers, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return t
+}
+
+// dotAspect returns how many times taller than wide each braille dot is drawn,
+// from the pixel size of the terminal's cells, which are two dots wide and four
+// tall. It is 1, for square dots, if the terminal doesn't say.
+func dotAspect() float64 {
+	if w, h, ok := askTerminal().cellSize(); ok {
+		return (float64(h) / 4) / (float64(w) / 2)
+	}
+	return 1
+}
+
+// darkBackground reports whether the terminal's background is dark, and whether
+// the terminal said what its background is.
+func darkBackground() (bool, bool) {
+	m := backgroundReport.FindSubmatch(askTerminal().answers)
+	if m == nil {
+		return false, false
+	}
+	var rgb [3]uint16
+	for i, hex := range m[1:] {
+		v, _ := strconv.ParseUint(string(hex), 16, 16)
+		// Components are scaled from their number of digits to 16 bits.
+		rgb[i] = uint16(v * 0xffff / (uint64(1)<<(4*uint(len(hex))) - 1))
+	}
+	// The background is weighed as the library weighs pixels, by color.GrayModel.
+	gray := color.GrayModel.Convert(color.RGBA64{rgb[0], rgb[1], rgb[2], 0xffff}).(color.Gray)
+	return gray.Y < 0x80, true
+}
+
+// cursorPosition returns the cell the cursor was in when the terminal was asked,
+// counting from 0, reporting false if the terminal didn't say.
+func cursorPosition() (image.Point, bool) {
+	row, col, ok := parseReport(cursorReport, askTerminal().answers)
+	return image.Pt(col-1, row-1), ok
+}
+
+// cellSize returns the size of the terminal's cells in pixels, reporting false if
+// the terminal didn't say.
+func (t terminalAnswers) cellSize() (int, int, bool) {
+	if h, w, ok := parseReport(cellSizeReport, t.answers); ok {
+		return w, h, true
+	}
+	if h, w, ok := parseReport(textAreaReport, t.answers); ok && t.cols > 0 && t.rows > 0 {
+		return w / t.cols, h / t.rows, w >= t.cols && h >= t.rows
+	}
+	return 0, 0, false
+}
+
+// parseReport parses the two numbers of a report, if answers include one.
+func parseReport(report *regexp.Regexp, answers []byte) (int, int, bool) {
+	m := report.FindSubmatch(answers)
+	if m == nil {
+		return 0, 0, false
+	}
+	a, _ := strconv.Atoi(string(m[1]))
+	b, _ := strconv.Atoi(string(m[2]))
+	return a, b, a > 0 && b > 0
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// reconnecting is the input, if it reconnects when it drops, so that players can
+// notify reconnects.
+var reconnecting *dotmatrix.ReconnectingReader
+
+// decodeReconnecting opens url like decodeReader, but reconnects when it drops.
+func decodeReconnecting(ctx context.Context, url string) (io.Reader, string, error) {
+	reconnecting = dotmatrix.NewReconnectingReader(ctx, openURL(url))
+	reconnecting.OnDrop = func(err error) {
+		fmt.Fprintln(os.Stderr, "reconnecting:", err)
+	}
+	return sniff(reconnecting)
+}
+
+// openURL opens url from offset, resuming with a range request if the server
+// supports them. Otherwise, files start over and skip what was already read, and
+// live streams, of unknown length, just start over.
+func openURL(url string) func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+	return func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 400 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		if resp.ContentLength < 0 {
+			return endless{resp.Body}, nil
+		}
+		if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+		return resp.Body, nil
+	}
+}
+
+// endless is a live stream, which only ends when it drops.
+type endless struct {
+	io.ReadCloser
+}
+
+func (e endless) Read(p []byte) (int, error) {
+	n, err := e.ReadCloser.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func replayCommand() cli.Command {
+	return cli.Command{
+		Name:      "replay",
+		Usage:     "Play a recording made with --record, eg: on devices too slow to render it.",
+		ArgsUsage: "<file.dmx>",
+		Description: "Writes each recorded frame exactly as it was rendered, with its recorded timing, so nothing\n" +
+			"   is decoded, scaled or dithered. The --loop, --step and notification flags apply as usual,\n" +
+			"   and --export-cast writes the recording as an asciinema cast instead of playing it.",
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "seek",
+				Usage: "Frame to start from, counting from 0.",
+			},
+		},
+		Action: replayAction,
+	}
+}
+
+func replayAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.ShowCommandHelp(c, "replay")
+	}
+	file, err := os.Open(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rec, err := dotmatrix.ReadRecording(file)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+	cfg.Name = filepath.Base(file.Name())
+
+	if path := c.GlobalString("export-cast"); path != "" {
+		return writeCast(cfg, rec, path)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleInterrupt(cancel)
+
+	if c.GlobalBool("step") {
+		if cfg.Step, err = openKeys(false); err != nil {
+			return err
+		}
+		defer restoreKeys()
+	} else if !c.GlobalBool("no-keys") {
+		openControls(false)
+		defer restoreKeys()
+	}
+
+	player := dotmatrix.NewReplayPlayer(os.Stdout, cfg, rec)
+	if seek := c.Int("seek"); seek > 0 {
+		player.Seek(seek)
+	}
+	return play(ctx, cfg, player)
+}
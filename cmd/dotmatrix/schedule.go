@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"gopkg.in/yaml.v2"
+)
+
+func scheduleCommand() cli.Command {
+	return cli.Command{
+		Name:      "schedule",
+		Usage:     "Play images, gifs and streams on a schedule, eg: for terminal signage.",
+		ArgsUsage: "<schedule.yaml>",
+		Description: "Plays the playlist of whichever slot covers the time of day, or else the default playlist,\n" +
+			"   breaking in with each interleaved input every so often. For example:\n\n" +
+			"     hold: 10s                 # how long to show each item, unless it says otherwise\n" +
+			"     playlist: [idle.gif]      # played outside of any slot\n" +
+			"     slots:\n" +
+			"       - days: [mon, tue, wed, thu, fri]\n" +
+			"         from: \"09:00\"\n" +
+			"         to: \"17:00\"\n" +
+			"         playlist: [news.gif, {input: chart.png, for: 30s}]\n" +
+			"     interleave:\n" +
+			"       - input: http://camera.local/video.mjpeg\n" +
+			"         every: 15m\n" +
+			"         for: 1m\n\n" +
+			"   Gifs and streams play for as long as the item is shown, leaving their last frame on screen if they end sooner.",
+		Action: scheduleAction,
+	}
+}
+
+// schedule decides what to play when.
+type schedule struct {
+	Hold       time.Duration     `yaml:"hold"`
+	Playlist   []scheduleItem    `yaml:"playlist"`
+	Slots      []scheduleSlot    `yaml:"slots"`
+	Interleave []interleavedItem `yaml:"interleave"`
+}
+
+// scheduleItem is an input to play, for For if it is set or else the schedule's
+// hold time. Items may be given as just the input.
+type scheduleItem struct {
+	Input string        `yaml:"input"`
+	For   time.Duration `yaml:"for"`
+}
+
+func (item *scheduleItem) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&item.Input); err == nil {
+		return nil
+	}
+	type plain scheduleItem
+	return unmarshal((*plain)(item))
+}
+
+// interleavedItem breaks in with an input every so often, whatever else is
+// playing.
+type interleavedItem struct {
+	Input string        `yaml:"input"`
+	For   time.Duration `yaml:"for"`
+	Every time.Duration `yaml:"every"`
+}
+
+// scheduleSlot is a playlist for a time of day, on some days of the week. Slots
+// that end before they start run past midnight.
+type scheduleSlot struct {
+	Days     []string       `yaml:"days"`
+	From     string         `yaml:"from"`
+	To       string         `yaml:"to"`
+	Playlist []scheduleItem `yaml:"playlist"`
+
+	days     map[time.Weekday]bool
+	from, to time.Duration // since midnight
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func loadSchedule(path string) (*schedule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &schedule{}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+	if s.Hold <= 0 {
+		s.Hold = 10 * time.Second
+	}
+	if len(s.Playlist) == 0 && len(s.Slots) == 0 {
+		return nil, fmt.Errorf("invalid schedule: nothing to play")
+	}
+	for i := range s.Slots {
+		slot := &s.Slots[i]
+		if len(slot.Playlist) == 0 {
+			return nil, fmt.Errorf("invalid schedule: slot %d has an empty playlist", i+1)
+		}
+		if slot.from, err = parseClock(slot.From); err != nil {
+			return nil, err
+		}
+		if slot.to, err = parseClock(slot.To); err != nil {
+			return nil, err
+		}
+		if len(slot.Days) > 0 {
+			slot.days = map[time.Weekday]bool{}
+		}
+		for _, day := range slot.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("invalid schedule: invalid day: %q", day)
+			}
+			slot.days[weekday] = true
+		}
+	}
+	for i, item := range s.Interleave {
+		if item.Input == "" || item.Every <= 0 {
+			return nil, fmt.Errorf("invalid schedule: interleaved item %d needs an input and every", i+1)
+		}
+	}
+	return s, nil
+}
+
+// parseClock parses a time of day of the form 15:04.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule: invalid time of day: %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// covers reports whether the slot covers t.
+func (slot *scheduleSlot) covers(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+	switch {
+	case slot.from <= slot.to:
+		if clock < slot.from || clock >= slot.to {
+			return false
+		}
+	case clock >= slot.from:
+	case clock < slot.to:
+		// Past midnight, so the slot started the day before.
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return slot.days == nil || slot.days[day]
+}
+
+// slot returns the index of the first slot covering t, or -1 if there is none.
+func (s *schedule) slot(t time.Time) int {
+	for i := range s.Slots {
+		if s.Slots[i].covers(t) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *schedule) playlist(slot int) []scheduleItem {
+	if slot < 0 {
+		return s.Playlist
+	}
+	return s.Slots[slot].Playlist
+}
+
+func scheduleAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.ShowCommandHelp(c, "schedule")
+	}
+	s, err := loadSchedule(c.Args().First())
+	if err != nil {
+		return err
+	}
+	// Validate the rendering flags up front rather than on every item.
+	if _, err := config(c); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleInterrupt(cancel)
+	if c.GlobalString("profile") == "" {
+		showCursor(false)
+		defer showCursor(true)
+	}
+
+	start := time.Now()
+	due := make([]time.Time, len(s.Interleave))
+	for i, item := range s.Interleave {
+		due[i] = start.Add(item.Every)
+	}
+	next := map[int]int{} // the next item of each slot's playlist
+	for ctx.Err() == nil {
+		now := time.Now()
+		slot := s.slot(now)
+		var item scheduleItem
+		interleaving := false
+		for i := range s.Interleave {
+			if !now.Before(due[i]) {
+				item, interleaving = scheduleItem{Input: s.Interleave[i].Input, For: s.Interleave[i].For}, true
+				due[i] = now.Add(s.Interleave[i].Every)
+				break
+			}
+		}
+		if !interleaving {
+			playlist := s.playlist(slot)
+			if len(playlist) == 0 {
+				// Nothing to play outside of the slots.
+				item = scheduleItem{For: time.Second}
+			} else {
+				item = playlist[next[slot]%len(playlist)]
+				next[slot]++
+			}
+		}
+		hold := item.For
+		if hold <= 0 {
+			hold = s.Hold
+		}
+
+		itemCtx, cancelItem := context.WithTimeout(ctx, hold)
+		if !interleaving {
+			// Cut the item short when its slot ends or an interleaved item is due.
+			due := append([]time.Time(nil), due...)
+			go func() {
+				ticker := time.NewTicker(time.Second)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-itemCtx.Done():
+						return
+					case now := <-ticker.C:
+						if s.slot(now) != slot {
+							cancelItem()
+						}
+						for _, t := range due {
+							if !now.Before(t) {
+								cancelItem()
+							}
+						}
+					}
+				}
+			}()
+		}
+		if item.Input != "" {
+			if err := playScheduled(itemCtx, c, item.Input); err != nil && itemCtx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", item.Input, err)
+			}
+		}
+		<-itemCtx.Done()
+		cancelItem()
+	}
+	return nil
+}
+
+// playScheduled clears the screen and plays input until it ends or ctx is done.
+// Still images are left on screen.
+func playScheduled(ctx context.Context, c *cli.Context, input string) error {
+	// Each item gets its own config, since filters such as motion highlighting keep
+	// state from frame to frame.
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+	cfg.Name = path.Base(input)
+	var body io.ReadCloser
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		req, err := http.NewRequest("GET", input, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		body = resp.Body
+	} else if body, err = os.Open(input); err != nil {
+		return err
+	}
+	defer body.Close()
+	reader, mimeType, err := sniff(body)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, "\033[2J\033[H")
+	return render(ctx, c, cfg, os.Stdout, input, reader, mimeType)
+}
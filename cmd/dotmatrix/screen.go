@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"github.com/codegangsta/cli"
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func screenCommand() cli.Command {
+	return cli.Command{
+		Name:  "screen",
+		Usage: "Mirror the desktop live, eg: for low-bandwidth screen sharing over SSH.",
+		Description: "Captures the screen with ffmpeg, which must be installed: with x11grab on Linux, avfoundation\n" +
+			"   on macOS and gdigrab on Windows. The rendering flags apply as usual.",
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "display",
+				Usage: "The display to capture. The default is $DISPLAY on Linux, and the main screen elsewhere.",
+				Value: -1,
+			},
+			cli.StringFlag{
+				Name:  "region",
+				Usage: "Capture only the region x,y,w,h of the screen, in pixels (eg: 0,0,1280,720).",
+			},
+			cli.IntFlag{
+				Name:  "fps",
+				Usage: "How many frames a second to capture.",
+				Value: 10,
+			},
+		},
+		Action: screenAction,
+	}
+}
+
+func screenAction(c *cli.Context) error {
+	var region []int
+	if spec := c.String("region"); spec != "" {
+		var x, y, w, h int
+		if _, err := fmt.Sscanf(spec, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil || w <= 0 || h <= 0 {
+			return fmt.Errorf("invalid region: %q", spec)
+		}
+		region = []int{x, y, w, h}
+	}
+	if c.Int("fps") <= 0 {
+		return fmt.Errorf("invalid fps: %d", c.Int("fps"))
+	}
+	cfg, err := config(c)
+	if err != nil {
+		return err
+	}
+	cfg.Name = "screen"
+	src, err := startFFmpeg(nil, screenInput(runtime.GOOS, c.Int("display"), region, c.Int("fps"))...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go handleInterrupt(cancel)
+
+	// The player stops ffmpeg when it stops.
+	return play(ctx, cfg, dotmatrix.NewVideoPlayer(os.Stdout, cfg, src))
+}
+
+// screenInput returns the ffmpeg input arguments that capture a display, or the
+// main one if display is negative, on the given OS. A region, if given, is the
+// x, y, width and height to capture.
+func screenInput(goos string, display int, region []int, fps int) []string {
+	rate := strconv.Itoa(fps)
+	switch goos {
+	case "darwin":
+		screen := "Capture screen 0"
+		if display >= 0 {
+			screen = "Capture screen " + strconv.Itoa(display)
+		}
+		args := []string{"-f", "avfoundation", "-framerate", rate, "-capture_cursor", "1", "-i", screen}
+		if region != nil {
+			args = append(args, "-vf", fmt.Sprintf("crop=%d:%d:%d:%d", region[2], region[3], region[0], region[1]))
+		}
+		return args
+	case "windows":
+		args := []string{"-f", "gdigrab", "-framerate", rate}
+		if region != nil {
+			args = append(args,
+				"-offset_x", strconv.Itoa(region[0]), "-offset_y", strconv.Itoa(region[1]),
+				"-video_size", fmt.Sprintf("%dx%d", region[2], region[3]))
+		}
+		return append(args, "-i", "desktop")
+	default:
+		input := os.Getenv("DISPLAY")
+		if display >= 0 {
+			input = ":" + strconv.Itoa(display)
+		} else if input == "" {
+			input = ":0"
+		}
+		args := []string{"-f", "x11grab", "-framerate", rate}
+		if region != nil {
+			args = append(args, "-video_size", fmt.Sprintf("%dx%d", region[2], region[3]))
+			input += fmt.Sprintf("+%d,%d", region[0], region[1])
+		}
+		return append(args, "-i", input)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Serial devices are assumed to be character displays of this size, unless the
+// size is given explicitly with --width and --height.
+const (
+	serialCols = 80
+	serialRows = 24
+)
+
+// openSerial opens a serial device given as path[@baud], such as
+// /dev/ttyUSB0@115200, and configures it for raw output. The default baud rate is
+// 9600. Unless flow is set, which enables hardware (RTS/CTS) flow control, writes
+// are paced to the line rate so that devices without flow control are not overrun.
+func openSerial(spec string, flow bool) (io.WriteCloser, error) {
+	path, baud := spec, 9600
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		var err error
+		if baud, err = strconv.Atoi(spec[i+1:]); err != nil || baud <= 0 {
+			return nil, fmt.Errorf("invalid baud rate: %q", spec[i+1:])
+		}
+		path = spec[:i]
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// stty configures the terminal device on its standard input, on both Linux and
+	// BSDs.
+	crtscts := "-crtscts"
+	if flow {
+		crtscts = "crtscts"
+	}
+	stty := exec.Command("stty", strconv.Itoa(baud), "raw", "-echo", crtscts)
+	stty.Stdin = f
+	if out, err := stty.CombinedOutput(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("configuring %s: %v: %s", path, err, strings.TrimSpace(string(out)))
+	}
+
+	if flow {
+		return f, nil
+	}
+	// Each byte is framed by a start and a stop bit.
+	return &pacedWriter{WriteCloser: f, rate: baud / 10}, nil
+}
+
+// pacedWriter limits writes to rate bytes per second.
+type pacedWriter struct {
+	io.WriteCloser
+	rate int
+
+	start time.Time
+	sent  int
+}
+
+func (p *pacedWriter) Write(b []byte) (int, error) {
+	now := time.Now()
+	// Start over after an idle period, such as a frame delay, so the idle time
+	// doesn't become credit for a burst.
+	if due := p.start.Add(time.Duration(p.sent) * time.Second / time.Duration(p.rate)); now.After(due) {
+		p.start, p.sent = now, 0
+	}
+
+	var written int
+	for len(b) > 0 {
+		// Write in chunks of roughly a tenth of a second.
+		n := p.rate/10 + 1
+		if n > len(b) {
+			n = len(b)
+		}
+		m, err := p.WriteCloser.Write(b[:n])
+		written += m
+		p.sent += m
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+		time.Sleep(time.Until(p.start.Add(time.Duration(p.sent) * time.Second / time.Duration(p.rate))))
+	}
+	return written, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func serveCommand() cli.Command {
+	return cli.Command{
+		Name:  "serve",
+		Usage: "Run a webhook bridge that renders alert images and forwards them to chat webhooks.",
+		Description: "Accepts POSTed JSON payloads containing an image url (eg: Grafana alert notifications),\n" +
+			"   renders the image as braille text and forwards it to each Slack or Discord webhook.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "listen,l",
+				Usage: "Address to listen on.",
+				Value: ":8080",
+			},
+			cli.StringSliceFlag{
+				Name:  "forward,f",
+				Usage: "Slack or Discord webhook url to forward rendered alerts to. May be repeated.",
+			},
+			cli.IntFlag{
+				Name:  "budget",
+				Usage: "Maximum number of characters in a forwarded message, including the border and caption.",
+				Value: 1800,
+			},
+		},
+		Action: serveAction,
+	}
+}
+
+func serveAction(c *cli.Context) error {
+	forwards := c.StringSlice("forward")
+	if len(forwards) == 0 {
+		return fmt.Errorf("at least one --forward webhook is required")
+	}
+	// Validate the rendering flags up front rather than on every request.
+	if _, err := config(c); err != nil {
+		return err
+	}
+	b := &bridge{
+		forwards: forwards,
+		budget:   c.Int("budget"),
+		config: func() *dotmatrix.Config {
+			cfg, _ := config(c)
+			// There is no terminal to fit to; Alert fits the image to the budget.
+			if filter := cfg.Filter.(*Filter); filter.Width == 0 && filter.Height == 0 && filter.Scale == 0 {
+				filter.Scale = 1
+			}
+			return cfg
+		},
+	}
+	log.Printf("listening on %s", c.String("listen"))
+	return http.ListenAndServe(c.String("listen"), b)
+}
+
+// alertPayload matches the fields we care about from both legacy and unified
+// Grafana alert notifications, as well as a generic {"title", "message", "image_url"}.
+type alertPayload struct {
+	Title    string `json:"title"`
+	RuleName string `json:"ruleName"`
+	Message  string `json:"message"`
+	ImageURL string `json:"imageUrl"`
+	ImageURI string `json:"image_url"`
+	Alerts   []struct {
+		ImageURL string `json:"imageURL"`
+	} `json:"alerts"`
+}
+
+func (p *alertPayload) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return p.RuleName
+}
+
+func (p *alertPayload) images() []string {
+	var urls []string
+	for _, u := range []string{p.ImageURL, p.ImageURI} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	for _, alert := range p.Alerts {
+		if alert.ImageURL != "" {
+			urls = append(urls, alert.ImageURL)
+		}
+	}
+	return urls
+}
+
+type bridge struct {
+	forwards []string
+	budget   int
+	// config returns a new config for each render, since filters hold per-image state.
+	config func() *dotmatrix.Config
+}
+
+func (b *bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload alertPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	urls := payload.images()
+	if len(urls) == 0 {
+		http.Error(w, "payload contains no image url", http.StatusBadRequest)
+		return
+	}
+
+	for _, u := range urls {
+		text, err := b.render(u, payload.title(), payload.Message)
+		if err != nil {
+			log.Printf("%s: %v", u, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		for _, forward := range b.forwards {
+			if err := forwardText(forward, text); err != nil {
+				log.Printf("%s: %v", forward, err)
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// render fetches the image at u and renders it as an alert block no longer than
+// the bridge's character budget.
+func (b *bridge) render(u, title, caption string) (string, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	opts := &dotmatrix.AlertOptions{
+		MaxCols: 60,
+		MaxRows: 30,
+		Caption: caption,
+		Config:  b.config(),
+	}
+	for {
+		var buf bytes.Buffer
+		if err := dotmatrix.Alert(&buf, title, img, opts); err != nil {
+			return "", err
+		}
+		n := utf8.RuneCount(buf.Bytes())
+		if n <= b.budget {
+			return buf.String(), nil
+		}
+		if opts.MaxCols <= 1 && opts.MaxRows <= 1 {
+			return "", fmt.Errorf("cannot render within a budget of %d characters", b.budget)
+		}
+		// Area shrinks with the square of the scale, so shrink both sides by the root.
+		shrink := math.Sqrt(float64(b.budget) / float64(n))
+		opts.MaxCols = shrinkDimension(opts.MaxCols, shrink)
+		opts.MaxRows = shrinkDimension(opts.MaxRows, shrink)
+	}
+}
+
+func shrinkDimension(n int, scale float64) int {
+	shrunk := int(float64(n) * scale)
+	if shrunk >= n {
+		shrunk = n - 1
+	}
+	if shrunk < 1 {
+		shrunk = 1
+	}
+	return shrunk
+}
+
+// forwardText posts text to a Slack or Discord incoming webhook as a code block so
+// that chat clients render it in a monospace font.
+func forwardText(webhook, text string) error {
+	body := map[string]string{"text": "```\n" + text + "```"}
+	if u, err := url.Parse(webhook); err == nil && isDiscord(u.Host) {
+		body = map[string]string{"content": "```\n" + text + "```"}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+func isDiscord(host string) bool {
+	return host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")
+}
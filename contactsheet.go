@@ -0,0 +1,122 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContactSheetOptions configures ContactSheet. Zero values select sensible defaults.
+type ContactSheetOptions struct {
+	// Columns is the number of frames in each row of the sheet. The default is 4.
+	Columns int
+	// TileCols and TileRows cap the size of each frame in braille cells. The
+	// default is 20x5.
+	TileCols, TileRows int
+	// Every prints only every nth frame, starting with the first. The default is 1,
+	// which prints every frame.
+	Every int
+	// Config is used to render the frames. If nil, the default config is used.
+	Config *Config
+}
+
+/*
+ContactSheet prints frames, such as those of an animation, side by side in a grid
+with the index of each frame beneath it, as one static block of text. It's a quick
+way to inspect the contents of an animation in a log or over ssh. Eg:
+
+	⠀⣠⣤⣄⠀  ⠀⠀⣠⣤⣄  ⠀⠀⠀⠀⣠
+	⠀⠙⠛⠋⠀  ⠀⠀⠙⠛⠋  ⠀⠀⠀⠀⠙
+	  #0     #1     #2
+*/
+func ContactSheet(w io.Writer, frames []image.Image, opts *ContactSheetOptions) error {
+	if opts == nil {
+		opts = &ContactSheetOptions{}
+	}
+	columns, every := opts.Columns, opts.Every
+	if columns <= 0 {
+		columns = 4
+	}
+	if every <= 0 {
+		every = 1
+	}
+	tileCols, tileRows := opts.TileCols, opts.TileRows
+	if tileCols <= 0 {
+		tileCols = 20
+	}
+	if tileRows <= 0 {
+		tileRows = 5
+	}
+
+	c := mergeConfig(opts.Config)
+	c.Filter = filters{c.Filter, FitFilter{Cols: tileCols, Rows: tileRows, Scaler: c.Scaler}}
+	// Anything printed besides the art would break up the grid.
+	c.AltText = nil
+
+	type tile struct {
+		index int
+		lines []string
+	}
+	var tiles []tile
+	var width, height int
+	for i := 0; i < len(frames); i += every {
+		var buf bytes.Buffer
+		if err := NewPrinter(&buf, &c).Print(frames[i]); err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+		for _, line := range lines {
+			if n := utf8.RuneCountInString(line); n > width {
+				width = n
+			}
+		}
+		if len(lines) > height {
+			height = len(lines)
+		}
+		tiles = append(tiles, tile{index: i, lines: lines})
+	}
+
+	var out bytes.Buffer
+	for row := 0; row < len(tiles); row += columns {
+		end := row + columns
+		if end > len(tiles) {
+			end = len(tiles)
+		}
+		if row > 0 {
+			out.WriteString("\n")
+		}
+		for y := 0; y <= height; y++ {
+			var line []string
+			for _, t := range tiles[row:end] {
+				switch {
+				case y == height:
+					// Label the frame, centered under its tile.
+					label := "#" + strconv.Itoa(t.index)
+					left := (width - utf8.RuneCountInString(label)) / 2
+					if left < 0 {
+						left = 0
+					}
+					line = append(line, pad(strings.Repeat(" ", left)+label, width))
+				case y < len(t.lines):
+					line = append(line, pad(t.lines[y], width))
+				default:
+					line = append(line, strings.Repeat(" ", width))
+				}
+			}
+			out.WriteString(strings.TrimRight(strings.Join(line, "  "), " ") + "\n")
+		}
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// pad right-pads s with spaces to width runes.
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"io"
+)
+
+// contextWriter writes to w until ctx is done, after which writes fail with ctx's
+// error. Writes are passed on a line at a time, so that ctx is checked between the
+// rows of a frame, even one written at once.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w contextWriter) Write(b []byte) (int, error) {
+	var n int
+	for len(b) > 0 {
+		if err := w.ctx.Err(); err != nil {
+			return n, err
+		}
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+		}
+		m, err := w.w.Write(line)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[len(line):]
+	}
+	return n, nil
+}
+
+// FlushContext flushes img to w with flusher, but gives up between the flusher's
+// writes once ctx is done, returning ctx's error.
+func FlushContext(ctx context.Context, w io.Writer, img image.Image, flusher Flusher) error {
+	return flusher.Flush(contextWriter{ctx, w}, img)
+}
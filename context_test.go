@@ -0,0 +1,41 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// cancellingWriter cancels a context once it has been written to.
+type cancellingWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancellingWriter) Write(b []byte) (int, error) {
+	defer w.cancel()
+	return w.Buffer.Write(b)
+}
+
+var _ = Describe("PrintContext", func() {
+	It("should stop between rows once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out := &cancellingWriter{cancel: cancel}
+		img := image.NewPaletted(image.Rect(0, 0, 4, 16), color.Palette{color.Black})
+
+		err := dotmatrix.NewPrinter(out, nil).PrintContext(ctx, img)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(out.String()).To(Equal("⣿⣿\n"))
+
+		out.Reset()
+		Expect(dotmatrix.FlushContext(ctx, out, img, dotmatrix.BrailleFlusher{})).To(Equal(context.Canceled))
+		Expect(out.Len()).To(BeZero())
+	})
+})
@@ -0,0 +1,182 @@
+package dotmatrix
+
+import (
+	"image"
+	"math"
+)
+
+// ContourFilter traces the outlines of an image as 1-dot-wide black lines on a white
+// background, in the style of a Canny edge detector: the image is smoothed, edges
+// are thinned by non-maximum suppression, and weak edges are only kept when they
+// connect to strong ones. The result is clean line art, suitable for logos and
+// diagrams in small terminals.
+//
+// The filter works at the image's current resolution, so it should be applied after
+// any resizing.
+type ContourFilter struct {
+	// Low and High are the hysteresis thresholds, as fractions of the image's
+	// strongest gradient. Edges weaker than Low are discarded, and edges between
+	// Low and High are kept only if they touch an edge stronger than High. The
+	// defaults are 0.1 and 0.3.
+	Low, High float64
+}
+
+func (f ContourFilter) Filter(img image.Image) image.Image {
+	low, high := f.Low, f.High
+	if low <= 0 {
+		low = 0.1
+	}
+	if high <= 0 {
+		high = 0.3
+	}
+	if low > high {
+		low = high
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	out := image.NewGray(bounds)
+	for i := range out.Pix {
+		out.Pix[i] = 0xff
+	}
+	if w < 3 || h < 3 {
+		return out
+	}
+
+	lum := blur(luminanceMap(img), w, h)
+
+	// Sobel gradients. Border pixels are left at zero.
+	mag := make([]float64, w*h)
+	dir := make([]uint8, w*h)
+	var max float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			at := func(dx, dy int) float64 { return lum[(y+dy)*w+x+dx] }
+			gx := at(1, -1) + 2*at(1, 0) + at(1, 1) - at(-1, -1) - 2*at(-1, 0) - at(-1, 1)
+			gy := at(-1, 1) + 2*at(0, 1) + at(1, 1) - at(-1, -1) - 2*at(0, -1) - at(1, -1)
+			i := y*w + x
+			mag[i] = math.Hypot(gx, gy)
+			if mag[i] > max {
+				max = mag[i]
+			}
+			dir[i] = quantizeAngle(math.Atan2(gy, gx))
+		}
+	}
+	if max == 0 {
+		return out
+	}
+
+	// Non-maximum suppression thins edges to a single pixel by keeping only
+	// pixels that are the strongest along their gradient direction. Ties go to the
+	// pixel behind, so edges falling between two pixels aren't drawn twice.
+	offsets := [4][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	thin := make([]float64, w*h)
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			o := offsets[dir[i]]
+			a := mag[(y+o[1])*w+x+o[0]]
+			b := mag[(y-o[1])*w+x-o[0]]
+			if mag[i] >= a && mag[i] > b {
+				thin[i] = mag[i] / max
+			}
+		}
+	}
+
+	// Hysteresis: flood out from strong edges through weak ones.
+	var stack []int
+	for i, m := range thin {
+		if m >= high {
+			stack = append(stack, i)
+			out.Pix[(i/w)*out.Stride+i%w] = 0
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				j := ny*w + nx
+				p := ny*out.Stride + nx
+				if thin[j] >= low && out.Pix[p] != 0 {
+					out.Pix[p] = 0
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// luminanceMap returns the gray level of each pixel in img, in row-major order, with
+// transparent pixels composited over white.
+func luminanceMap(img image.Image) []float64 {
+	bounds := img.Bounds()
+	lum := make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			lum = append(lum, float64(luminance(img.At(x, y))))
+		}
+	}
+	return lum
+}
+
+// blur applies a separable 5-tap binomial (approximately gaussian) blur to a w by h
+// grid, clamping at the edges.
+func blur(src []float64, w, h int) []float64 {
+	kernel := [5]float64{1, 4, 6, 4, 1}
+	clamp := func(v, max int) int {
+		if v < 0 {
+			return 0
+		}
+		if v >= max {
+			return max - 1
+		}
+		return v
+	}
+	tmp := make([]float64, len(src))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for k, weight := range kernel {
+				sum += weight * src[y*w+clamp(x+k-2, w)]
+			}
+			tmp[y*w+x] = sum / 16
+		}
+	}
+	dst := make([]float64, len(src))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for k, weight := range kernel {
+				sum += weight * tmp[clamp(y+k-2, h)*w+x]
+			}
+			dst[y*w+x] = sum / 16
+		}
+	}
+	return dst
+}
+
+// quantizeAngle maps a gradient angle to one of four directions: horizontal,
+// diagonal down, vertical, and diagonal up.
+func quantizeAngle(theta float64) uint8 {
+	deg := theta * 180 / math.Pi
+	if deg < 0 {
+		deg += 180
+	}
+	switch {
+	case deg < 22.5 || deg >= 157.5:
+		return 0
+	case deg < 67.5:
+		return 1
+	case deg < 112.5:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,104 @@
+package dotmatrix_test
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// shade returns a w by h gray image with the given level at each pixel.
+func shade(w, h int, level func(x, y int) uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{level(x, y)})
+		}
+	}
+	return img
+}
+
+// traced returns img as rows of # for black pixels and . for the rest.
+func traced(img image.Image) string {
+	var b strings.Builder
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r == 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var _ = Describe("ContourFilter", func() {
+	// Black, then white, then a slightly darker gray: a strong edge and a weak one.
+	steps := shade(16, 6, func(x, y int) uint8 {
+		switch {
+		case x < 5:
+			return 0
+		case x < 11:
+			return 0xff
+		default:
+			return 0xd7
+		}
+	})
+
+	It("should trace strong edges as lines a dot wide", func() {
+		Expect(traced(dotmatrix.ContourFilter{}.Filter(steps))).To(Equal("" +
+			"................\n" +
+			"....#...........\n" +
+			"....#...........\n" +
+			"....#...........\n" +
+			"....#...........\n" +
+			"................\n"))
+	})
+
+	It("should keep weak edges once the thresholds are lowered", func() {
+		Expect(traced(dotmatrix.ContourFilter{Low: 0.05, High: 0.1}.Filter(steps))).To(Equal("" +
+			"................\n" +
+			"....#.....#.....\n" +
+			"....#.....#.....\n" +
+			"....#.....#.....\n" +
+			"....#.....#.....\n" +
+			"................\n"))
+	})
+
+	It("should keep weak edges that connect to strong ones", func() {
+		// One edge, fading from strong at the top to weak at the bottom.
+		img := shade(6, 16, func(x, y int) uint8 {
+			if x >= 3 {
+				return 0xff
+			}
+			return uint8(y * 0xe0 / 15)
+		})
+		line := strings.Repeat("..#...\n", 12)
+		// Without a band between the thresholds, the weak end is cut off.
+		Expect(traced(dotmatrix.ContourFilter{Low: 0.3, High: 0.3}.Filter(img))).To(Equal("" +
+			"......\n" + line +
+			"......\n" +
+			"......\n" +
+			"......\n"))
+		// With the default band, it's kept by following the line down from the top.
+		Expect(traced(dotmatrix.ContourFilter{}.Filter(img))).To(Equal("" +
+			"......\n" + line +
+			"..#...\n" +
+			".##...\n" +
+			"......\n"))
+	})
+
+	It("should leave flat and tiny images blank", func() {
+		flat := shade(8, 8, func(x, y int) uint8 { return 0x80 })
+		Expect(traced(dotmatrix.ContourFilter{}.Filter(flat))).NotTo(ContainSubstring("#"))
+		tiny := shade(2, 8, func(x, y int) uint8 { return uint8(x * 0xff) })
+		Expect(traced(dotmatrix.ContourFilter{}.Filter(tiny))).NotTo(ContainSubstring("#"))
+	})
+})
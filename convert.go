@@ -0,0 +1,106 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// ConvertGIF renders giff as a GIFPrinter would, but into a new gif instead of
+// text: each frame is filtered and dithered to the black, white and transparent
+// dots that would be printed, one pixel per dot. This exports the dotmatrix look
+// as a gif that can be shared anywhere.
+//
+// Frames that only change part of the screen are cropped to the part that
+// changed, unless any frame has transparent dots, which are left for the frames
+// under them to show through.
+func ConvertGIF(giff *gif.GIF, c *Config) (*gif.GIF, error) {
+	config := mergeConfig(c)
+	t := &animationTrack{c: &config, anim: newGIFCanvas(giff, gifBackground(giff, &config))}
+
+	var screens []*image.Paletted
+	var damages []image.Rectangle
+	transparent := false
+	for i := range giff.Image {
+		screen, damage, _, err := t.frame(context.Background(), i)
+		if err != nil {
+			return nil, err
+		}
+		// The track reuses its screens, so each is copied.
+		img := copyRegion(screen.(*image.Paletted), screen.Bounds())
+		screens, damages = append(screens, img), append(damages, damage)
+		for _, v := range img.Pix {
+			if img.Palette[v] == color.Transparent {
+				transparent = true
+				break
+			}
+		}
+	}
+
+	out := &gif.GIF{LoopCount: giff.LoopCount}
+	for i, screen := range screens {
+		frame, disposal := screen, byte(gif.DisposalNone)
+		switch {
+		case transparent:
+			// A transparent dot over a dot from the last frame would show the last
+			// dot, so every frame is drawn on a cleared screen.
+			disposal = gif.DisposalBackground
+		case i > 0 && damages[i].Empty():
+			// GIF frames can't be empty, so a frame that changes nothing redraws a dot.
+			frame = copyRegion(screen, image.Rect(screen.Rect.Min.X, screen.Rect.Min.Y, screen.Rect.Min.X+1, screen.Rect.Min.Y+1))
+		case i > 0:
+			frame = copyRegion(screen, damages[i])
+		}
+		out.Image = append(out.Image, frame)
+		out.Delay = append(out.Delay, giff.Delay[i])
+		out.Disposal = append(out.Disposal, disposal)
+	}
+	if len(screens) > 0 {
+		out.Config = image.Config{
+			ColorModel: screens[0].Palette,
+			Width:      screens[0].Rect.Max.X,
+			Height:     screens[0].Rect.Max.Y,
+		}
+	}
+	return out, nil
+}
+
+// RecordGIF renders the first play through of giff as a GIFPrinter would, without
+// waiting out the frames' delays, into a recording.
+func RecordGIF(giff *gif.GIF, c *Config) (*Recording, error) {
+	config := mergeConfig(c)
+	return recordTrack(&animationTrack{c: &config, anim: newGIFCanvas(giff, gifBackground(giff, &config))}, &config)
+}
+
+// RecordWebP renders the first play through of an animated WebP as a WebPPrinter
+// would, without waiting out the frames' delays, into a recording.
+func RecordWebP(anim *WebP, c *Config) (*Recording, error) {
+	config := mergeConfig(c)
+	return recordTrack(&animationTrack{c: &config, anim: newWebPCanvas(anim)}, &config)
+}
+
+// recordTrack flushes every frame of t in turn.
+func recordTrack(t track, c *Config) (*Recording, error) {
+	rec := &Recording{}
+	for i := 0; ; i++ {
+		screen, _, delay, err := t.frame(context.Background(), i)
+		if err == io.EOF {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := flush(&buf, screen, c.Flusher); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			bounds := screen.Bounds()
+			rec.Cols, rec.Rows = (bounds.Dx()+1)/2, (bounds.Dy()+3)/4
+		}
+		rec.Frames = append(rec.Frames, RecordedFrame{Text: buf.Bytes(), Delay: delay})
+	}
+}
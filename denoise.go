@@ -0,0 +1,74 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+)
+
+// DenoiseFilter suppresses sensor noise with a median filter, so that noise in dark
+// webcam footage isn't amplified into flickering dots by error diffusion. It is
+// best applied after any downscaling, since scaling already averages away some
+// noise and the median is cheaper on smaller images.
+type DenoiseFilter struct {
+	// Strength is the radius of the median window in pixels. A strength of 1 uses a
+	// 3x3 window, 2 a 5x5 window, and so on. Values less than 1 disable the filter.
+	Strength int
+}
+
+func (f DenoiseFilter) Filter(img image.Image) image.Image {
+	radius := f.Strength
+	if radius < 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	dst := image.NewNRGBA(bounds)
+	size := (2*radius + 1) * (2*radius + 1)
+	channels := [4][]uint8{
+		make([]uint8, 0, size), make([]uint8, 0, size), make([]uint8, 0, size), make([]uint8, 0, size),
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			for c := range channels {
+				channels[c] = channels[c][:0]
+			}
+			for wy := y - radius; wy <= y+radius; wy++ {
+				if wy < 0 || wy >= h {
+					continue
+				}
+				for wx := x - radius; wx <= x+radius; wx++ {
+					if wx < 0 || wx >= w {
+						continue
+					}
+					i := src.PixOffset(wx, wy)
+					for c := range channels {
+						channels[c] = append(channels[c], src.Pix[i+c])
+					}
+				}
+			}
+			dst.SetNRGBA(bounds.Min.X+x, bounds.Min.Y+y, color.NRGBA{
+				median(channels[0]), median(channels[1]), median(channels[2]), median(channels[3]),
+			})
+		}
+	}
+	return dst
+}
+
+// median sorts values in place and returns the middle one. Windows are small, so
+// an insertion sort is fastest.
+func median(values []uint8) uint8 {
+	for i := 1; i < len(values); i++ {
+		for j := i; j > 0 && values[j] < values[j-1]; j-- {
+			values[j], values[j-1] = values[j-1], values[j]
+		}
+	}
+	return values[len(values)/2]
+}
@@ -0,0 +1,163 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+)
+
+// monoPalette is the dotmatrix palette as the standard library's drawers see it:
+// 16-bit, premultiplied RGBA.
+var monoPalette = func() (p [3][4]int32) {
+	for i, c := range defaultPalette {
+		r, g, b, a := c.RGBA()
+		p[i] = [4]int32{int32(r), int32(g), int32(b), int32(a)}
+	}
+	return p
+}()
+
+// ditherBuffers hold a row of source pixels and the quantization errors carried
+// into the current and next rows. They are pooled, so that dithering each frame
+// of an animation doesn't allocate them anew.
+type ditherBuffers struct {
+	row, curr, next [][4]int32
+}
+
+var ditherPool = sync.Pool{New: func() interface{} { return &ditherBuffers{} }}
+
+/*
+ditherMono draws src into dst, an image of the same size in the dotmatrix palette,
+exactly as drawer would if it is draw.FloydSteinberg or draw.Src. Unlike them, it
+is specialized for the dotmatrix palette, reads the pixels of RGBA, NRGBA and YCbCr
+images directly, and reuses its buffers from frame to frame. It reports false,
+leaving dst untouched, for other drawers and images.
+*/
+func ditherMono(dst *image.Paletted, src image.Image, drawer draw.Drawer) bool {
+	var diffuse bool
+	switch drawer {
+	case draw.FloydSteinberg:
+		diffuse = true
+	case draw.Src:
+	default:
+		return false
+	}
+	switch src.(type) {
+	case *image.RGBA, *image.NRGBA, *image.YCbCr:
+	default:
+		return false
+	}
+
+	buf := ditherPool.Get().(*ditherBuffers)
+	defer ditherPool.Put(buf)
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	// The +2 simplifies diffusing errors past the edges.
+	buf.row = resizeErrors(buf.row, w)
+	buf.curr = resizeErrors(buf.curr, w+2)
+	buf.next = resizeErrors(buf.next, w+2)
+	sp := src.Bounds().Min
+	for y := 0; y < h; y++ {
+		readRGBA(buf.row, src, sp.X, sp.Y+y)
+		pix := dst.Pix[y*dst.Stride:][:w]
+		curr, next := buf.curr, buf.next
+		for x, px := range buf.row {
+			er, eg, eb, ea := px[0], px[1], px[2], px[3]
+			if diffuse {
+				er = clamp16(er + curr[x+1][0]/16)
+				eg = clamp16(eg + curr[x+1][1]/16)
+				eb = clamp16(eb + curr[x+1][2]/16)
+				ea = clamp16(ea + curr[x+1][3]/16)
+			}
+			// The closest palette color, by sum-squared-difference, with ties going to
+			// the first, as the standard library finds it.
+			best, bestSum := 0, uint32(1<<32-1)
+			for i, p := range monoPalette {
+				sum := sqDiff(er, p[0]) + sqDiff(eg, p[1]) + sqDiff(eb, p[2]) + sqDiff(ea, p[3])
+				if sum < bestSum {
+					best, bestSum = i, sum
+					if sum == 0 {
+						break
+					}
+				}
+			}
+			pix[x] = uint8(best)
+			if !diffuse {
+				continue
+			}
+			p := monoPalette[best]
+			e := [4]int32{er - p[0], eg - p[1], eb - p[2], ea - p[3]}
+			for i := range e {
+				next[x][i] += e[i] * 3
+				next[x+1][i] += e[i] * 5
+				next[x+2][i] += e[i]
+				curr[x+2][i] += e[i] * 7
+			}
+		}
+		if diffuse {
+			buf.curr, buf.next = next, curr
+			for i := range buf.next {
+				buf.next[i] = [4]int32{}
+			}
+		}
+	}
+	return true
+}
+
+// resizeErrors returns errs resized to n zeroed elements, reusing its storage if
+// there is enough of it.
+func resizeErrors(errs [][4]int32, n int) [][4]int32 {
+	if cap(errs) < n {
+		return make([][4]int32, n)
+	}
+	errs = errs[:n]
+	for i := range errs {
+		errs[i] = [4]int32{}
+	}
+	return errs
+}
+
+// readRGBA reads the row of src starting at x, y into row as 16-bit premultiplied
+// RGBA, as color.Color's RGBA method returns it.
+func readRGBA(row [][4]int32, src image.Image, x, y int) {
+	switch src := src.(type) {
+	case *image.RGBA:
+		pix := src.Pix[src.PixOffset(x, y):]
+		for i := range row {
+			p := pix[4*i : 4*i+4]
+			row[i] = [4]int32{int32(p[0]) * 0x101, int32(p[1]) * 0x101, int32(p[2]) * 0x101, int32(p[3]) * 0x101}
+		}
+	case *image.NRGBA:
+		pix := src.Pix[src.PixOffset(x, y):]
+		for i := range row {
+			p := pix[4*i : 4*i+4]
+			a := uint32(p[3])
+			row[i] = [4]int32{
+				int32(uint32(p[0]) * 0x101 * a / 0xff),
+				int32(uint32(p[1]) * 0x101 * a / 0xff),
+				int32(uint32(p[2]) * 0x101 * a / 0xff),
+				int32(a * 0x101),
+			}
+		}
+	case *image.YCbCr:
+		for i := range row {
+			r, g, b, a := src.YCbCrAt(x+i, y).RGBA()
+			row[i] = [4]int32{int32(r), int32(g), int32(b), int32(a)}
+		}
+	}
+}
+
+func clamp16(i int32) int32 {
+	if i < 0 {
+		return 0
+	}
+	if i > 0xffff {
+		return 0xffff
+	}
+	return i
+}
+
+// sqDiff returns the squared difference of x and y, shifted by 2 so that adding
+// four of them won't overflow a uint32.
+func sqDiff(x, y int32) uint32 {
+	d := uint32(x - y)
+	return (d * d) >> 2
+}
@@ -0,0 +1,42 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Dithering", func() {
+	printed := func(img image.Image, drawer draw.Drawer) string {
+		var out bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&out, &dotmatrix.Config{Drawer: drawer}).Print(img)).To(Succeed())
+		return out.String()
+	}
+
+	It("should dither exactly as the standard library does", func() {
+		r := image.Rect(5, 3, 77, 51)
+		rgba, nrgba := image.NewRGBA(r), image.NewNRGBA(r)
+		ycbcr := image.NewYCbCr(r, image.YCbCrSubsampleRatio420)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				v := uint8(x*x + y*5)
+				a := uint8(255 - x*y%97)
+				rgba.SetRGBA(x, y, color.RGBA{v / 2, v / 3, v / 4, 255})
+				nrgba.SetNRGBA(x, y, color.NRGBA{v, v / 2, 255 - v, a})
+				ycbcr.Y[ycbcr.YOffset(x, y)] = v
+				ycbcr.Cb[ycbcr.COffset(x, y)] = uint8(x * 3)
+			}
+		}
+		for _, img := range []image.Image{rgba, nrgba, ycbcr} {
+			for _, drawer := range []draw.Drawer{draw.FloydSteinberg, draw.Src} {
+				Expect(printed(img, drawer)).To(Equal(printed(opaqueImage{img}, drawer)))
+			}
+		}
+	})
+})
@@ -0,0 +1,178 @@
+package dotmatrix
+
+import (
+	"image"
+	"math"
+)
+
+// DocumentFilter cleans up photographed pages for previewing: it stretches the
+// contrast so paper is white and ink is black, straightens pages that were shot at
+// a slight angle, and binarizes them with a threshold that adapts to uneven
+// lighting. Like ContourFilter, it works at the image's current resolution and is
+// best applied after downscaling with a filter that averages, so that small text
+// becomes gray rather than disappearing.
+type DocumentFilter struct {
+	// MaxSkew is the largest skew corrected, in degrees. The default is 10.
+	MaxSkew float64
+	// Window is the width in pixels of the neighborhood that each pixel's threshold
+	// is computed from. The default is an eighth of the image's longer side.
+	Window int
+}
+
+func (f DocumentFilter) Filter(img image.Image) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	out := image.NewGray(bounds)
+	if w == 0 || h == 0 {
+		return out
+	}
+	maxSkew := f.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = 10
+	}
+	window := f.Window
+	if window <= 0 {
+		window = w
+		if h > w {
+			window = h
+		}
+		window /= 8
+	}
+	if window < 3 {
+		window = 3
+	}
+
+	lum := normalize(luminanceMap(img))
+	if angle := skewAngle(lum, w, h, maxSkew); angle != 0 {
+		lum = rotate(lum, w, h, angle)
+	}
+
+	// Bradley's adaptive threshold: pixels noticeably darker than the mean of their
+	// neighborhood are ink. The means come from a summed-area table.
+	const sensitivity = 0.15
+	sums := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var row float64
+		for x := 0; x < w; x++ {
+			row += lum[y*w+x]
+			sums[(y+1)*(w+1)+x+1] = sums[y*(w+1)+x+1] + row
+		}
+	}
+	clamp := func(v, max int) int {
+		if v < 0 {
+			return 0
+		}
+		if v > max {
+			return max
+		}
+		return v
+	}
+	half := window / 2
+	for y := 0; y < h; y++ {
+		y0, y1 := clamp(y-half, h), clamp(y+half+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := clamp(x-half, w), clamp(x+half+1, w)
+			sum := sums[y1*(w+1)+x1] - sums[y0*(w+1)+x1] - sums[y1*(w+1)+x0] + sums[y0*(w+1)+x0]
+			mean := sum / float64((x1-x0)*(y1-y0))
+			if lum[y*w+x] >= mean*(1-sensitivity) {
+				out.Pix[y*out.Stride+x] = 0xff
+			}
+		}
+	}
+	return out
+}
+
+// normalize stretches gray levels so that the darkest and lightest 2% of pixels
+// become black and white, which evens out dim or washed out photographs.
+func normalize(lum []float64) []float64 {
+	var histogram [256]int
+	for _, v := range lum {
+		histogram[int(v)]++
+	}
+	cut := len(lum) / 50
+	low, high := 0, 255
+	for n := 0; low < 255 && n+histogram[low] <= cut; low++ {
+		n += histogram[low]
+	}
+	for n := 0; high > 0 && n+histogram[high] <= cut; high-- {
+		n += histogram[high]
+	}
+	if high <= low {
+		return lum
+	}
+	out := make([]float64, len(lum))
+	for i, v := range lum {
+		out[i] = math.Max(0, math.Min(0xff, (v-float64(low))*0xff/float64(high-low)))
+	}
+	return out
+}
+
+// skewAngle estimates the angle in radians at which lines of text run across a w
+// by h grid, searching up to maxSkew degrees either way. Text lines are found by
+// projecting dark pixels onto lines at each candidate angle: at the right angle,
+// lines of text and the gaps between them fall into separate bins, so the squared
+// bin counts are greatest.
+func skewAngle(lum []float64, w, h int, maxSkew float64) float64 {
+	type point struct{ x, y float64 }
+	var dark []point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if lum[y*w+x] < 0x80 {
+				dark = append(dark, point{float64(x), float64(y)})
+			}
+		}
+	}
+	if len(dark) == 0 {
+		return 0
+	}
+
+	const step = 0.25
+	diagonal := int(math.Hypot(float64(w), float64(h)))
+	bins := make([]int, 2*diagonal+1)
+	var best, bestScore float64
+	for deg := -maxSkew; deg <= maxSkew; deg += step {
+		theta := deg * math.Pi / 180
+		sin, cos := math.Sin(theta), math.Cos(theta)
+		for i := range bins {
+			bins[i] = 0
+		}
+		for _, p := range dark {
+			bins[diagonal+int(math.Round(p.y*cos-p.x*sin))]++
+		}
+		var score float64
+		for _, n := range bins {
+			score += float64(n * n)
+		}
+		// Prefer the smallest correction when angles score the same.
+		if score > bestScore || (score == bestScore && math.Abs(theta) < math.Abs(best)) {
+			best, bestScore = theta, score
+		}
+	}
+	return best
+}
+
+// rotate turns a w by h grid by theta radians about its center, so that lines
+// running at theta become horizontal. Pixels rotated in from outside are white.
+func rotate(lum []float64, w, h int, theta float64) []float64 {
+	sin, cos := math.Sin(theta), math.Cos(theta)
+	cx, cy := float64(w-1)/2, float64(h-1)/2
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0xff
+		}
+		return lum[y*w+x]
+	}
+	out := make([]float64, len(lum))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx, sy := cx+dx*cos-dy*sin, cy+dx*sin+dy*cos
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			top := at(x0, y0)*(1-fx) + at(x0+1, y0)*fx
+			bottom := at(x0, y0+1)*(1-fx) + at(x0+1, y0+1)*fx
+			out[y*w+x] = top*(1-fy) + bottom*fy
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package dotmatrix
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+/*
+DOMWriter displays printed output in a web page, for running dotmatrix in the
+browser when compiled to WebAssembly. It keeps a virtual screen that follows the
+cursor movement used to animate, so animations play in place, and shows the
+screen as the text of an element, such as a <pre>. Eg:
+
+	w, err := dotmatrix.NewDOMWriter("output")
+	if err != nil {
+		panic(err)
+	}
+	dotmatrix.NewGIFPrinter(w, nil).Print(ctx, giff)
+
+Colors and other escape sequences are discarded. The element should be styled
+with a font that includes braille characters.
+*/
+type DOMWriter struct {
+	el     js.Value
+	screen textScreen
+}
+
+// NewDOMWriter returns a writer that displays output in the element with the given
+// id.
+func NewDOMWriter(id string) (*DOMWriter, error) {
+	el := js.Global().Get("document").Call("getElementById", id)
+	if el.IsNull() || el.IsUndefined() {
+		return nil, fmt.Errorf("dotmatrix: no element with id %q", id)
+	}
+	return &DOMWriter{el: el}, nil
+}
+
+func (w *DOMWriter) Write(b []byte) (int, error) {
+	n, err := w.screen.Write(b)
+	w.el.Set("textContent", w.screen.String())
+	return n, err
+}
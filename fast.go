@@ -0,0 +1,99 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+)
+
+// rgbaPool recycles the images that fastSource converts into, as frames of an
+// animation are usually the same size.
+var rgbaPool sync.Pool
+
+// fastSource returns img converted to RGBA, if drawer is one of the standard
+// library's, which read RGBA images directly but box every pixel of gray and
+// paletted images in a color.Color. It returns nil if img needn't be converted.
+// The converted image should be put back in rgbaPool once drawn.
+func fastSource(img image.Image, drawer draw.Drawer) *image.RGBA {
+	if drawer != draw.FloydSteinberg && drawer != draw.Src && drawer != draw.Over {
+		return nil
+	}
+	switch src := img.(type) {
+	case *image.Gray:
+		dst := pooledRGBA(src.Rect)
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			s := src.Pix[src.PixOffset(src.Rect.Min.X, y):][:src.Rect.Dx()]
+			d := dst.Pix[dst.PixOffset(dst.Rect.Min.X, y):][:4*len(s)]
+			for i, v := range s {
+				d[4*i], d[4*i+1], d[4*i+2], d[4*i+3] = v, v, v, 0xff
+			}
+		}
+		return dst
+	case *image.Paletted:
+		var rgba [256]color.RGBA
+		for i, c := range src.Palette {
+			rgba[i] = color.RGBAModel.Convert(c).(color.RGBA)
+		}
+		dst := pooledRGBA(src.Rect)
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			s := src.Pix[src.PixOffset(src.Rect.Min.X, y):][:src.Rect.Dx()]
+			d := dst.Pix[dst.PixOffset(dst.Rect.Min.X, y):][:4*len(s)]
+			for i, v := range s {
+				c := rgba[v]
+				d[4*i], d[4*i+1], d[4*i+2], d[4*i+3] = c.R, c.G, c.B, c.A
+			}
+		}
+		return dst
+	}
+	return nil
+}
+
+// pooledRGBA returns an RGBA image with bounds r from the pool, or a new one if
+// none in the pool has enough pixels. Its pixels are not cleared.
+func pooledRGBA(r image.Rectangle) *image.RGBA {
+	n := 4 * r.Dx() * r.Dy()
+	if dst, ok := rgbaPool.Get().(*image.RGBA); ok && cap(dst.Pix) >= n {
+		dst.Pix, dst.Stride, dst.Rect = dst.Pix[:n], 4*r.Dx(), r
+		return dst
+	}
+	return image.NewRGBA(r)
+}
+
+// palettedBraille is BrailleOf for paletted images, reading their pixels directly
+// rather than boxing each in a color.Color. It reports false if the cell isn't
+// within img, whose pixels outside it BrailleOf reads as img.At does.
+func palettedBraille(img *image.Paletted, cell image.Rectangle) (Braille, bool) {
+	var b Braille
+	if !cell.In(img.Rect) {
+		return b, false
+	}
+	for y := cell.Min.Y; y < cell.Max.Y && y-cell.Min.Y < 4; y++ {
+		row := img.Pix[img.PixOffset(cell.Min.X, y):]
+		for x := cell.Min.X; x < cell.Max.X && x-cell.Min.X < 2; x++ {
+			if IsDot(img.Palette[row[x-cell.Min.X]]) {
+				b[x-cell.Min.X][y-cell.Min.Y] = 1
+			}
+		}
+	}
+	return b, true
+}
+
+// grayBraille is BrailleOf for gray images, reading their pixels directly. It
+// reports false if the cell isn't within img.
+func grayBraille(img *image.Gray, cell image.Rectangle) (Braille, bool) {
+	var b Braille
+	if !cell.In(img.Rect) {
+		return b, false
+	}
+	for y := cell.Min.Y; y < cell.Max.Y && y-cell.Min.Y < 4; y++ {
+		row := img.Pix[img.PixOffset(cell.Min.X, y):]
+		for x := cell.Min.X; x < cell.Max.X && x-cell.Min.X < 2; x++ {
+			// Grays darker than 0x80 are darker than mid-gray. See IsDot.
+			if row[x-cell.Min.X] < 0x80 {
+				b[x-cell.Min.X][y-cell.Min.Y] = 1
+			}
+		}
+	}
+	return b, true
+}
@@ -0,0 +1,39 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// opaqueImage hides the type of an image, so that it is printed the slow way.
+type opaqueImage struct {
+	image.Image
+}
+
+var _ = Describe("Fast paths", func() {
+	printed := func(img image.Image) string {
+		var out bytes.Buffer
+		Expect(dotmatrix.Print(&out, img)).To(Succeed())
+		return out.String()
+	}
+
+	It("should print gray and paletted images as the slow way does", func() {
+		gray := image.NewGray(image.Rect(3, 5, 67, 45))
+		plan9 := image.NewPaletted(gray.Rect, palette.Plan9)
+		for y := gray.Rect.Min.Y; y < gray.Rect.Max.Y; y++ {
+			for x := gray.Rect.Min.X; x < gray.Rect.Max.X; x++ {
+				gray.SetGray(x, y, color.Gray{uint8(x*y + x*3)})
+				plan9.SetColorIndex(x, y, uint8(x*7+y*13))
+			}
+		}
+		Expect(printed(gray)).To(Equal(printed(opaqueImage{gray})))
+		Expect(printed(plan9)).To(Equal(printed(opaqueImage{plan9})))
+	})
+})
@@ -0,0 +1,62 @@
+package dotmatrix
+
+import (
+	"image"
+)
+
+// Fingerprint is a 64-bit perceptual hash of an image. Images that look alike have
+// fingerprints with a small Distance, regardless of their size or encoding, which
+// makes fingerprints useful for deduplicating frames and for testing.
+type Fingerprint uint64
+
+// FingerprintOf calculates a difference hash of img. The image is reduced to a 9x8
+// grid of average luminances (transparent pixels count as white), and each bit of
+// the fingerprint records whether a cell is brighter than its right-hand neighbor.
+func FingerprintOf(img image.Image) Fingerprint {
+	const cols, rows = 9, 8
+
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+
+	var sums, counts [rows][cols]uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		gy := (y - bounds.Min.Y) * rows / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gx := (x - bounds.Min.X) * cols / bounds.Dx()
+			sums[gy][gx] += uint64(luminance(img.At(x, y)))
+			counts[gy][gx]++
+		}
+	}
+
+	var fp Fingerprint
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols-1; x++ {
+			fp <<= 1
+			if average(sums[y][x], counts[y][x]) > average(sums[y][x+1], counts[y][x+1]) {
+				fp |= 1
+			}
+		}
+	}
+	return fp
+}
+
+// Distance returns the number of bits that differ between two fingerprints. Zero
+// means the images are perceptually identical; 64 means they are opposites.
+func (fp Fingerprint) Distance(other Fingerprint) int {
+	var n int
+	for v := fp ^ other; v != 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
+
+// average returns sum/count, or zero for empty grid cells, which occur when an
+// image is smaller than the grid.
+func average(sum, count uint64) uint64 {
+	if count == 0 {
+		return 0
+	}
+	return sum / count
+}
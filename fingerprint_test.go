@@ -0,0 +1,53 @@
+package dotmatrix_test
+
+import (
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+func gradient(w, h int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{uint8(255 - x*255/w)})
+		}
+	}
+	return img
+}
+
+var _ = Describe("Fingerprint", func() {
+	It("should not depend on image size", func() {
+		Expect(dotmatrix.FingerprintOf(gradient(90, 80))).To(Equal(dotmatrix.FingerprintOf(gradient(360, 320))))
+	})
+
+	It("should distinguish different images", func() {
+		a := dotmatrix.FingerprintOf(gradient(90, 80))
+		b := dotmatrix.FingerprintOf(image.NewGray(image.Rect(0, 0, 90, 80)))
+		Expect(a.Distance(b)).To(Equal(64))
+	})
+
+	It("should match known fingerprints", func() {
+		// A dark disk left of center on a light background, with a translucent band
+		// across its bottom that composites to pink over white.
+		img := image.NewNRGBA(image.Rect(0, 0, 36, 32))
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 36; x++ {
+				c := color.NRGBA{0xe0, 0xe0, 0xe0, 0xff}
+				if dx, dy := x-12, y-16; dx*dx+dy*dy < 100 {
+					c = color.NRGBA{0x20, 0x30, 0x40, 0xff}
+				}
+				if y >= 20 && y < 26 {
+					c = color.NRGBA{0xff, 0, 0, 0x80}
+				}
+				img.Set(x, y, c)
+			}
+		}
+		Expect(dotmatrix.FingerprintOf(img)).To(Equal(dotmatrix.Fingerprint(0x40c08080000000)))
+		Expect(dotmatrix.FingerprintOf(gradient(90, 80))).To(Equal(dotmatrix.Fingerprint(0xffffffffffffffff)))
+	})
+})
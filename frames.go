@@ -0,0 +1,94 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// RenderedFrame is a frame of an animation or stream, rendered as it would be
+// printed.
+type RenderedFrame struct {
+	// Image is the frame drawn in dots: its black pixels are printed as dots.
+	Image *image.Paletted
+	// Text is the frame as the config's flusher prints it, eg: in braille.
+	Text []byte
+	// Delay is how long to show the frame for. Live streams are paced by their
+	// source, so their frames have no delay.
+	Delay time.Duration
+}
+
+/*
+FrameIterator renders the frames of an animation or stream one at a time, for
+programs that forward them elsewhere, such as chat bots and web services, rather
+than print them to a terminal. The frames are filtered and drawn as printers
+draw them, but are rendered once through, whatever the loop count. Eg:
+
+	frames := dotmatrix.NewGIFIterator(nil, giff)
+	defer frames.Close()
+	for {
+		frame, err := frames.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		...
+	}
+*/
+type FrameIterator struct {
+	t track
+	c *Config
+	n int // frames rendered so far
+}
+
+// NewGIFIterator provides a FrameIterator over the frames of giff.
+func NewGIFIterator(c *Config, giff *gif.GIF) *FrameIterator {
+	p := NewGIFPrinter(nil, c)
+	return &FrameIterator{t: p.track(giff), c: &p.c}
+}
+
+// NewMJPEGIterator provides a FrameIterator over the mjpeg stream r, with fps as
+// for MJPEGPrinter.Print. The stream is read in the background from the first
+// call to Next until the iterator is closed or that call's ctx is done.
+func NewMJPEGIterator(c *Config, r io.Reader, fps int) *FrameIterator {
+	p := NewMJPEGPrinter(nil, c)
+	return &FrameIterator{t: &mjpegTrack{p: p, r: r, fps: fps}, c: &p.c}
+}
+
+// NewVideoIterator provides a FrameIterator over the frames of src.
+func NewVideoIterator(c *Config, src FrameSource) *FrameIterator {
+	p := NewVideoPrinter(nil, c)
+	return &FrameIterator{t: &videoTrack{c: &p.c, src: src}, c: &p.c}
+}
+
+// Next renders the next frame, or returns io.EOF after the last.
+func (it *FrameIterator) Next(ctx context.Context) (RenderedFrame, error) {
+	if it.n == 0 {
+		if err := it.c.Validate(); err != nil {
+			return RenderedFrame{}, err
+		}
+	}
+	screen, _, delay, err := it.t.frame(ctx, it.n)
+	if err != nil {
+		return RenderedFrame{}, err
+	}
+	img, _ := it.c.Stamp.stamp(screen, it.n)
+	it.n++
+
+	var text bytes.Buffer
+	if err := flush(&text, img, it.c.Flusher); err != nil {
+		return RenderedFrame{}, err
+	}
+	// Tracks draw each frame over the last, so frames are copied to be kept.
+	return RenderedFrame{Image: copyImageInto(nil, img), Text: text.Bytes(), Delay: delay}, nil
+}
+
+// Close stops any work in the background, such as reading a stream.
+func (it *FrameIterator) Close() error {
+	if closer, ok := it.t.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
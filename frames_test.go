@@ -0,0 +1,51 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("FrameIterator", func() {
+	It("should render each frame of a gif once", func() {
+		palette := color.Palette{color.White, color.Black}
+		giff := &gif.GIF{LoopCount: 0, Config: image.Config{Width: 4, Height: 4}}
+		for i := 0; i < 3; i++ {
+			img := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+			img.SetColorIndex(i, i, 1)
+			giff.Image = append(giff.Image, img)
+			giff.Delay = append(giff.Delay, 5) // 50ms
+			giff.Disposal = append(giff.Disposal, gif.DisposalNone)
+		}
+
+		frames := dotmatrix.NewGIFIterator(nil, giff)
+		defer frames.Close()
+		var rendered []dotmatrix.RenderedFrame
+		for {
+			frame, err := frames.Next(context.Background())
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			rendered = append(rendered, frame)
+		}
+		Expect(rendered).To(HaveLen(3))
+		for _, frame := range rendered {
+			Expect(frame.Delay).To(Equal(50 * time.Millisecond))
+			var text bytes.Buffer
+			Expect(dotmatrix.NewPrinter(&text, nil).Print(frame.Image)).To(Succeed())
+			Expect(frame.Text).To(Equal(text.Bytes()))
+		}
+		// Frames are kept, not drawn over by the next.
+		Expect(rendered[0].Text).NotTo(Equal(rendered[2].Text))
+	})
+})
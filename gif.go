@@ -4,16 +4,21 @@ import (
 	"context"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/gif"
 	"io"
 	"time"
 )
 
 type GIFPrinter struct {
-	w io.Writer
-	c Config
+	w     io.Writer
+	c     Config
+	stats statsMeter
 }
 
+// NewGIFPrinter provides a GIFPrinter. Neither w nor c is checked until Print,
+// which reports any problem with them before printing anything. Use NewConfig to
+// check c up front.
 func NewGIFPrinter(w io.Writer, c *Config) *GIFPrinter {
 	return &GIFPrinter{
 		w: w,
@@ -25,94 +30,148 @@ func NewGIFPrinter(w io.Writer, c *Config) *GIFPrinter {
 	Print animates a gif
 */
 func (p *GIFPrinter) Print(ctx context.Context, giff *gif.GIF) error {
-	if len(giff.Image) < 1 {
-		return nil
+	player := newPlayer(p.w, &p.c, p.track(giff))
+	player.stats = &p.stats
+	return player.Play(ctx)
+}
+
+// Stats returns how well the current or last Print kept up. It may be called from
+// any goroutine.
+func (p *GIFPrinter) Stats() Stats {
+	return p.stats.snapshot()
+}
+
+// NewGIFPlayer provides a Player for giff, which plays it as a GIFPrinter would.
+func NewGIFPlayer(w io.Writer, c *Config, giff *gif.GIF) *Player {
+	p := NewGIFPrinter(w, c)
+	return newPlayer(p.w, &p.c, p.track(giff))
+}
+
+func (p *GIFPrinter) track(giff *gif.GIF) track {
+	return newAnimationTrack(&p.c, newGIFCanvas(giff, gifBackground(giff, &p.c)))
+}
+
+// gifBackground returns the color background disposal restores: the config's
+// matte color if it has one, or else the global palette's entry at
+// BackgroundIndex. Without a global palette, or with an index outside it, there is
+// no background color and frames restore to transparent.
+func gifBackground(giff *gif.GIF, c *Config) color.Color {
+	if matte := c.matte(); matte != nil {
+		return matte
 	}
+	if palette, ok := giff.Config.ColorModel.(color.Palette); ok && int(giff.BackgroundIndex) < len(palette) {
+		return palette[giff.BackgroundIndex]
+	}
+	return color.Transparent
+}
+
+// gifCanvas composites the frames of a gif onto its logical screen at their
+// original resolution, following their disposal methods.
+type gifCanvas struct {
+	giff *gif.GIF
+	bg   color.Color
+
+	rgba *image.RGBA
+	next int // the frame that follows the last one drawn
+	// Disposal undoes the last frame after it has been shown, by restoring what was
+	// under it, or the background.
+	disposal byte
+	disposed image.Rectangle
+	previous *image.RGBA // what was under the last frame
+}
 
-	// Only used if we see background disposal methods
-	bgPallette := []color.Color{color.Transparent}
-	if giff.Config.ColorModel != nil {
-		bgPallette = giff.Config.ColorModel.(color.Palette)
+func newGIFCanvas(giff *gif.GIF, bg color.Color) *gifCanvas {
+	return &gifCanvas{giff: giff, bg: bg}
+}
+
+func (c *gifCanvas) frames() int {
+	return len(c.giff.Image)
+}
+
+func (c *gifCanvas) delay(i int) time.Duration {
+	return time.Duration(c.giff.Delay[i]) * time.Second / 100
+}
+
+// loops returns how many times to play the gif. In gifs, a loop count of 0 loops
+// forever, -1 plays once, and n restarts the animation n times after the first
+// play.
+func (c *gifCanvas) loops() int {
+	switch n := c.giff.LoopCount; {
+	case n == 0:
+		return 0
+	case n < 0:
+		return 1
+	default:
+		return n + 1
 	}
+}
 
-	// The screen is what we flush to the writer on each iteration
-	screen := redraw(image.NewPaletted(giff.Image[0].Bounds(), bgPallette), p.c.Filter, p.c.Drawer)
-	rows := screen.Bounds().Dy() / 4
-	if screen.Bounds().Dy()%4 != 0 {
-		rows++
+// bounds returns the gif's logical screen, or the first frame's bounds if it
+// doesn't give one.
+func (c *gifCanvas) bounds() image.Rectangle {
+	bounds := image.Rect(0, 0, c.giff.Config.Width, c.giff.Config.Height)
+	if bounds.Empty() {
+		bounds = c.giff.Image[0].Bounds()
 	}
+	return bounds
+}
 
-	for c := 0; giff.LoopCount == 0 || c < giff.LoopCount; c++ {
-		for i := 0; i < len(giff.Image); i++ {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-
-			delay := time.After(time.Duration(giff.Delay[i]) * time.Second / 100)
-
-			frame := redraw(giff.Image[i], p.c.Filter, p.c.Drawer)
-
-			switch giff.Disposal[i] {
-			case gif.DisposalPrevious: // Dispose previous essentially means draw then undo
-				temp := image.NewPaletted(screen.Bounds(), screen.Palette)
-				copy(temp.Pix, screen.Pix)
-
-				p.drawOver(screen, frame)
-				if err := flush(p.w, screen, p.c.Flusher); err != nil {
-					return err
-				}
-				<-delay
-
-				screen = temp
-			case gif.DisposalBackground: // Dispose background replaces everything just drawn with the background canvas
-				background := redraw(image.NewPaletted(frame.Bounds(), bgPallette), p.c.Filter, p.c.Drawer)
-				p.drawExact(screen, background)
-				temp := image.NewPaletted(screen.Bounds(), screen.Palette)
-				copy(temp.Pix, screen.Pix)
-
-				p.drawOver(screen, frame)
-				if err := flush(p.w, screen, p.c.Flusher); err != nil {
-					return err
-				}
-				<-delay
-
-				screen = temp
-			default: // Dispose none or undefined means we just draw what we got over top
-				p.drawOver(screen, frame)
-				if err := flush(p.w, screen, p.c.Flusher); err != nil {
-					return err
-				}
-				<-delay
-			}
-
-			p.c.Reset(p.w, rows)
+// composite returns the canvas with frame i drawn on it. The canvas is drawn over
+// from frame to frame, so it is only valid until the next call.
+func (c *gifCanvas) composite(i int) *image.RGBA {
+	// Frames build on one another, so seeking replays the gif from the start.
+	if c.rgba == nil || (i != c.next && !(i == 0 && c.next == len(c.giff.Image))) {
+		bounds := c.bounds()
+		if c.rgba == nil || c.rgba.Rect != bounds {
+			c.rgba = image.NewRGBA(bounds)
 		}
-	}
-	return nil
-}
-
-// Draws any non-transparent pixels into target
-func (p *GIFPrinter) drawOver(target *image.Paletted, source image.Image) {
-	bounds := source.Bounds()
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			c := source.At(x, y)
-			if c == color.Transparent {
-				continue
-			}
-			target.Set(x, y, c)
+		draw.Draw(c.rgba, bounds, image.NewUniform(c.bg), image.ZP, draw.Src)
+		c.disposal, c.disposed = gif.DisposalNone, image.Rectangle{}
+		for j := 0; j < i; j++ {
+			c.draw(j)
 		}
 	}
+	c.draw(i)
+	return c.rgba
 }
 
-// Draws pixels into target, including transparent ones.
-func (p *GIFPrinter) drawExact(target *image.Paletted, source image.Image) {
-	bounds := source.Bounds()
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			target.Set(x, y, source.At(x, y))
+// draw disposes of the last frame and draws frame i over the canvas.
+func (c *gifCanvas) draw(i int) {
+	switch c.disposal {
+	case gif.DisposalBackground:
+		draw.Draw(c.rgba, c.disposed, image.NewUniform(c.bg), image.ZP, draw.Src)
+	case gif.DisposalPrevious:
+		draw.Draw(c.rgba, c.disposed, c.previous, c.disposed.Min, draw.Src)
+	}
+
+	frame := c.giff.Image[i]
+	r := frame.Bounds().Intersect(c.rgba.Rect)
+	c.disposal, c.disposed = gif.DisposalNone, r
+	if i < len(c.giff.Disposal) {
+		c.disposal = c.giff.Disposal[i]
+	}
+	if c.disposal == gif.DisposalPrevious {
+		if c.previous == nil || !r.In(c.previous.Rect) {
+			c.previous = image.NewRGBA(c.rgba.Rect)
 		}
+		draw.Draw(c.previous, r, c.rgba, r.Min, draw.Src)
+	}
+	draw.Draw(c.rgba, r, frame, r.Min, draw.Over)
+	c.next = i + 1
+}
+
+// copyRegion copies the pixels of src within r.
+func copyRegion(src *image.Paletted, r image.Rectangle) *image.Paletted {
+	return copyRegionInto(nil, src, r)
+}
+
+// copyRegionInto is like copyRegion, but copies into dst's pixels if there are
+// enough of them.
+func copyRegionInto(dst *image.Paletted, src *image.Paletted, r image.Rectangle) *image.Paletted {
+	r = r.Intersect(src.Bounds())
+	dst = reusePaletted(dst, r, src.Palette)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		copy(dst.Pix[dst.PixOffset(r.Min.X, y):dst.PixOffset(r.Max.X, y)], src.Pix[src.PixOffset(r.Min.X, y):src.PixOffset(r.Max.X, y)])
 	}
+	return dst
 }
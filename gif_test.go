@@ -0,0 +1,131 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("GIFPrinter", func() {
+	It("should dispose to the background color", func() {
+		palette := color.Palette{color.Transparent, color.Black}
+		giff := &gif.GIF{
+			Image: []*image.Paletted{
+				image.NewPaletted(image.Rect(0, 0, 2, 4), palette),
+				image.NewPaletted(image.Rect(0, 0, 1, 1), palette),
+			},
+			Delay:           []int{0, 0},
+			Disposal:        []byte{gif.DisposalBackground, gif.DisposalNone},
+			Config:          image.Config{Width: 2, Height: 4, ColorModel: color.Palette{color.White, color.Black}},
+			BackgroundIndex: 1,
+		}
+		var out bytes.Buffer
+		Expect(dotmatrix.NewGIFPrinter(&out, &dotmatrix.Config{LoopCount: 1}).Print(context.Background(), giff)).To(Succeed())
+		// The first frame is blank, and disposing of it fills the screen with black.
+		Expect(out.String()).To(ContainSubstring("⣿"))
+	})
+
+	It("should dispose to the config's background in place of the gif's", func() {
+		palette := color.Palette{color.Transparent, color.Black}
+		giff := &gif.GIF{
+			Image: []*image.Paletted{
+				image.NewPaletted(image.Rect(0, 0, 2, 4), palette),
+				image.NewPaletted(image.Rect(0, 0, 1, 1), palette),
+			},
+			Delay:           []int{0, 0},
+			Disposal:        []byte{gif.DisposalBackground, gif.DisposalNone},
+			Config:          image.Config{Width: 2, Height: 4, ColorModel: color.Palette{color.Black, color.White}},
+			BackgroundIndex: 1,
+		}
+		var out bytes.Buffer
+		Expect(dotmatrix.NewGIFPrinter(&out, &dotmatrix.Config{LoopCount: 1, Background: color.Black}).Print(context.Background(), giff)).To(Succeed())
+		// The gif's background is white, but the config's black is restored instead.
+		Expect(out.String()).To(ContainSubstring("⣿"))
+	})
+
+	It("should line partial frames up with the frames under them once scaled", func() {
+		palette := color.Palette{color.Transparent, color.Black, color.White}
+		fill := func(r image.Rectangle, index uint8) *image.Paletted {
+			img := image.NewPaletted(r, palette)
+			for i := range img.Pix {
+				img.Pix[i] = index
+			}
+			return img
+		}
+		giff := &gif.GIF{
+			// White over the right of a black screen, from an odd offset that doesn't
+			// survive halving.
+			Image:    []*image.Paletted{fill(image.Rect(0, 0, 8, 8), 1), fill(image.Rect(3, 0, 8, 8), 2)},
+			Delay:    []int{0, 0},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+			Config:   image.Config{Width: 8, Height: 8},
+		}
+		var out bytes.Buffer
+		Expect(dotmatrix.NewGIFPrinter(&out, &dotmatrix.Config{LoopCount: 1, Filter: halve{}, Drawer: draw.Src}).Print(context.Background(), giff)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("⣿⠀\n"))
+	})
+})
+
+type halve struct{}
+
+func (halve) Filter(img image.Image) image.Image {
+	return dotmatrix.NearestNeighbor.Scale(img, img.Bounds().Dx()/2, img.Bounds().Dy()/2)
+}
+
+var _ = Describe("ConvertGIF", func() {
+	It("should dither frames to dots and crop them to what changed", func() {
+		palette := color.Palette{color.White, color.Black}
+		giff := &gif.GIF{
+			Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 4, 4), palette), image.NewPaletted(image.Rect(1, 1, 2, 2), palette)},
+			Delay:    []int{10, 20},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+			Config:   image.Config{Width: 4, Height: 4},
+		}
+		giff.Image[1].SetColorIndex(1, 1, 1)
+
+		converted, err := dotmatrix.ConvertGIF(giff, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(converted.Image).To(HaveLen(2))
+		Expect(converted.Delay).To(Equal([]int{10, 20}))
+		Expect(converted.Image[0].Bounds()).To(Equal(image.Rect(0, 0, 4, 4)))
+		Expect(converted.Image[1].Bounds()).To(Equal(image.Rect(1, 1, 2, 2)))
+		Expect(converted.Image[1].At(1, 1)).To(Equal(color.Black))
+
+		var out bytes.Buffer
+		Expect(gif.EncodeAll(&out, converted)).To(Succeed())
+	})
+})
+
+var _ = Describe("WriteCast", func() {
+	It("should write a timed output event per frame", func() {
+		palette := color.Palette{color.White, color.Black}
+		giff := &gif.GIF{
+			Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 4, 8), palette), image.NewPaletted(image.Rect(0, 0, 4, 8), palette)},
+			Delay:    []int{50, 25},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+			Config:   image.Config{Width: 4, Height: 8},
+		}
+		for i := range giff.Image[1].Pix {
+			giff.Image[1].Pix[i] = 1
+		}
+		rec, err := dotmatrix.RecordGIF(giff, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var out bytes.Buffer
+		Expect(dotmatrix.WriteCast(&out, rec, nil)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(MatchJSON(`{"version": 2, "width": 2, "height": 3}`))
+		Expect(lines[1]).To(MatchJSON(`[0, "o", "⠀⠀\r\n⠀⠀\r\n"]`))
+		Expect(lines[2]).To(MatchJSON(`[0.5, "o", "\u001b[999D\u001b[2A⣿⣿\r\n⣿⣿\r\n"]`))
+	})
+})
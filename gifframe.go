@@ -0,0 +1,37 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+)
+
+// GIFFrame returns frame n of giff, counting from 0, as it appears when played:
+// drawn over the frames before it according to their disposal methods. It returns
+// nil if giff has no frame n.
+func GIFFrame(giff *gif.GIF, n int) image.Image {
+	if n < 0 || n >= len(giff.Image) {
+		return nil
+	}
+	return compositeGIF(giff, n)[n]
+}
+
+// GIFFrames returns every frame of giff as it appears when played.
+func GIFFrames(giff *gif.GIF) []image.Image {
+	return compositeGIF(giff, len(giff.Image)-1)
+}
+
+// compositeGIF composites the frames of giff up to and including frame last, on a
+// canvas the size of the gif's logical screen. Background disposal clears to
+// transparent, as browsers do.
+func compositeGIF(giff *gif.GIF, last int) []image.Image {
+	if len(giff.Image) == 0 {
+		return nil
+	}
+	canvas := newGIFCanvas(giff, color.Transparent)
+	var frames []image.Image
+	for i := 0; i <= last; i++ {
+		frames = append(frames, copyRGBA(canvas.composite(i)))
+	}
+	return frames
+}
@@ -0,0 +1,48 @@
+package dotmatrix_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("GIFFrame", func() {
+	palette := color.Palette{color.Transparent, color.Black}
+	dot := func(x, y int) *image.Paletted {
+		img := image.NewPaletted(image.Rect(x, y, x+1, y+1), palette)
+		img.SetColorIndex(x, y, 1)
+		return img
+	}
+	giff := &gif.GIF{
+		Image:    []*image.Paletted{dot(0, 0), dot(1, 0), dot(2, 0)},
+		Delay:    []int{0, 0, 0},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{Width: 3, Height: 1},
+	}
+	black := func(img image.Image, x int) bool {
+		_, _, _, a := img.At(x, 0).RGBA()
+		return a != 0
+	}
+
+	It("should draw frames over the frames before them", func() {
+		frame := dotmatrix.GIFFrame(giff, 1)
+		Expect(black(frame, 0)).To(BeTrue())
+		Expect(black(frame, 1)).To(BeTrue())
+	})
+
+	It("should respect disposal methods", func() {
+		frame := dotmatrix.GIFFrame(giff, 2)
+		Expect(black(frame, 0)).To(BeTrue())
+		Expect(black(frame, 1)).To(BeFalse())
+		Expect(black(frame, 2)).To(BeTrue())
+	})
+
+	It("should return nil for missing frames", func() {
+		Expect(dotmatrix.GIFFrame(giff, 3)).To(BeNil())
+	})
+})
@@ -0,0 +1,61 @@
+package dotmatrix
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+/*
+Highlight replaces the highlighted cells of the last printed image, reprinting only
+the cells whose highlighting changed rather than re-rendering the image. The cursor
+must be where Print left it, below the image or at the end of its last row with
+Config.OmitFinalNewline, which is also where Highlight leaves it. The printer's flusher must be a BrailleFlusher.
+*/
+func (p *Printer) Highlight(highlights ...Highlight) error {
+	flusher, ok := p.c.Flusher.(BrailleFlusher)
+	if !ok {
+		return errors.New("dotmatrix: highlighting requires a BrailleFlusher")
+	}
+	var changed image.Rectangle
+	for _, h := range flusher.Highlights {
+		changed = changed.Union(h.Cells)
+	}
+	for _, h := range highlights {
+		changed = changed.Union(h.Cells)
+	}
+	flusher.Highlights = highlights
+	p.c.Flusher = flusher
+
+	return p.reflush(image.Rect(changed.Min.X*2, changed.Min.Y*4, changed.Max.X*2, changed.Max.Y*4))
+}
+
+// reflush reprints the cells of the last printed image that cover region, moving
+// the cursor up to the image and back down again afterwards.
+func (p *Printer) reflush(region image.Rectangle) error {
+	if p.last == nil {
+		return errors.New("dotmatrix: nothing has been printed")
+	}
+	region = region.Intersect(p.last.Bounds())
+	if region.Empty() {
+		return nil
+	}
+	if p.open {
+		io.WriteString(p.w, saveCursor)
+		p.resetCursor()
+		_, err := flushRegion(p.w, p.last, region, p.c.Flusher)
+		io.WriteString(p.w, restoreCursor)
+		return err
+	}
+	rows := screenRows(p.last.Bounds(), p.c.Flusher) + p.trailing
+	p.resetCursor()
+	moved, err := flushRegion(p.w, p.last, region, p.c.Flusher)
+	if err != nil {
+		return err
+	}
+	if rows > moved {
+		_, err = fmt.Fprintf(p.w, "\033[%dB", rows-moved)
+	}
+	return err
+}
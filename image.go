@@ -1,6 +1,7 @@
 package dotmatrix
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
@@ -25,24 +26,187 @@ func (noop) Filter(img image.Image) image.Image {
 	return img
 }
 
+// filters applies each of its filters in turn.
+type filters []Filter
+
+func (fs filters) Filter(img image.Image) image.Image {
+	for _, f := range fs {
+		img = f.Filter(img)
+	}
+	return img
+}
+
 type Config struct {
 	Filter  Filter
 	Flusher Flusher
 	Drawer  draw.Drawer
+	// Quantizer, if set, decides which pixels of filtered images are printed as
+	// dots, in place of the Drawer and Palette.
+	Quantizer Quantizer
+	// Palette, if set, is the palette that the Drawer quantizes images to, in place
+	// of black, white and transparent. Pixels drawn black are printed as dots, and
+	// the rest as blanks, so extra dark grays, say, raise the threshold for a dot.
+	// It must include color.Black.
+	Palette color.Palette
 	// Reset is invoked between animated frames of an image. It can be used to
 	// apply custom cursor positioning.
 	Reset func(w io.Writer, rows int)
+	// OnFingerprint, if set, is called with the perceptual fingerprint of each
+	// rendered frame before it is flushed. Still images are frame 0.
+	OnFingerprint func(frame int, fp Fingerprint)
+	// Alpha decides how transparent pixels are rendered. The zero value leaves them
+	// transparent, which prints them as empty dots.
+	Alpha AlphaPolicy
+	// Background, if set, is composited under transparent pixels before the image
+	// is dithered, and is restored by gif background disposal. It is the matte for
+	// an AlphaMatte policy with no Matte of its own, and is ignored by an
+	// AlphaThreshold policy.
+	Background color.Color
+	// PartialFlush rewrites only the braille cells that animated frames change,
+	// using cursor addressing, instead of reprinting every frame in full. Gif
+	// frames that animate a small area benefit the most.
+	PartialFlush bool
+	// SkipIdentical skips flushing animated frames whose fingerprint matches the
+	// previously flushed frame, while still honoring their delay. This saves
+	// bandwidth and flicker on mostly static streams, such as security cameras.
+	SkipIdentical bool
+	// ByteBudget, if greater than zero, caps the number of bytes flushed for each
+	// frame. Frames over budget are degraded by the BudgetPolicy until they fit, or
+	// skipped if they cannot be made to fit.
+	ByteBudget int
+	// BudgetPolicy degrades frames that exceed the ByteBudget. The default is a
+	// ShrinkPolicy.
+	BudgetPolicy BudgetPolicy
+	// LoopCount overrides the number of times gifs play: n plays n times, and
+	// LoopForever loops forever. Zero defers to each gif's own loop count.
+	LoopCount int
+	// Stamp stamps the time and/or frame number into each printed frame, after the
+	// frame has been drawn in the dotmatrix palette.
+	Stamp Stamp
+	// AltText, if set, describes the source image in words for those who can't see
+	// the art, e.g. by running a captioning command (see AltTextCommand). Printer
+	// prints the description on a line after the art, and Alert adds it to the
+	// caption.
+	AltText func(img image.Image) (string, error)
+	// Step, if set, steps through animations one frame at a time instead of
+	// following their delays: each byte read from Step, such as a keypress or a
+	// newline, advances a frame. The index of each frame is printed on a line
+	// below it. Playback ends when Step runs out.
+	Step io.Reader
+	// Scaler resamples images that printers shrink to fit, as in Alert and
+	// ContactSheet, and is what a FitFilter in the Filter should scale with. The
+	// default is NearestNeighbor.
+	Scaler Scaler
+	// Prerender draws and encodes every frame of an animation before playing it, so
+	// that playback is only a matter of writing and sleeping. This keeps animations
+	// smooth on slow CPUs, at the cost of a delay before the first frame and the
+	// memory to hold every frame.
+	Prerender bool
+	// DecodeAhead is how many frames of an animation to filter and dither in the
+	// background while earlier ones play, spreading the dithering across CPUs. It
+	// keeps high frame rate animations smooth without Prerender's delay before the
+	// first frame. Filters still see every frame in order, one at a time.
+	DecodeAhead int
+	// Notifications decides how events during playback, such as an animation
+	// ending, are brought to the user's attention. Events without a notification
+	// are ignored.
+	Notifications map[Event]Notification
+	// Recorder, if set, records each frame printed, for replaying later. Players
+	// record each frame of the first play through, including frames skipped on
+	// screen for being late.
+	Recorder *Recorder
+	// SkipCorruptFrames skips mjpeg frames that can't be decoded, instead of
+	// stopping with an error, and resynchronizes on the start of the next frame.
+	// Webcams and flaky networks routinely send partial frames.
+	SkipCorruptFrames bool
+	// OnCorruptFrame, if set, is called with the reason each corrupt frame was
+	// skipped, from the goroutine reading the stream.
+	OnCorruptFrame func(error)
+	// DropPolicy decides which frames of live streams are dropped when they arrive
+	// faster than they can be printed. The default is DropOldest.
+	DropPolicy DropPolicy
+	// CameraTiming paces mjpeg frames as far apart as the X-Timestamp headers of
+	// multipart streams say the camera captured them, so that recorded streams
+	// replay at the camera's pace. Whatever comes between frames, such as
+	// multipart headers, is skipped.
+	CameraTiming bool
+	// Interpolate is how many blended frames to crossfade through between each pair
+	// of animation frames, smoothing low frame rate gifs. Each frame's delay is
+	// shared with the blends after it. Live streams, whose next frame isn't known
+	// in advance, instead blend each frame into the ones before it, moving
+	// 1/(Interpolate+1) of the way towards it, which smooths janky streams.
+	Interpolate int
+	// ShowStats prints a line below each frame of an animation or stream saying how
+	// well playback is keeping up: the frame rate achieved, frames skipped and
+	// dropped, and bytes written per second.
+	ShowStats bool
+	// OnStats, if set, is called from the playing goroutine with the stats of
+	// playback about once a second, for reporting them elsewhere.
+	OnStats func(Stats)
+	// SynchronizedOutput wraps each frame flushed in a synchronized update, so that
+	// terminals that support them show large frames all at once instead of tearing
+	// mid-refresh. Terminals that don't ignore them, but other displays, such as
+	// serial devices, may not.
+	SynchronizedOutput bool
+	// KeepCursor leaves the cursor alone during playback, for programs that manage
+	// it themselves. Otherwise players hide the cursor while they play, and show it
+	// again when they stop, even if they stop by panicking.
+	KeepCursor bool
+	// StatusLine prints a line below each frame of an animation or stream, updated
+	// in place, saying what's playing (see Name), the frame, the time into the
+	// animation and the playback speed.
+	StatusLine bool
+	// Name names what's playing in the status line, such as its file name.
+	Name string
+	// MouseReporting has the terminal report mouse buttons while players play, to
+	// be read from its input with ParseMouseEvent. See ImageLayout for mapping the
+	// cells clicked back to the image.
+	MouseReporting bool
+	// LineEnding ends each row printed: "\n", the default, or "\r\n" for raw serial
+	// lines and Windows pipes.
+	LineEnding string
+	// OmitFinalNewline leaves the cursor at the end of the last row of still images,
+	// rather than on the line below, so that TUIs embedding them can place the
+	// cursor precisely. Players ignore it, as they end each frame with a line end
+	// to reset the cursor over.
+	OmitFinalNewline bool
+	// Blank, if set, is printed for cells without any dots in place of the blank
+	// braille pattern, U+2800, which some fonts draw with dot outlines or at the
+	// wrong width. A space also shrinks the output. It applies to BrailleFlushers,
+	// including those in a BorderFlusher, that have no Blank of their own.
+	Blank rune
+	// TrimTrailingCells stops each row at its last cell with dots, rather than
+	// printing the full width of the image, which shrinks the output of mostly
+	// dark or letterboxed images. Players erase the rest of trimmed rows instead,
+	// so that earlier frames don't show through. Rows within a frame, such as
+	// BorderFlusher's, aren't trimmed.
+	TrimTrailingCells bool
+	// OnFrameStart, if set, is called with the index of each frame as printers start
+	// on it: before still images are drawn, and before each animation frame is
+	// flushed. Still images are frame 0, and frames skipped for being late aren't
+	// started.
+	OnFrameStart func(frame int)
+	// OnFlush, if set, is called after each frame is written, with the rows it
+	// takes up on screen and the bytes written for it. Frames that aren't written,
+	// such as those skipped for being identical, aren't flushed.
+	OnFlush func(rows, bytes int)
+	// OnError, if set, is called with the error that printing or playback stops
+	// with, before it is returned. Stopping because the context was cancelled or
+	// the player closed isn't an error.
+	OnError func(err error)
 }
 
 var defaultConfig = Config{
-	Filter:  noop{},
-	Flusher: BrailleFlusher{},
-	Drawer:  draw.FloydSteinberg,
+	Filter:       noop{},
+	Flusher:      BrailleFlusher{},
+	Drawer:       draw.FloydSteinberg,
+	BudgetPolicy: ShrinkPolicy{},
+	Scaler:       NearestNeighbor,
 }
 
 func mergeConfig(c *Config) Config {
 	if c == nil {
-		return defaultConfig
+		c = &Config{}
 	}
 	if c.Filter == nil {
 		c.Filter = defaultConfig.Filter
@@ -53,6 +217,15 @@ func mergeConfig(c *Config) Config {
 	if c.Flusher == nil {
 		c.Flusher = defaultConfig.Flusher
 	}
+	if c.Blank != 0 {
+		c.Flusher = withBlank(c.Flusher, c.Blank)
+	}
+	if c.BudgetPolicy == nil {
+		c.BudgetPolicy = defaultConfig.BudgetPolicy
+	}
+	if c.Scaler == nil {
+		c.Scaler = defaultConfig.Scaler
+	}
 	if c.Reset == nil {
 		c.Reset = func(w io.Writer, rows int) {
 			fmt.Fprintf(w, "\033[999D\033[%dA", rows)
@@ -61,22 +234,66 @@ func mergeConfig(c *Config) Config {
 	return *c
 }
 
+// withBlank returns flusher printing blank for empty cells, if it prints braille
+// and has no blank of its own.
+func withBlank(flusher Flusher, blank rune) Flusher {
+	switch f := flusher.(type) {
+	case BrailleFlusher:
+		if f.Blank == 0 {
+			f.Blank = blank
+		}
+		return f
+	case BorderFlusher:
+		f.Flusher = withBlank(f.inner(), blank)
+		return f
+	}
+	return flusher
+}
+
+// reportError reports *err to OnError, unless printing was only cancelled. It is
+// deferred by printers with named results.
+func (c *Config) reportError(err *error) {
+	if *err == nil || c.OnError == nil {
+		return
+	}
+	switch *err {
+	case context.Canceled, context.DeadlineExceeded, ErrPlayerClosed:
+		return
+	}
+	c.OnError(*err)
+}
+
+// LoopForever is a Config.LoopCount that loops gifs forever.
+const LoopForever = -1
+
 var defaultPalette = []color.Color{color.Black, color.White, color.Transparent}
 
+// Printer prints still images. It remembers what it last printed, for Update and
+// Highlight, so it must only be used by one goroutine at a time. See PrinterPool
+// for printing from many.
 type Printer struct {
 	w io.Writer
 	c Config
+
+	last     *image.Paletted // the last image printed
+	trailing int             // lines printed below the last image
+	open     bool            // whether the last line printed was left open
 }
 
+// Print prints img to w with the default config. It may be called from any
+// goroutine.
 func Print(w io.Writer, img image.Image) error {
-	return NewPrinter(w, &defaultConfig).Print(img)
+	return NewPrinter(w, nil).Print(img)
 }
 
 // NewPrinter provides an Printer. If drawer is nil, draw.FloydSteinberg is used.
+// Neither w nor c is checked until Print, which reports any problem with them
+// before printing anything. Use NewPrinterWith to check them up front.
 func NewPrinter(w io.Writer, c *Config) *Printer {
+	config := mergeConfig(c)
 	return &Printer{
-		w: w,
-		c: mergeConfig(c),
+		w: lineWriter(w, config.LineEnding),
+		c: config,
 	}
 }
 
@@ -121,14 +338,75 @@ As an example, this output was printed from a 134px by 108px image of Saturn:
 	⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿⠿
 */
 func (p *Printer) Print(img image.Image) error {
-	img = redraw(img, p.c.Filter, p.c.Drawer)
-	return flush(p.w, img, p.c.Flusher)
+	return p.PrintContext(context.Background(), img)
+}
+
+// PrintContext is like Print, but gives up once ctx is done, checking it between
+// the rows it writes, so that printing to a slow or blocked writer, such as a
+// network connection or a pager, can be abandoned. It returns ctx's error if so.
+func (p *Printer) PrintContext(ctx context.Context, img image.Image) (err error) {
+	defer p.c.reportError(&err)
+	if err := checkWriter(p.w); err != nil {
+		return err
+	}
+	if err := p.c.Validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var w io.Writer = contextWriter{ctx, p.w}
+	if p.c.OmitFinalNewline {
+		final := &finalNewlineWriter{w: w}
+		defer func() { p.open = len(final.pending) > 0 }()
+		w = final
+	}
+	if p.c.OnFrameStart != nil {
+		p.c.OnFrameStart(0)
+	}
+	p.last = redraw(img, &p.c)
+	if err := newPresenter(w, &p.c).present(0, p.last); err != nil {
+		return err
+	}
+	if p.c.Recorder != nil {
+		if err := p.c.Recorder.record(p.last, 0, &p.c); err != nil {
+			return err
+		}
+	}
+	return p.writeAltText(w, img)
+}
+
+// resetCursor moves the cursor back to the top left of the last image printed.
+func (p *Printer) resetCursor() {
+	rows := screenRows(p.last.Bounds(), p.c.Flusher) + p.trailing
+	if p.open {
+		rows--
+	}
+	if rows == 0 {
+		io.WriteString(p.w, "\r")
+		return
+	}
+	p.c.Reset(p.w, rows)
 }
 
-func redraw(img image.Image, filter Filter, drawer draw.Drawer) *image.Paletted {
+func redraw(img image.Image, c *Config) *image.Paletted {
+	return redrawInto(nil, img, c)
+}
+
+// redrawInto is like redraw, but draws into dst's pixels if there are enough of
+// them, rather than allocating new ones.
+func redrawInto(dst *image.Paletted, img image.Image, c *Config) *image.Paletted {
+	img, offset := filterFrame(img, c)
+	return ditherInto(dst, img, offset, c)
+}
+
+// filterFrame applies the config's filter and alpha policy to img, returning the
+// result and where its min point belongs once redrawn.
+func filterFrame(img image.Image, c *Config) (image.Image, image.Point) {
 	origBounds := img.Bounds()
 
-	img = filter.Filter(img)
+	img = c.Filter.Filter(img)
+	img = c.alpha().apply(img)
 
 	newBounds := img.Bounds()
 
@@ -138,14 +416,55 @@ func redraw(img image.Image, filter Filter, drawer draw.Drawer) *image.Paletted
 	// The offset is important because not all images have bounds starting at (0, 0), and
 	// the filter may accidentally zero the min bounding point.
 	offset := image.Pt(int(float64(origBounds.Min.X)*scaleX), int(float64(origBounds.Min.Y)*scaleY))
+	return img, offset
+}
 
-	// Create a new paletted image using a monochrome+transparent color palette.
-	paletted := image.NewPaletted(img.Bounds(), defaultPalette)
-	paletted.Rect = paletted.Bounds().Add(offset)
-	drawer.Draw(paletted, paletted.Bounds(), img, img.Bounds().Min)
+// ditherInto quantizes a filtered image into a monochrome+transparent paletted
+// image with its min point at offset, reusing dst's pixels if there are enough of
+// them. Unlike filters, quantizers keep no state, so it is safe to call
+// concurrently.
+func ditherInto(dst *image.Paletted, img image.Image, offset image.Point, c *Config) *image.Paletted {
+	// Filters that leave the image untouched keep its min point, so always start
+	// from the origin before offsetting.
+	bounds := img.Bounds().Sub(img.Bounds().Min).Add(offset)
+	paletted := reusePaletted(dst, bounds, defaultPalette)
+	c.quantizer().Quantize(paletted, img)
 	return paletted
 }
 
+// toDefaultPalette converts img, drawn in a custom palette, to the dotmatrix
+// palette in place: black stays black, transparent colors become transparent, and
+// all other colors white.
+func toDefaultPalette(img *image.Paletted) *image.Paletted {
+	var index [256]uint8
+	for i, c := range img.Palette {
+		switch _, _, _, a := c.RGBA(); {
+		case c == color.Black:
+			index[i] = 0
+		case a == 0:
+			index[i] = 2
+		default:
+			index[i] = 1
+		}
+	}
+	for i, p := range img.Pix {
+		img.Pix[i] = index[p]
+	}
+	img.Palette = defaultPalette
+	return img
+}
+
+// reusePaletted returns a paletted image with the given bounds and palette, reusing
+// dst's pixels if there are enough of them. Reused pixels are not cleared.
+func reusePaletted(dst *image.Paletted, r image.Rectangle, palette color.Palette) *image.Paletted {
+	n := r.Dx() * r.Dy()
+	if dst == nil || cap(dst.Pix) < n {
+		return image.NewPaletted(r, palette)
+	}
+	dst.Pix, dst.Stride, dst.Rect, dst.Palette = dst.Pix[:n], r.Dx(), r, palette
+	return dst
+}
+
 func flush(w io.Writer, img image.Image, flusher Flusher) error {
 	return flusher.Flush(w, img)
 
@@ -0,0 +1,115 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/draw"
+	"time"
+)
+
+// interpolatedAnimation crossfades between the frames of an animation, inserting
+// n blended frames between each pair, including from the last frame back to the
+// first, and sharing the delay of each frame between it and the blends after it.
+type interpolatedAnimation struct {
+	anim animation
+	n    int
+
+	// The canvases of the two frames last composited are kept, so that frames are
+	// composited in order, once each. The first frame is kept too, to blend into
+	// from the last.
+	first      *image.RGBA
+	from, to   *image.RGBA
+	fromI, toI int
+	blended    *image.RGBA
+}
+
+func newInterpolatedAnimation(anim animation, n int) *interpolatedAnimation {
+	return &interpolatedAnimation{anim: anim, n: n, fromI: -1, toI: -1}
+}
+
+func (a *interpolatedAnimation) frames() int {
+	return a.anim.frames() * (a.n + 1)
+}
+
+func (a *interpolatedAnimation) delay(i int) time.Duration {
+	return a.anim.delay(i/(a.n+1)) / time.Duration(a.n+1)
+}
+
+func (a *interpolatedAnimation) loops() int {
+	return a.anim.loops()
+}
+
+func (a *interpolatedAnimation) composite(i int) *image.RGBA {
+	k, j := i/(a.n+1), i%(a.n+1)
+	from := a.canvas(k)
+	if j == 0 {
+		return from
+	}
+	to := a.canvas((k + 1) % a.anim.frames())
+	if a.blended == nil || a.blended.Rect != from.Rect {
+		a.blended = image.NewRGBA(from.Rect)
+	}
+	crossfade(a.blended.Pix, from.Pix, to.Pix, j, a.n+1)
+	return a.blended
+}
+
+// canvas returns a copy of the canvas with frame k composited on it.
+func (a *interpolatedAnimation) canvas(k int) *image.RGBA {
+	switch {
+	case k == 0 && a.first != nil:
+		return a.first
+	case k == a.fromI:
+		return a.from
+	case k == a.toI:
+		return a.to
+	}
+	composited := a.anim.composite(k)
+	if k == 0 {
+		a.first = copyRGBA(composited)
+		return a.first
+	}
+	// The older of the two canvases is drawn over.
+	img := a.from
+	if img == nil || img.Rect != composited.Rect {
+		img = image.NewRGBA(composited.Rect)
+	}
+	copy(img.Pix, composited.Pix)
+	a.from, a.fromI = a.to, a.toI
+	a.to, a.toI = img, k
+	return img
+}
+
+// crossfade sets dst to the blend of from and to that is j steps of n towards to.
+func crossfade(dst, from, to []uint8, j, n int) {
+	for i := range dst {
+		dst[i] = uint8((int(from[i])*(n-j) + int(to[i])*j + n/2) / n)
+	}
+}
+
+// streamBlender blends each frame of a live stream into the frames before it,
+// which smooths streams whose frames can't be known in advance to interpolate
+// between. Each frame moves the blend 1/(n+1) of the way towards itself.
+type streamBlender struct {
+	n        int
+	blend    *image.RGBA
+	frame    *image.RGBA
+	blending bool
+}
+
+func (b *streamBlender) next(img image.Image) image.Image {
+	if b.n <= 0 {
+		return img
+	}
+	bounds := img.Bounds()
+	if b.blend == nil || b.blend.Rect != bounds {
+		b.blend, b.frame = image.NewRGBA(bounds), image.NewRGBA(bounds)
+		b.blending = false
+	}
+	if !b.blending {
+		draw.Draw(b.blend, bounds, img, bounds.Min, draw.Src)
+		b.blending = true
+		return b.blend
+	}
+	draw.Draw(b.frame, bounds, img, bounds.Min, draw.Src)
+	crossfade(b.blend.Pix, b.blend.Pix, b.frame.Pix, 1, b.n+1)
+	return b.blend
+}
@@ -0,0 +1,103 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// LevelsFilter keeps animations legible as scenes fade to dark or bright. For
+// each frame it estimates a black point and a white point from the luminance of
+// the opaque pixels, and stretches the colors between them over the full range.
+// The points are smoothed from frame to frame, so that the threshold between dots
+// and blank doesn't pump as the contents of a scene move around.
+//
+// LevelsFilter keeps the points it estimated for the previous frame, so use a
+// pointer and don't share it between animations.
+type LevelsFilter struct {
+	// Clip is the fraction of pixels, from 0 to 0.5, allowed to fall below the black
+	// point and above the white point, so that a few specks of glare or shadow don't
+	// set the levels. The zero value is treated as 0.01.
+	Clip float64
+	// Smoothing, from 0 to 1, is how much of the previous frame's levels carry over
+	// into each frame's. 0 sets the levels from each frame alone, and values closer
+	// to 1 follow changes in the scene more slowly. The zero value is treated as 0.8.
+	Smoothing float64
+	// MinRange is the least distance, out of 255, kept between the black and white
+	// points, so that flat frames aren't stretched into noise. The zero value is
+	// treated as 32.
+	MinRange float64
+
+	black, white float64
+	primed       bool
+}
+
+func (f *LevelsFilter) Filter(img image.Image) image.Image {
+	clip := f.Clip
+	if clip <= 0 || clip >= 0.5 {
+		clip = 0.01
+	}
+	smoothing := f.Smoothing
+	if smoothing <= 0 || smoothing >= 1 {
+		smoothing = 0.8
+	}
+	minRange := f.MinRange
+	if minRange <= 0 || minRange > 0xff {
+		minRange = 32
+	}
+
+	bounds := img.Bounds()
+	var histogram [256]int
+	var n int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			histogram[luminance(color.NRGBA{c.R, c.G, c.B, 0xff})]++
+			n++
+		}
+	}
+	if n == 0 {
+		return img
+	}
+
+	cut := int(float64(n) * clip)
+	low, high := 0, 255
+	for m := 0; low < 255 && m+histogram[low] <= cut; low++ {
+		m += histogram[low]
+	}
+	for m := 0; high > 0 && m+histogram[high] <= cut; high-- {
+		m += histogram[high]
+	}
+	black, white := float64(low), float64(high)
+	if f.primed {
+		black = smoothing*f.black + (1-smoothing)*black
+		white = smoothing*f.white + (1-smoothing)*white
+	}
+	f.black, f.white, f.primed = black, white, true
+
+	// Widen narrow ranges about their middle, within bounds.
+	if white-black < minRange {
+		mid := math.Max(minRange/2, math.Min(0xff-minRange/2, (black+white)/2))
+		black, white = mid-minRange/2, mid+minRange/2
+	}
+	if black <= 0 && white >= 0xff {
+		return img
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(math.Max(0, math.Min(0xff, math.Round((float64(i)-black)*0xff/(white-black)))))
+	}
+	dst := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			c.R, c.G, c.B = lut[c.R], lut[c.G], lut[c.B]
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,78 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"io"
+)
+
+// Printers that leave the last line open save and restore the cursor around
+// reprinting, as they can't tell which column to return it to.
+const (
+	saveCursor    = "\0337"
+	restoreCursor = "\0338"
+)
+
+// lineWriter returns w, translated to end lines with ending if it isn't the
+// default "\n".
+func lineWriter(w io.Writer, ending string) io.Writer {
+	if ending != "\r\n" || w == nil {
+		return w
+	}
+	return &crlfWriter{w: w}
+}
+
+// crlfWriter ends lines written to w with CRLF instead of LF, for raw serial lines
+// and Windows pipes. Lines already ended with CRLF, such as BBSFlusher's, are left
+// alone.
+type crlfWriter struct {
+	w   io.Writer
+	cr  bool // whether the last byte written was a CR
+	buf []byte
+}
+
+func (w *crlfWriter) Write(b []byte) (int, error) {
+	w.buf = w.buf[:0]
+	for _, c := range b {
+		if c == '\n' && !w.cr {
+			w.buf = append(w.buf, '\r')
+		}
+		w.buf = append(w.buf, c)
+		w.cr = c == '\r'
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// finalNewlineWriter holds back the line end at the end of each write until more
+// is written, so that the last line written can be left open.
+type finalNewlineWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (w *finalNewlineWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if len(w.pending) > 0 {
+		if _, err := w.w.Write(w.pending); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[:0]
+	}
+	n := len(b)
+	if bytes.HasSuffix(b, []byte("\n")) {
+		end := 1
+		if bytes.HasSuffix(b, []byte("\r\n")) {
+			end = 2
+		}
+		w.pending = append(w.pending, b[n-end:]...)
+		b = b[:n-end]
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,35 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Line endings", func() {
+	img := image.NewGray(image.Rect(0, 0, 4, 8))
+
+	It("should end rows with CRLF when asked to", func() {
+		var lf, crlf bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&lf, nil).Print(img)).To(Succeed())
+		Expect(dotmatrix.NewPrinter(&crlf, &dotmatrix.Config{LineEnding: "\r\n"}).Print(img)).To(Succeed())
+		Expect(crlf.String()).To(Equal(strings.Replace(lf.String(), "\n", "\r\n", -1)))
+	})
+
+	It("should leave the last row open when asked to", func() {
+		var out bytes.Buffer
+		printer := dotmatrix.NewPrinter(&out, &dotmatrix.Config{OmitFinalNewline: true})
+		Expect(printer.Print(img)).To(Succeed())
+		Expect(strings.Count(out.String(), "\n")).To(Equal(1))
+		Expect(out.String()).NotTo(HaveSuffix("\n"))
+	})
+
+	It("should refuse other line endings", func() {
+		Expect((&dotmatrix.Config{LineEnding: "\r"}).Validate()).NotTo(Succeed())
+	})
+})
@@ -3,17 +3,25 @@ package dotmatrix
 import (
 	"bytes"
 	"context"
+	"errors"
 	"image"
 	"image/jpeg"
 	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type MJPEGPrinter struct {
-	w io.Writer
-	c Config
+	w     io.Writer
+	c     Config
+	stats statsMeter
 }
 
+// NewMJPEGPrinter provides an MJPEGPrinter. Neither w nor c is checked until
+// Print, which reports any problem with them before reading or printing anything.
+// Use NewConfig to check c up front.
 func NewMJPEGPrinter(w io.Writer, c *Config) *MJPEGPrinter {
 	return &MJPEGPrinter{
 		w: w,
@@ -22,35 +30,79 @@ func NewMJPEGPrinter(w io.Writer, c *Config) *MJPEGPrinter {
 }
 
 /*
-	Print animates an mpeg stream. If fps is less than zero, it will print each
-	frame as quickly as it can. Otherwise, fps dictacts how many frames per second
-	are printed.
+	Print animates an mpeg stream. If fps is less than or equal to zero, it will
+	print each frame as quickly as it arrives. Otherwise, fps caps how many frames
+	per second are printed, pacing streams that are read faster, such as files.
+	With Config.CameraTiming, frames are also paced by the timestamps the camera
+	sent with them. Print returns as soon as it is cancelled, and stops reading r
+	when it does. A read in progress can't be interrupted unless r is an
+	io.Closer, so if it is, a stream still being read is closed when Print
+	returns.
 */
 func (p *MJPEGPrinter) Print(ctx context.Context, r io.Reader, fps int) error {
-	reader := mjpegStreamer{
-		r:   r,
-		fps: fps,
-	}
+	player := newPlayer(p.w, &p.c, &mjpegTrack{p: p, r: r, fps: fps})
+	player.stats = &p.stats
+	return player.Play(ctx)
+}
 
-	for frame := range reader.ReadAll(ctx) {
-		if frame.err != nil {
-			return frame.err
-		}
+// Stats returns how well the current or last Print kept up. It may be called from
+// any goroutine.
+func (p *MJPEGPrinter) Stats() Stats {
+	return p.stats.snapshot()
+}
 
-		frame.img = redraw(frame.img, p.c.Filter, p.c.Drawer)
+// NewMJPEGPlayer provides a Player for the mjpeg stream r, which plays it as an
+// MJPEGPrinter would.
+func NewMJPEGPlayer(w io.Writer, c *Config, r io.Reader, fps int) *Player {
+	p := NewMJPEGPrinter(w, c)
+	return newPlayer(p.w, &p.c, &mjpegTrack{p: p, r: r, fps: fps})
+}
 
-		// Draw the image and reset the cursor
-		if err := flush(p.w, frame.img, p.c.Flusher); err != nil {
-			return err
-		}
-		rows := frame.img.Bounds().Dy() / 4
-		if frame.img.Bounds().Dy()%4 != 0 {
-			rows++
-		}
+// mjpegTrack plays frames as they arrive from a stream, which paces them itself.
+type mjpegTrack struct {
+	p      *MJPEGPrinter
+	r      io.Reader
+	fps    int
+	stream *mjpegStreamer
+	blend  streamBlender
+}
+
+func (t *mjpegTrack) loops() int {
+	return 1
+}
 
-		p.c.Reset(p.w, rows)
+func (t *mjpegTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	if t.stream == nil {
+		t.stream = startMJPEGStreamer(ctx, t.r, t.fps, &t.p.c)
+	}
+	var frame frame
+	var ok bool
+	select {
+	case frame, ok = <-t.stream.frames:
+	case <-ctx.Done():
+		// The stream may be stuck in a read that never returns.
+		return nil, image.Rectangle{}, 0, ctx.Err()
+	}
+	if !ok {
+		// The stream also ends early when playback is cancelled.
+		if err := ctx.Err(); err != nil {
+			return nil, image.Rectangle{}, 0, err
+		}
+		return nil, image.Rectangle{}, 0, io.EOF
+	}
+	if frame.err != nil {
+		return nil, image.Rectangle{}, 0, frame.err
 	}
+	t.blend.n = t.p.c.Interpolate
+	img := redraw(t.blend.next(frame.img), &t.p.c)
+	return img, img.Bounds(), 0, nil
+}
 
+// Close stops reading the stream.
+func (t *mjpegTrack) Close() error {
+	if t.stream != nil {
+		t.stream.Close()
+	}
 	return nil
 }
 
@@ -59,56 +111,252 @@ type frame struct {
 	err error
 }
 
+// dropped returns how many frames the stream has dropped.
+func (t *mjpegTrack) dropped() int {
+	if t.stream == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&t.stream.dropped))
+}
+
+// DropPolicy decides which frames of a live stream are dropped when they arrive
+// faster than they can be printed. One decoded frame waits to be printed while
+// the last is printing, and the policy decides what happens to the next.
+type DropPolicy int
+
+const (
+	// DropOldest replaces the waiting frame with the next, so that the latest frame
+	// is always printed next and live streams stay live. It is the default.
+	DropOldest DropPolicy = iota
+	// DropNewest drops the next frame, keeping the waiting frame.
+	DropNewest
+	// DropNone drops nothing, and stops reading the stream until the waiting frame
+	// is printed, so that every frame is shown however far behind it falls.
+	DropNone
+)
+
+// mjpegStreamer decodes the frames of an mjpeg stream on its own goroutine, until
+// the stream ends or the streamer is closed. Frames are sent at most fps times a
+// second, and frames that arrive while the last is still being printed are
+// dropped according to the config's DropPolicy.
 type mjpegStreamer struct {
-	r   io.Reader
-	fps int
+	dropped int64 // accessed atomically, so first for alignment
+	frames  <-chan frame
+	cancel  context.CancelFunc
+	r       io.Reader
+	done    chan struct{} // closed once the goroutine stops reading
 }
 
-func (mjpeg *mjpegStreamer) ReadAll(ctx context.Context) <-chan frame {
-	frames := make(chan frame)
+func startMJPEGStreamer(ctx context.Context, r io.Reader, fps int, c *Config) *mjpegStreamer {
+	ctx, cancel := context.WithCancel(ctx)
+	frames := make(chan frame, 1)
+	s := &mjpegStreamer{frames: frames, cancel: cancel, r: r, done: make(chan struct{})}
 	go func() {
-		defer close(frames)
-
-		var buf bytes.Buffer
-		p := make([]byte, 1)
-		delay := time.After(time.Second / time.Duration(mjpeg.fps))
-		for {
-			n, err := mjpeg.r.Read(p)
-			if n == 0 {
-				if err == nil {
-					continue
-				}
-				if err != io.EOF {
-					frames <- frame{err: err}
-				}
-				return
+		defer close(s.done)
+		s.stream(ctx, r, fps, c, frames)
+	}()
+	return s
+}
+
+// Close stops the streamer's goroutine. A goroutine blocked reading the stream
+// exits once the read returns, so if it is still reading and the stream is an
+// io.Closer, such as a network connection or an HTTP body, the stream is closed to
+// interrupt the read. Close doesn't wait for the goroutine.
+func (s *mjpegStreamer) Close() error {
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	if closer, ok := s.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// errFrameCutShort is the reason a frame is skipped when the next frame starts
+// before it ends.
+var errFrameCutShort = errors.New("dotmatrix: mjpeg frame cut short by the next")
+
+// soi is the marker every jpeg starts with.
+var soi = []byte{0xff, 0xd8}
+
+// maxHeader is the most that is kept of what comes between frames.
+const maxHeader = 4096
+
+// frameTimestamp parses the X-Timestamp header that cameras such as mjpg-streamer
+// send with each frame of a multipart stream, in seconds.
+func frameTimestamp(header []byte) (time.Duration, bool) {
+	for _, line := range strings.Split(string(header), "\n") {
+		i := strings.Index(line, ":")
+		if i < 0 || !strings.EqualFold(strings.TrimSpace(line[:i]), "X-Timestamp") {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// sleepUntil sleeps until t, and reports false if ctx is done first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *mjpegStreamer) stream(ctx context.Context, r io.Reader, fps int, c *Config, frames chan frame) {
+	defer close(frames)
+
+	skip := func(err error) {
+		if c.OnCorruptFrame != nil {
+			c.OnCorruptFrame(err)
+		}
+	}
+	send := func(f frame) {
+		select {
+		case <-ctx.Done():
+		case frames <- f:
+		}
+	}
+	var interval time.Duration
+	if fps > 0 {
+		interval = time.Second / time.Duration(fps)
+	}
+
+	var buf bytes.Buffer
+	p := make([]byte, 1)
+	// next is the soonest the next frame may be queued, at fps. header is what came
+	// before the frame being read, and last is the timestamp of the last frame
+	// queued at lastAt, if stamped.
+	next := time.Now().Add(interval)
+	var (
+		header  []byte
+		last    time.Duration
+		lastAt  time.Time
+		stamped bool
+	)
+	for ctx.Err() == nil {
+		n, err := r.Read(p)
+		if n == 0 {
+			if err == nil {
+				continue
 			}
+			if err != io.EOF {
+				send(frame{err: err})
+			}
+			return
+		}
 
-			if _, err := buf.Write(p); err != nil {
-				frames <- frame{err: err}
-				return
+		if _, err := buf.Write(p); err != nil {
+			send(frame{err: err})
+			return
+		}
+
+		// Whatever comes between frames, such as multipart headers, is set aside
+		// when it matters, and leniently a frame is abandoned as soon as another
+		// starts.
+		if data := buf.Bytes(); (c.SkipCorruptFrames || c.CameraTiming) && len(data) > 1 {
+			n := len(data)
+			framing := bytes.HasPrefix(data, soi)
+			starting := data[n-2] == 0xff && data[n-1] == 0xd8
+			switch {
+			case !framing && starting:
+				header = append(header[:0], data[:n-2]...)
+				buf.Reset()
+				buf.Write(soi)
+				continue
+			case framing && starting && n > 2 && c.SkipCorruptFrames:
+				skip(errFrameCutShort)
+				header = header[:0]
+				buf.Reset()
+				buf.Write(soi)
+				continue
+			case !framing && n > maxHeader:
+				// Too long for headers, so only the last byte is kept, to spot the
+				// start of the next frame.
+				last := data[n-1]
+				buf.Reset()
+				buf.WriteByte(last)
+				continue
+			case !framing:
+				continue
 			}
+		}
 
-			if buf.Len() > 1 {
-				data := buf.Bytes()
-				if data[buf.Len()-2] == 0xff && data[buf.Len()-1] == 0xd9 {
-					img, err := jpeg.Decode(&buf)
-					if err != nil {
-						frames <- frame{err: err}
-						return
-					}
-					select {
-					case <-ctx.Done():
+		if buf.Len() > 1 {
+			data := buf.Bytes()
+			if data[buf.Len()-2] == 0xff && data[buf.Len()-1] == 0xd9 {
+				img, err := jpeg.Decode(&buf)
+				if err != nil && c.SkipCorruptFrames {
+					skip(err)
+					buf.Reset()
+					continue
+				}
+				if err != nil {
+					send(frame{err: err})
+					return
+				}
+				buf.Reset()
+				if stamp, ok := frameTimestamp(header); c.CameraTiming && ok {
+					// Frames are shown as far apart as the camera stamped them,
+					// unless the camera's clock went backwards.
+					if stamped && stamp > last && !sleepUntil(ctx, lastAt.Add(stamp-last)) {
 						return
-					case frames <- frame{img: img, err: err}:
-						<-delay
-					default:
-						buf.Truncate(0)
 					}
-					delay = time.After(time.Second / time.Duration(mjpeg.fps))
+					last, stamped = stamp, true
+				}
+				header = header[:0]
+				lastAt = time.Now()
+				if s.queue(ctx, frames, frame{img: img}, c.DropPolicy) && !sleepUntil(ctx, next) {
+					return
 				}
+				next = time.Now().Add(interval)
 			}
 		}
-	}()
-	return frames
+	}
+}
+
+// queue queues f to be printed, dropping a frame if one is already waiting as
+// policy decides. It reports whether f was queued.
+func (s *mjpegStreamer) queue(ctx context.Context, frames chan frame, f frame, policy DropPolicy) bool {
+	if policy == DropNone {
+		select {
+		case <-ctx.Done():
+			return false
+		case frames <- f:
+			return true
+		}
+	}
+	select {
+	case frames <- f:
+		return true
+	default:
+	}
+	if policy == DropNewest {
+		atomic.AddInt64(&s.dropped, 1)
+		return false
+	}
+	// Only this goroutine sends, so once the waiting frame is taken, by the player
+	// or dropped here, there is room for f.
+	select {
+	case <-frames:
+		atomic.AddInt64(&s.dropped, 1)
+	default:
+	}
+	frames <- f
+	return true
 }
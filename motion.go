@@ -0,0 +1,92 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+)
+
+// MotionFilter highlights what changed since the previous frame, turning an
+// MJPEGPrinter into a simple motion detection viewer. Unchanged areas are faded
+// out, or blanked entirely if Exclusive is set. The first frame, and any frame
+// whose size differs from the last, is passed through unchanged.
+//
+// MotionFilter keeps the previous frame, so use a pointer and don't share it
+// between streams. It is cheapest and least sensitive to noise when applied after
+// any downscaling.
+type MotionFilter struct {
+	// Sensitivity, from 0 to 1, decides how much a pixel must change to count as
+	// motion: a pixel whose luminance changes by more than 1-Sensitivity of the full
+	// range has moved. The zero value is treated as 0.9.
+	Sensitivity float64
+	// Exclusive renders only changed areas, leaving the rest of the frame blank.
+	Exclusive bool
+	// OnMotion, if set, is called with the fraction of the frame that moved, for
+	// each frame in which anything moved. It can notify a Player of MotionDetected
+	// events.
+	OnMotion func(area float64)
+
+	prev   []float64
+	bounds image.Rectangle
+}
+
+func (f *MotionFilter) Filter(img image.Image) image.Image {
+	sensitivity := f.Sensitivity
+	if sensitivity <= 0 || sensitivity > 1 {
+		sensitivity = 0.9
+	}
+	threshold := (1 - sensitivity) * 0xff
+
+	bounds := img.Bounds()
+	lum := luminanceMap(img)
+	prev, prevBounds := f.prev, f.bounds
+	f.prev, f.bounds = lum, bounds
+	if prev == nil || bounds.Size() != prevBounds.Size() {
+		return img
+	}
+
+	// Grow moving pixels into regions by a pixel each way, so that motion reads as
+	// shapes rather than speckles.
+	w, h := bounds.Dx(), bounds.Dy()
+	moved := make([]bool, len(lum))
+	var area int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if d := lum[i] - prev[i]; d <= threshold && -d <= threshold {
+				continue
+			}
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					if nx >= 0 && ny >= 0 && nx < w && ny < h {
+						moved[ny*w+nx] = true
+					}
+				}
+			}
+		}
+	}
+
+	dst := image.NewNRGBA(bounds)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			switch {
+			case moved[y*w+x]:
+				area++
+			case f.Exclusive:
+				c = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+			default:
+				// Fade three quarters of the way to white.
+				c.R, c.G, c.B = fade(c.R), fade(c.G), fade(c.B)
+			}
+			dst.SetNRGBA(bounds.Min.X+x, bounds.Min.Y+y, c)
+		}
+	}
+	if area > 0 && f.OnMotion != nil {
+		f.OnMotion(float64(area) / float64(len(moved)))
+	}
+	return dst
+}
+
+func fade(v uint8) uint8 {
+	return 0xff - (0xff-v)/4
+}
@@ -0,0 +1,87 @@
+package dotmatrix
+
+import (
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Players have the terminal report mouse buttons with SGR coordinates, which,
+// unlike the original encoding, aren't limited to 223 columns.
+const (
+	enableMouse  = "\033[?1000h\033[?1006h"
+	disableMouse = "\033[?1006l\033[?1000l"
+)
+
+// MouseEvent is a mouse button pressed or released over the terminal.
+type MouseEvent struct {
+	// Button is 0, 1 or 2 for the left, middle and right buttons, or 64 and 65 for
+	// the wheel rolled up and down. Holding shift adds 4, meta 8 and control 16.
+	Button int
+	// Col and Row are the cell under the mouse, counting from 0 at the top-left of
+	// the terminal.
+	Col, Row int
+	Released bool
+}
+
+var mouseReport = regexp.MustCompile(`^\033\[<(\d+);(\d+);(\d+)([Mm])`)
+
+// ParseMouseEvent parses the mouse event that a terminal reported at the start of
+// b, as terminals do while players play with Config.MouseReporting, and returns
+// how many bytes of b it took. It reports false if b doesn't start with a whole
+// mouse event.
+func ParseMouseEvent(b []byte) (MouseEvent, int, bool) {
+	m := mouseReport.FindSubmatch(b)
+	if m == nil {
+		return MouseEvent{}, 0, false
+	}
+	button, _ := strconv.Atoi(string(m[1]))
+	col, _ := strconv.Atoi(string(m[2]))
+	row, _ := strconv.Atoi(string(m[3]))
+	// Terminals count cells from 1.
+	return MouseEvent{Button: button, Col: col - 1, Row: row - 1, Released: m[4][0] == 'm'}, len(m[0]), true
+}
+
+/*
+ImageLayout is where an image was printed on screen and how it was scaled to be
+printed, so that cells on screen, such as those clicked, can be mapped back to the
+pixels of the image they show. Eg: an 800x600 image fitted into 80 columns is
+printed 160 dots wide, so each cell covers 10 pixels across:
+
+	layout := dotmatrix.ImageLayout{
+		Origin:  image.Pt(0, 2),
+		Source:  image.Rect(0, 0, 800, 600),
+		Printed: image.Rect(0, 0, 160, 120),
+	}
+	px, ok := layout.Pixel(click.Col, click.Row)
+*/
+type ImageLayout struct {
+	// Origin is the cell at the top-left of the printed image, counting from 0 at
+	// the top-left of the terminal. Frames, such as BorderFlusher's, aren't part
+	// of the image.
+	Origin image.Point
+	// Source is the bounds of the image as it was given to print.
+	Source image.Rectangle
+	// Printed is the bounds of the image in dots once filtered, eg: after being
+	// scaled to fit the terminal.
+	Printed image.Rectangle
+}
+
+// Pixel returns the pixel of the source image shown in the middle of the cell at
+// col, row on screen, and reports false if the cell isn't over the image.
+func (l ImageLayout) Pixel(col, row int) (image.Point, bool) {
+	col, row = col-l.Origin.X, row-l.Origin.Y
+	dx, dy := l.Printed.Dx(), l.Printed.Dy()
+	// Each cell is two dots wide and four tall, though those at the right and
+	// bottom edges may be cut short by the image's.
+	if col < 0 || row < 0 || 2*col >= dx || 4*row >= dy || l.Source.Empty() {
+		return image.Point{}, false
+	}
+	x := (float64(2*col) + math.Min(float64(2*col+2), float64(dx))) / 2
+	y := (float64(4*row) + math.Min(float64(4*row+4), float64(dy))) / 2
+	return image.Pt(
+		l.Source.Min.X+int(x*float64(l.Source.Dx())/float64(dx)),
+		l.Source.Min.Y+int(y*float64(l.Source.Dy())/float64(dy)),
+	), true
+}
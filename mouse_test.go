@@ -0,0 +1,41 @@
+package dotmatrix_test
+
+import (
+	"image"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Mouse", func() {
+	It("should parse SGR mouse reports", func() {
+		event, n, ok := dotmatrix.ParseMouseEvent([]byte("\033[<0;12;3Mq"))
+		Expect(ok).To(BeTrue())
+		Expect(n).To(Equal(10))
+		Expect(event).To(Equal(dotmatrix.MouseEvent{Button: 0, Col: 11, Row: 2}))
+
+		_, _, ok = dotmatrix.ParseMouseEvent([]byte("\033[<0;12"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should map cells back to the pixels of the source image", func() {
+		layout := dotmatrix.ImageLayout{
+			Origin:  image.Pt(1, 2),
+			Source:  image.Rect(0, 0, 800, 600),
+			Printed: image.Rect(0, 0, 160, 120),
+		}
+		px, ok := layout.Pixel(1, 2)
+		Expect(ok).To(BeTrue())
+		Expect(px).To(Equal(image.Pt(5, 10)))
+		px, ok = layout.Pixel(80, 31)
+		Expect(ok).To(BeTrue())
+		Expect(px).To(Equal(image.Pt(795, 590)))
+
+		_, ok = layout.Pixel(0, 2)
+		Expect(ok).To(BeFalse())
+		_, ok = layout.Pixel(81, 2)
+		Expect(ok).To(BeFalse())
+	})
+})
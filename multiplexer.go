@@ -0,0 +1,66 @@
+package dotmatrix
+
+import (
+	"os"
+	"strings"
+)
+
+// Multiplexer is a terminal multiplexer, such as tmux or GNU screen, that sits
+// between a program and the terminal displaying it. Multiplexers interpret
+// cursor movement themselves, but swallow escape sequences they don't
+// understand, such as graphics protocols and queries, unless they are wrapped to
+// be passed through to the terminal outside.
+type Multiplexer int
+
+const (
+	NoMultiplexer Multiplexer = iota
+	Tmux
+	Screen
+)
+
+// screenPassthroughLimit is the longest string GNU screen passes through at once.
+const screenPassthroughLimit = 768
+
+// DetectMultiplexer reports the multiplexer the process is running in, from the
+// environment. TERM is consulted last, as it is passed on by ssh where the
+// multiplexer's own variables aren't.
+func DetectMultiplexer() Multiplexer {
+	switch term := os.Getenv("TERM"); {
+	case os.Getenv("TMUX") != "":
+		return Tmux
+	case os.Getenv("STY") != "":
+		return Screen
+	case strings.HasPrefix(term, "tmux"):
+		return Tmux
+	case strings.HasPrefix(term, "screen"):
+		return Screen
+	}
+	return NoMultiplexer
+}
+
+// Passthrough wraps seq in device control strings that have the multiplexer pass
+// it through to the terminal outside, unchanged. Without a multiplexer, seq is
+// returned as it is. Tmux only passes sequences through if its
+// allow-passthrough option is on.
+func (m Multiplexer) Passthrough(seq string) string {
+	switch m {
+	case Tmux:
+		// Escapes within the sequence are doubled, so that the first one that isn't
+		// ends the passthrough.
+		return "\033Ptmux;" + strings.Replace(seq, "\033", "\033\033", -1) + "\033\\"
+	case Screen:
+		// Screen passes through only so much of each device control string, so long
+		// sequences are passed through in pieces.
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := len(seq)
+			if n > screenPassthroughLimit {
+				n = screenPassthroughLimit
+			}
+			b.WriteString("\033P" + seq[:n] + "\033\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	}
+	return seq
+}
@@ -0,0 +1,26 @@
+package dotmatrix_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Multiplexer", func() {
+	It("should pass sequences through tmux with their escapes doubled", func() {
+		Expect(dotmatrix.Tmux.Passthrough("\033]11;?\007")).To(Equal("\033Ptmux;\033\033]11;?\007\033\\"))
+	})
+
+	It("should pass long sequences through screen in pieces", func() {
+		wrapped := dotmatrix.Screen.Passthrough(strings.Repeat("x", 1000))
+		Expect(strings.Count(wrapped, "\033P")).To(Equal(2))
+		Expect(strings.Count(wrapped, "x")).To(Equal(1000))
+	})
+
+	It("should leave sequences alone without a multiplexer", func() {
+		Expect(dotmatrix.NoMultiplexer.Passthrough("\033[?25l")).To(Equal("\033[?25l"))
+	})
+})
@@ -0,0 +1,94 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"time"
+)
+
+// Event is something that happens during playback which users may want to be
+// notified of.
+type Event int
+
+const (
+	// PlaybackDone is notified when an animation or stream plays to its end.
+	PlaybackDone Event = iota
+	// MotionDetected is notified by a MotionFilter's OnMotion hook.
+	MotionDetected
+	// StreamReconnected is notified when a stream's source reconnects after
+	// dropping.
+	StreamReconnected
+)
+
+// Notification is a set of ways to bring an event to the user's attention.
+type Notification int
+
+const (
+	// Bell rings the terminal bell.
+	Bell Notification = 1 << iota
+	// Flash briefly shows the frame on screen with its dots inverted.
+	Flash
+)
+
+const (
+	// flashDuration is how long a Flash shows the inverted frame for.
+	flashDuration = 100 * time.Millisecond
+	// notifyInterval is the least time between notifications of the same event,
+	// so that continuous motion doesn't ring the bell on every frame.
+	notifyInterval = time.Second
+)
+
+// notify brings events to the user's attention as the config asks.
+func (p *presenter) notify(events ...Event) error {
+	img := p.shown
+	var how Notification
+	now := time.Now()
+	for _, e := range events {
+		n := p.c.Notifications[e]
+		if n == 0 || now.Sub(p.notified[e]) < notifyInterval {
+			continue
+		}
+		if p.notified == nil {
+			p.notified = map[Event]time.Time{}
+		}
+		p.notified[e] = now
+		how |= n
+	}
+	if how&Bell != 0 {
+		if _, err := io.WriteString(p.w, "\a"); err != nil {
+			return err
+		}
+	}
+	if how&Flash != 0 && img != nil {
+		for _, frame := range []image.Image{invert(img), img} {
+			p.reset()
+			rows, err := p.flushFull(p.w, frame, false)
+			if err != nil {
+				return err
+			}
+			p.rows = rows
+			if frame != img {
+				time.Sleep(flashDuration)
+			}
+		}
+	}
+	return nil
+}
+
+// invert returns a copy of img with its dots inverted: dots become white and all
+// other pixels black.
+func invert(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+	dst := image.NewPaletted(bounds, defaultPalette)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if IsDot(img.At(x, y)) {
+				dst.Set(x, y, color.White)
+			} else {
+				dst.Set(x, y, color.Black)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,116 @@
+package dotmatrix
+
+import (
+	"errors"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+/*
+Option sets part of a Config, as an alternative to filling one in directly. Unlike
+a Config, options check what they're given, so that mistakes are reported when a
+printer is made instead of showing up as garbled output. Eg:
+
+	printer, err := dotmatrix.NewPrinterWith(os.Stdout,
+		dotmatrix.WithDrawer(draw.Src),
+		dotmatrix.WithFlusher(dotmatrix.BorderFlusher{Title: "Saturn"}),
+	)
+*/
+type Option func(*Config) error
+
+// WithFilter filters images before they're drawn, eg: to scale them.
+func WithFilter(f Filter) Option {
+	return func(c *Config) error {
+		if f == nil {
+			return errors.New("dotmatrix: nil filter")
+		}
+		c.Filter = f
+		return nil
+	}
+}
+
+// WithFlusher prints drawn images, eg: as braille.
+func WithFlusher(f Flusher) Option {
+	return func(c *Config) error {
+		if f == nil {
+			return errors.New("dotmatrix: nil flusher")
+		}
+		c.Flusher = f
+		return nil
+	}
+}
+
+// WithDrawer draws filtered images in the palette, eg: by dithering them.
+func WithDrawer(d draw.Drawer) Option {
+	return func(c *Config) error {
+		if d == nil {
+			return errors.New("dotmatrix: nil drawer")
+		}
+		c.Drawer = d
+		return nil
+	}
+}
+
+// WithQuantizer decides which pixels of filtered images are printed as dots, in
+// place of the drawer and palette.
+func WithQuantizer(q Quantizer) Option {
+	return func(c *Config) error {
+		if q == nil {
+			return errors.New("dotmatrix: nil quantizer")
+		}
+		c.Quantizer = q
+		return nil
+	}
+}
+
+// WithReset moves the cursor back over the rows of an animated frame before the
+// next one is printed.
+func WithReset(reset func(w io.Writer, rows int)) Option {
+	return func(c *Config) error {
+		if reset == nil {
+			return errors.New("dotmatrix: nil reset")
+		}
+		c.Reset = reset
+		return nil
+	}
+}
+
+// WithPalette quantizes images to p. See Config.Palette.
+func WithPalette(p color.Palette) Option {
+	return func(c *Config) error {
+		if err := checkPalette(p); err != nil {
+			return err
+		}
+		c.Palette = p
+		return nil
+	}
+}
+
+// NewConfig returns a config with the given options set, or the first error in
+// them. See Config.Validate.
+func NewConfig(opts ...Option) (*Config, error) {
+	c := &Config{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewPrinterWith provides a Printer writing to w with the given options, or the
+// first error in them.
+func NewPrinterWith(w io.Writer, opts ...Option) (*Printer, error) {
+	if err := checkWriter(w); err != nil {
+		return nil, err
+	}
+	c, err := NewConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrinter(w, c), nil
+}
@@ -0,0 +1,43 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Options", func() {
+	It("should reject nil settings and palettes without black", func() {
+		_, err := dotmatrix.NewPrinterWith(nil, dotmatrix.WithFlusher(nil))
+		Expect(err).To(HaveOccurred())
+		_, err = dotmatrix.NewConfig(dotmatrix.WithPalette(color.Palette{color.White, color.Transparent}))
+		Expect(err).To(MatchError(ContainSubstring("color.Black")))
+	})
+
+	It("should print only the pixels quantized to black as dots", func() {
+		img := image.NewUniform(color.Gray{0x30})
+		dark := image.NewRGBA(image.Rect(0, 0, 2, 4))
+		draw.Draw(dark, dark.Bounds(), img, image.Point{}, draw.Src)
+
+		var out bytes.Buffer
+		printer, err := dotmatrix.NewPrinterWith(&out, dotmatrix.WithDrawer(draw.Src))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(printer.Print(dark)).To(Succeed())
+		Expect(out.String()).To(Equal("⣿\n"))
+
+		out.Reset()
+		printer, err = dotmatrix.NewPrinterWith(&out,
+			dotmatrix.WithDrawer(draw.Src),
+			dotmatrix.WithPalette(color.Palette{color.Black, color.Gray{0x40}, color.White}),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(printer.Print(dark)).To(Succeed())
+		Expect(out.String()).To(Equal("⠀\n"))
+	})
+})
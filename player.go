@@ -0,0 +1,512 @@
+package dotmatrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlayerState is whether a Player is playing.
+type PlayerState int
+
+const (
+	Stopped PlayerState = iota
+	Playing
+	Paused
+)
+
+// PlayerStatus describes a Player at a moment in time.
+type PlayerStatus struct {
+	State PlayerState
+	// Frame is the index of the frame on screen, or about to be.
+	Frame int
+	Speed float64
+	// Dropped is how many frames of a live stream have been dropped so far for
+	// arriving faster than they could be printed.
+	Dropped int
+}
+
+// track is a sequence of frames that a Player plays.
+type track interface {
+	// frame draws frame i and returns the screen, the region of the screen that
+	// changed since the last frame, and how long to show the frame for. It returns
+	// io.EOF once i is past the last frame.
+	frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error)
+	// loops returns how many times to play the track, or 0 to loop forever.
+	loops() int
+}
+
+// timeline is a track whose frames are known before they are played, such as an
+// animation's, unlike a live stream's.
+type timeline interface {
+	length() int
+	delayOf(i int) time.Duration
+}
+
+// dropper is a track that drops frames it can't keep up with.
+type dropper interface {
+	dropped() int
+}
+
+/*
+Player plays animations, and can be paused, resumed, sought and sped up or slowed
+down from other goroutines while it plays. This lets interactive programs control
+playback instead of just cancelling it. GIFPrinter and MJPEGPrinter play with a
+Player under the hood.
+*/
+type Player struct {
+	// OnStatus, if set, is called from the playing goroutine whenever the player's
+	// status changes, including when each frame is shown.
+	OnStatus func(PlayerStatus)
+
+	w     io.Writer
+	c     *Config
+	t     track
+	clock clock
+	stats *statsMeter
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc // stops the running Play, if any
+	closed  bool
+	paused  bool
+	seek    int // the frame to seek to, or -1
+	speed   float64
+	events  []Event // to notify once the frame on screen has been flushed
+	changed chan struct{}
+}
+
+func newPlayer(w io.Writer, c *Config, t track) *Player {
+	return &Player{
+		w:       w,
+		c:       c,
+		t:       t,
+		clock:   systemClock{},
+		stats:   &statsMeter{},
+		seek:    -1,
+		speed:   1,
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// clock tells the time and sets timers for scheduling frames.
+type clock interface {
+	now() time.Time
+	// timer returns a channel that receives once d has passed, and a function that
+	// stops it early.
+	timer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+// systemClock is the clock players schedule frames by, outside of tests.
+type systemClock struct{}
+
+func (systemClock) now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) timer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
+
+// Pause stops playback on the current frame.
+func (p *Player) Pause() {
+	p.control(func() { p.paused = true })
+}
+
+// Resume continues playback after a Pause.
+func (p *Player) Resume() {
+	p.control(func() { p.paused = false })
+}
+
+// Seek skips to the given frame, counting from 0, whether playing or paused.
+// Frames of live streams can't be sought, so seeking them has no effect.
+func (p *Player) Seek(frame int) {
+	if frame < 0 {
+		frame = 0
+	}
+	p.control(func() { p.seek = frame })
+}
+
+// SetSpeed scales the rate of playback, e.g. 2 plays twice as fast and 0.5 at half
+// speed. Speeds that aren't positive are ignored. The frame rate of live streams
+// is set by their source, so changing speed has no effect on them.
+func (p *Player) SetSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+	p.control(func() { p.speed = speed })
+}
+
+// Stats returns how well the current or last playback kept up. It may be called
+// from any goroutine.
+func (p *Player) Stats() Stats {
+	return p.stats.snapshot()
+}
+
+// Notify notifies the user of an event as the config's Notifications ask, once
+// the frame being drawn has been shown. It is safe to call from any goroutine,
+// including from filters as they draw.
+func (p *Player) Notify(e Event) {
+	p.mu.Lock()
+	p.events = append(p.events, e)
+	p.mu.Unlock()
+}
+
+// control applies a change and wakes the playing goroutine to act on it.
+func (p *Player) control(change func()) {
+	p.mu.Lock()
+	change()
+	p.mu.Unlock()
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Players hide the cursor while they play, so that it doesn't flicker over frames.
+const (
+	hideCursor = "\033[?25l"
+	showCursor = "\033[?12l\033[?25h"
+)
+
+// ErrPlayerClosed is returned by Play after Close.
+var ErrPlayerClosed = errors.New("dotmatrix: player closed")
+
+// Close stops playback, if playing, and releases the player's resources, such as
+// the goroutine reading a stream. It may be called from any goroutine. Closed
+// players can't be played again.
+func (p *Player) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	cancel := p.cancel
+	p.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// Play plays the animation to its end, or until ctx is cancelled or the player is
+// closed.
+func (p *Player) Play(ctx context.Context) (err error) {
+	defer p.c.reportError(&err)
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPlayerClosed
+	}
+	if err := checkWriter(p.w); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	if err := p.c.Validate(); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.mu.Unlock()
+	defer cancel()
+	if closer, ok := p.t.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	w := lineWriter(p.w, p.c.LineEnding)
+	if !p.c.KeepCursor {
+		io.WriteString(w, hideCursor)
+		// Deferred, so that the cursor comes back even if playback panics.
+		defer io.WriteString(w, showCursor)
+	}
+	if p.c.MouseReporting {
+		io.WriteString(w, enableMouse)
+		defer io.WriteString(w, disableMouse)
+	}
+
+	p.stats.reset()
+	started := p.clock.now()
+	out := newPresenter(meteredWriter{w, p.stats}, p.c)
+	out.overwrite = true
+	status := PlayerStatus{State: Playing, Speed: 1}
+	report := func() {
+		if p.OnStatus != nil {
+			p.OnStatus(status)
+		}
+	}
+	dropped := func() {
+		if d, ok := p.t.(dropper); ok {
+			status.Dropped = d.dropped()
+		}
+	}
+	defer func() {
+		status.State = Stopped
+		dropped()
+		report()
+	}()
+
+	var (
+		steps <-chan error
+		// Frames are scheduled against the clock, so that time spent drawing and
+		// flushing doesn't add to their delays: each frame is due when the one before
+		// it is, plus its delay. due is when the frame on screen should be replaced,
+		// or zero if the schedule starts over from the next frame.
+		due time.Time
+		// skipped is the screen of the last frame skipped for being late, or nil.
+		skipped image.Image
+		// recorded is how many frames have been recorded.
+		recorded int
+	)
+	for i, loop := 0, 0; ; {
+		// Wait out any pause, then act on any seek.
+		for {
+			p.mu.Lock()
+			paused, seek, speed := p.paused, p.seek, p.speed
+			p.seek = -1
+			p.mu.Unlock()
+
+			if seek >= 0 {
+				i = seek
+			}
+			if paused || seek >= 0 {
+				due = time.Time{}
+			}
+			status.Frame, status.Speed = i, speed
+			if paused {
+				if status.State != Paused || seek >= 0 {
+					status.State = Paused
+					report()
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-p.changed:
+					continue
+				}
+			}
+			status.State = Playing
+			break
+		}
+
+		screen, damage, delay, err := p.t.frame(ctx, i)
+		if err == io.EOF {
+			loop++
+			if i == 0 || (p.t.loops() != 0 && loop >= p.t.loops()) {
+				// Leave the last frame on screen, even if it was late.
+				if skipped != nil {
+					if p.c.OnFrameStart != nil {
+						p.c.OnFrameStart(i - 1)
+					}
+					if err := out.presentDamage(i-1, skipped, image.Rectangle{}); err != nil {
+						return err
+					}
+				}
+				p.Notify(PlaybackDone)
+				return out.notify(p.takeEvents()...)
+			}
+			i = 0
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Only the first play through is recorded, so that looping forever doesn't
+		// record forever, and only in order, so that seeking doesn't record twice.
+		if p.c.Recorder != nil && loop == 0 && i == recorded {
+			if err := p.c.Recorder.record(screen, delay, p.c); err != nil {
+				return err
+			}
+			recorded++
+		}
+
+		if p.c.Step == nil {
+			now := p.clock.now()
+			if due.IsZero() {
+				due = now
+			}
+			due = due.Add(time.Duration(float64(delay) / status.Speed))
+			// A frame that is already over by the time it has been drawn is skipped,
+			// so that slow terminals don't make the animation run long. Frames without
+			// delays are never late.
+			if delay > 0 && out.flushed && !due.After(now) {
+				out.skip(damage)
+				p.stats.skipped()
+				skipped = screen
+				i++
+				continue
+			}
+		}
+		skipped = nil
+
+		dropped()
+		report()
+		if p.c.OnFrameStart != nil {
+			p.c.OnFrameStart(i)
+		}
+		if err := out.presentDamage(i, screen, damage); err != nil {
+			return err
+		}
+		if err := out.notify(p.takeEvents()...); err != nil {
+			return err
+		}
+		if p.stats.shown(status.Dropped) && p.c.OnStats != nil {
+			p.c.OnStats(p.stats.snapshot())
+		}
+		var lines []string
+		if p.c.StatusLine {
+			lines = append(lines, p.statusLine(i, started, status.Speed))
+		} else if p.c.Step != nil {
+			lines = append(lines, fmt.Sprintf("frame %d", i))
+		}
+		if p.c.ShowStats {
+			lines = append(lines, p.stats.snapshot().String())
+		}
+		if len(lines) > 0 {
+			if err := out.status(strings.Join(lines, ", ")); err != nil {
+				return err
+			}
+		}
+		if p.c.Step != nil {
+			if steps == nil {
+				steps = readSteps(ctx, p.c.Step)
+			}
+			if ok, err := p.step(ctx, steps); !ok {
+				return err
+			}
+		} else if due, err = p.wait(ctx, due, status.Speed); err != nil {
+			return err
+		}
+		out.reset()
+		i++
+	}
+}
+
+// statusLine describes playback of frame i for the status line: what's playing,
+// which frame, how far into the track it is and how fast it's playing. Tracks
+// known in advance are timed by their frames' delays, and live streams by the
+// clock since playback started.
+func (p *Player) statusLine(i int, started time.Time, speed float64) string {
+	var fields []string
+	if p.c.Name != "" {
+		fields = append(fields, p.c.Name)
+	}
+	if t, ok := p.t.(timeline); ok {
+		var elapsed, total time.Duration
+		for j := 0; j < t.length(); j++ {
+			if j == i {
+				elapsed = total
+			}
+			total += t.delayOf(j)
+		}
+		fields = append(fields,
+			fmt.Sprintf("frame %d/%d", i+1, t.length()),
+			formatPosition(elapsed)+"/"+formatPosition(total))
+	} else {
+		fields = append(fields, fmt.Sprintf("frame %d", i+1), formatPosition(p.clock.now().Sub(started)))
+	}
+	fields = append(fields, strconv.FormatFloat(speed, 'g', -1, 64)+"x")
+	return strings.Join(fields, "  ")
+}
+
+// formatPosition formats a position in a track as minutes and seconds, to a tenth
+// of a second.
+func formatPosition(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+	return fmt.Sprintf("%d:%04.1f", int(d/time.Minute), (d % time.Minute).Seconds())
+}
+
+// takeEvents returns and clears the events waiting to be notified.
+func (p *Player) takeEvents() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := p.events
+	p.events = nil
+	return events
+}
+
+// wait sleeps until due, when the frame on screen ends if played at speed. Pausing
+// or seeking cuts the wait short so they take effect immediately, while speed
+// changes reschedule the rest of the wait. It returns when the frame ends, as
+// rescheduled.
+func (p *Player) wait(ctx context.Context, due time.Time, speed float64) (time.Time, error) {
+	for {
+		p.mu.Lock()
+		interrupted, changed := p.paused || p.seek >= 0, p.speed
+		p.mu.Unlock()
+		now := p.clock.now()
+		if changed != speed {
+			due = now.Add(time.Duration(float64(due.Sub(now)) * speed / changed))
+			speed = changed
+		}
+		if interrupted || !due.After(now) {
+			return due, ctx.Err()
+		}
+
+		timer, stop := p.clock.timer(due.Sub(now))
+		select {
+		case <-ctx.Done():
+			stop()
+			return due, ctx.Err()
+		case <-timer:
+			return due, nil
+		case <-p.changed:
+			stop()
+		}
+	}
+}
+
+// step waits for the next step to be read, or for a pause or seek. It reports false
+// once there are no more steps, along with any error reading them.
+func (p *Player) step(ctx context.Context, steps <-chan error) (bool, error) {
+	p.mu.Lock()
+	interrupted := p.paused || p.seek >= 0
+	p.mu.Unlock()
+	if interrupted {
+		return true, nil
+	}
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case err, ok := <-steps:
+		if !ok || err == io.EOF {
+			return false, nil
+		}
+		return err == nil, err
+	case <-p.changed:
+		return true, nil
+	}
+}
+
+// readSteps reads r a byte at a time, sending nil for each byte read and then the
+// error that ended reading, until ctx is done. Reads can't be cancelled, so this
+// is done on its own goroutine, which exits once a read in progress returns.
+func readSteps(ctx context.Context, r io.Reader) <-chan error {
+	steps := make(chan error)
+	go func() {
+		defer close(steps)
+		send := func(err error) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case steps <- err:
+				return true
+			}
+		}
+		b := make([]byte, 1)
+		for {
+			n, err := r.Read(b)
+			if n > 0 && !send(nil) {
+				return
+			}
+			if err != nil {
+				send(err)
+				return
+			}
+		}
+	}()
+	return steps
+}
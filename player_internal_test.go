@@ -0,0 +1,201 @@
+package dotmatrix
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Player scheduling", func() {
+	var (
+		clock  *fakeClock
+		track  *fakeTrack
+		player *Player
+		shown  []string
+		done   chan error
+	)
+
+	BeforeEach(func() {
+		clock = newFakeClock()
+		track = &fakeTrack{clock: clock, delays: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}}
+		shown = nil
+	})
+
+	play := func() {
+		c := mergeConfig(&Config{
+			KeepCursor: true,
+			OnFrameStart: func(i int) {
+				shown = append(shown, fmt.Sprintf("%d@%v", i, clock.elapsed()))
+			},
+		})
+		player = newPlayer(ioutil.Discard, &c, track)
+		player.clock = clock
+		done = make(chan error, 1)
+		go func() { done <- player.Play(context.Background()) }()
+	}
+	nextTimer := func() time.Duration {
+		var d time.Duration
+		Eventually(clock.set).Should(Receive(&d))
+		return d
+	}
+
+	It("should schedule frames against the clock, not after drawing them", func() {
+		track.draws = []time.Duration{30 * time.Millisecond, 30 * time.Millisecond}
+		play()
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		clock.advance(100 * time.Millisecond)
+		// Drawing the second frame took 30ms of its 100ms.
+		Expect(nextTimer()).To(Equal(70 * time.Millisecond))
+		clock.advance(70 * time.Millisecond)
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(shown).To(Equal([]string{"0@30ms", "1@160ms"}))
+	})
+
+	It("should skip frames drawn too late to show", func() {
+		track.delays = append(track.delays, 100*time.Millisecond)
+		track.draws = []time.Duration{0, 150 * time.Millisecond}
+		play()
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		clock.advance(100 * time.Millisecond)
+		// The second frame was over 50ms before it was drawn, and the third is due
+		// 50ms from then.
+		Expect(nextTimer()).To(Equal(50 * time.Millisecond))
+		clock.advance(50 * time.Millisecond)
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(shown).To(Equal([]string{"0@0s", "2@250ms"}))
+	})
+
+	It("should cut the frame short and start the schedule over when seeking", func() {
+		track.delays = append(track.delays, 100*time.Millisecond, 100*time.Millisecond)
+		play()
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		player.Seek(2)
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		clock.advance(100 * time.Millisecond)
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		clock.advance(100 * time.Millisecond)
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(shown).To(Equal([]string{"0@0s", "2@0s", "3@100ms"}))
+	})
+
+	It("should reschedule the rest of the frame when the speed changes", func() {
+		play()
+		Expect(nextTimer()).To(Equal(100 * time.Millisecond))
+		clock.advance(50 * time.Millisecond)
+		player.SetSpeed(2)
+		// The 50ms left of the first frame take 25ms at double speed, and the
+		// second frame 50ms.
+		Expect(nextTimer()).To(Equal(25 * time.Millisecond))
+		clock.advance(25 * time.Millisecond)
+		Expect(nextTimer()).To(Equal(50 * time.Millisecond))
+		clock.advance(50 * time.Millisecond)
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(shown).To(Equal([]string{"0@0s", "1@75ms"}))
+	})
+
+	It("should stop playing and refuse to play again once closed", func() {
+		play()
+		nextTimer()
+		Expect(player.Close()).To(Succeed())
+		Eventually(done).Should(Receive(Equal(context.Canceled)))
+		Expect(track.closed).To(BeTrue())
+		Expect(shown).To(Equal([]string{"0@0s"}))
+
+		Expect(player.Play(context.Background())).To(Equal(ErrPlayerClosed))
+	})
+})
+
+// fakeTrack plays blank frames with the given delays, advancing its clock by the
+// given draw times as it draws them, once through.
+type fakeTrack struct {
+	clock  *fakeClock
+	delays []time.Duration
+	draws  []time.Duration
+	closed bool
+}
+
+func (t *fakeTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	if i >= len(t.delays) {
+		return nil, image.Rectangle{}, 0, io.EOF
+	}
+	if i < len(t.draws) {
+		t.clock.advance(t.draws[i])
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 2, 4), defaultPalette)
+	return img, img.Bounds(), t.delays[i], nil
+}
+
+func (t *fakeTrack) loops() int {
+	return 1
+}
+
+func (t *fakeTrack) Close() error {
+	t.closed = true
+	return nil
+}
+
+// fakeClock only moves when advanced. Each timer set on it is sent to set, which
+// must be received from for the timer to be returned.
+type fakeClock struct {
+	set chan time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	t      time.Time
+	timers map[chan time.Time]time.Time
+}
+
+func newFakeClock() *fakeClock {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &fakeClock{
+		set:    make(chan time.Duration),
+		start:  start,
+		t:      start,
+		timers: make(map[chan time.Time]time.Time),
+	}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) elapsed() time.Duration {
+	return c.now().Sub(c.start)
+}
+
+func (c *fakeClock) timer(d time.Duration) (<-chan time.Time, func() bool) {
+	ch := make(chan time.Time, 1)
+	c.mu.Lock()
+	c.timers[ch] = c.t.Add(d)
+	c.mu.Unlock()
+	c.set <- d
+	return ch, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, ok := c.timers[ch]
+		delete(c.timers, ch)
+		return ok
+	}
+}
+
+// advance moves the clock on by d, firing the timers that are then due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	for ch, at := range c.timers {
+		if !at.After(c.t) {
+			ch <- c.t
+			delete(c.timers, ch)
+		}
+	}
+}
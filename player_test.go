@@ -0,0 +1,356 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Player", func() {
+	palette := color.Palette{color.Transparent, color.Black}
+	giff := &gif.GIF{LoopCount: -1, Config: image.Config{Width: 20, Height: 8}}
+	for i := 0; i < 10; i++ {
+		img := image.NewPaletted(image.Rect(i*2, 0, i*2+2, 8), palette)
+		img.SetColorIndex(i*2, 0, 1)
+		giff.Image = append(giff.Image, img)
+		giff.Delay = append(giff.Delay, 2) // 20ms
+		giff.Disposal = append(giff.Disposal, gif.DisposalNone)
+	}
+
+	It("should skip late frames to keep pace", func() {
+		// Each reset takes longer than a frame's delay, like a slow terminal.
+		player := dotmatrix.NewGIFPlayer(ioutil.Discard, &dotmatrix.Config{
+			Reset: func(w io.Writer, rows int) { time.Sleep(50 * time.Millisecond) },
+		}, giff)
+		var shown []int
+		player.OnStatus = func(s dotmatrix.PlayerStatus) {
+			if s.State == dotmatrix.Playing {
+				shown = append(shown, s.Frame)
+			}
+		}
+
+		start := time.Now()
+		Expect(player.Play(context.Background())).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 400*time.Millisecond))
+		Expect(len(shown)).To(BeNumerically("<", len(giff.Image)))
+		Expect(shown[0]).To(Equal(0))
+	})
+
+	It("should stop streaming when closed", func() {
+		var jpg bytes.Buffer
+		Expect(jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil)).To(Succeed())
+		before := runtime.NumGoroutine()
+
+		// An endless stream, which is never drained after the player stops.
+		r, w := io.Pipe()
+		go func() {
+			for {
+				if _, err := w.Write(jpg.Bytes()); err != nil {
+					return
+				}
+			}
+		}()
+		player := dotmatrix.NewMJPEGPlayer(ioutil.Discard, nil, r, 0)
+		done := make(chan error)
+		go func() { done <- player.Play(context.Background()) }()
+		time.Sleep(20 * time.Millisecond)
+
+		Expect(player.Close()).To(Succeed())
+		Eventually(done).Should(Receive(Equal(context.Canceled)))
+		Expect(player.Play(context.Background())).To(Equal(dotmatrix.ErrPlayerClosed))
+		r.Close()
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+	})
+
+	It("should stop streams stuck in a read that never returns", func() {
+		before := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &stalledReader{closed: make(chan struct{})}
+		player := dotmatrix.NewMJPEGPlayer(ioutil.Discard, nil, r, 0)
+		done := make(chan error)
+		go func() { done <- player.Play(ctx) }()
+		time.Sleep(20 * time.Millisecond)
+
+		cancel()
+		Eventually(done).Should(Receive(Equal(context.Canceled)))
+		Expect(r.closed).To(BeClosed())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+	})
+	It("should skip corrupt mjpeg frames when asked to", func() {
+		var jpg bytes.Buffer
+		Expect(jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil)).To(Succeed())
+		var stream bytes.Buffer
+		stream.Write(jpg.Bytes())
+		stream.WriteString("--boundary\r\n\r\n")
+		stream.Write(jpg.Bytes()[:jpg.Len()/2])
+		stream.Write(jpg.Bytes())
+		stream.Write([]byte{0xff, 0xd8, 0x00, 0x01, 0xff, 0xd9})
+		stream.Write(jpg.Bytes())
+
+		var skipped []error
+		c := &dotmatrix.Config{
+			SkipCorruptFrames: true,
+			OnCorruptFrame:    func(err error) { skipped = append(skipped, err) },
+		}
+		Expect(dotmatrix.NewMJPEGPrinter(ioutil.Discard, c).Print(context.Background(), bytes.NewReader(stream.Bytes()), 0)).To(Succeed())
+		Expect(skipped).To(HaveLen(2))
+
+		Expect(dotmatrix.NewMJPEGPrinter(ioutil.Discard, nil).Print(context.Background(), bytes.NewReader(stream.Bytes()), 0)).NotTo(Succeed())
+	})
+
+	It("should drop frames of streams it can't keep up with as the policy says", func() {
+		var jpg bytes.Buffer
+		Expect(jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil)).To(Succeed())
+		stream := bytes.Repeat(jpg.Bytes(), 10)
+
+		play := func(policy dotmatrix.DropPolicy) (shown int, last dotmatrix.PlayerStatus) {
+			// Each reset is slower than the stream, like a slow terminal.
+			player := dotmatrix.NewMJPEGPlayer(ioutil.Discard, &dotmatrix.Config{
+				DropPolicy: policy,
+				Reset:      func(w io.Writer, rows int) { time.Sleep(10 * time.Millisecond) },
+			}, bytes.NewReader(stream), 0)
+			player.OnStatus = func(s dotmatrix.PlayerStatus) {
+				if s.State == dotmatrix.Playing {
+					shown++
+				}
+				last = s
+			}
+			Expect(player.Play(context.Background())).To(Succeed())
+			return shown, last
+		}
+
+		shown, last := play(dotmatrix.DropNone)
+		Expect(shown).To(Equal(10))
+		Expect(last.Dropped).To(BeZero())
+
+		shown, last = play(dotmatrix.DropOldest)
+		Expect(last.Dropped).To(BeNumerically(">", 0))
+		Expect(shown + last.Dropped).To(Equal(10))
+	})
+
+	It("should pace mjpeg frames by the camera's timestamps when asked to", func() {
+		var jpg bytes.Buffer
+		Expect(jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 8, 8)), nil)).To(Succeed())
+		var stream bytes.Buffer
+		for _, stamp := range []string{"100.000000", "100.100000", "100.200000"} {
+			fmt.Fprintf(&stream, "--boundary\r\nContent-Type: image/jpeg\r\nX-Timestamp: %s\r\n\r\n", stamp)
+			stream.Write(jpg.Bytes())
+			stream.WriteString("\r\n")
+		}
+
+		start := time.Now()
+		c := &dotmatrix.Config{CameraTiming: true, DropPolicy: dotmatrix.DropNone}
+		Expect(dotmatrix.NewMJPEGPrinter(ioutil.Discard, c).Print(context.Background(), &stream, 0)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("should record each frame of the first play through", func() {
+		var rec bytes.Buffer
+		recorder := dotmatrix.NewRecorder(&rec, "braille")
+		looped := *giff
+		looped.LoopCount, looped.Delay = 1, make([]int, len(giff.Delay))
+		player := dotmatrix.NewGIFPlayer(ioutil.Discard, &dotmatrix.Config{Recorder: recorder}, &looped)
+		Expect(player.Play(context.Background())).To(Succeed())
+		Expect(recorder.Close()).To(Succeed())
+
+		recording, err := dotmatrix.ReadRecording(bytes.NewReader(rec.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recording.Cols).To(Equal(10))
+		Expect(recording.Rows).To(Equal(2))
+		Expect(recording.Renderer).To(Equal("braille"))
+		Expect(recording.Frames).To(HaveLen(len(giff.Image)))
+	})
+
+	It("should show the cursor again even if playback panics", func() {
+		var out bytes.Buffer
+		c := &dotmatrix.Config{Filter: panicky{}}
+		Expect(func() {
+			dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)
+		}).To(Panic())
+		Expect(out.String()).To(HavePrefix("\033[?25l"))
+		Expect(out.String()).To(HaveSuffix("\033[?25h"))
+
+		out.Reset()
+		c.KeepCursor = true
+		Expect(func() {
+			dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)
+		}).To(Panic())
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	It("should wrap each frame in a synchronized update when asked to", func() {
+		once := *giff
+		once.LoopCount, once.Delay = -1, make([]int, len(giff.Delay))
+		var out bytes.Buffer
+		c := &dotmatrix.Config{SynchronizedOutput: true}
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), &once)).To(Succeed())
+		Expect(strings.Count(out.String(), "\033[?2026h")).To(Equal(len(giff.Image)))
+		Expect(strings.Count(out.String(), "\033[?2026l")).To(Equal(len(giff.Image)))
+	})
+
+	It("should measure how well playback keeps up", func() {
+		once := *giff
+		once.LoopCount = -1
+		var out bytes.Buffer
+		printer := dotmatrix.NewGIFPrinter(&out, &dotmatrix.Config{ShowStats: true})
+		Expect(printer.Print(context.Background(), &once)).To(Succeed())
+
+		stats := printer.Stats()
+		Expect(stats.Frames).To(Equal(len(giff.Image)))
+		Expect(stats.FPS).To(BeNumerically(">", 0))
+		Expect(stats.BytesPerSecond).To(BeNumerically(">", 0))
+		Expect(out.String()).To(ContainSubstring(" fps, "))
+	})
+
+	It("should describe playback in a status line", func() {
+		once := *giff
+		once.LoopCount, once.Delay = -1, make([]int, len(giff.Delay))
+		var out bytes.Buffer
+		c := &dotmatrix.Config{StatusLine: true, Name: "giff.gif"}
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), &once)).To(Succeed())
+
+		last := fmt.Sprintf("giff.gif  frame %d/%d  0:00.0/0:00.0  1x", len(giff.Image), len(giff.Image))
+		Expect(out.String()).To(ContainSubstring(last))
+	})
+
+	It("should call the lifecycle hooks as it plays", func() {
+		once := *giff
+		once.LoopCount, once.Delay = -1, make([]int, len(giff.Delay))
+		var (
+			out     bytes.Buffer
+			started []int
+			written int
+			failed  error
+		)
+		c := &dotmatrix.Config{
+			OnFrameStart: func(frame int) { started = append(started, frame) },
+			OnFlush: func(rows, bytes int) {
+				Expect(rows).To(Equal(2))
+				written += bytes
+			},
+			OnError: func(err error) { failed = err },
+		}
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), &once)).To(Succeed())
+		Expect(started).To(Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		Expect(written).To(BeNumerically(">", 0))
+		Expect(failed).To(BeNil())
+
+		c.LoopCount = -2
+		err := dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), &once)
+		Expect(err).To(HaveOccurred())
+		Expect(failed).To(Equal(err))
+	})
+
+	It("should crossfade through blended frames when interpolating", func() {
+		var rec bytes.Buffer
+		recorder := dotmatrix.NewRecorder(&rec, "braille")
+		once := *giff
+		once.LoopCount, once.Delay = -1, make([]int, len(giff.Delay))
+		c := &dotmatrix.Config{Recorder: recorder, Interpolate: 2}
+		Expect(dotmatrix.NewGIFPrinter(ioutil.Discard, c).Print(context.Background(), &once)).To(Succeed())
+		Expect(recorder.Close()).To(Succeed())
+
+		recording, err := dotmatrix.ReadRecording(bytes.NewReader(rec.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recording.Frames).To(HaveLen(3 * len(giff.Image)))
+	})
+
+	It("should replay recordings as they were printed", func() {
+		var rec, printed, replayed bytes.Buffer
+		recorder := dotmatrix.NewRecorder(&rec, "braille")
+		once := *giff
+		once.LoopCount, once.Delay = -1, make([]int, len(giff.Delay))
+		Expect(dotmatrix.NewGIFPrinter(&printed, &dotmatrix.Config{Recorder: recorder}).Print(context.Background(), &once)).To(Succeed())
+		Expect(recorder.Close()).To(Succeed())
+
+		Expect(dotmatrix.NewReplayPrinter(&replayed, nil).Print(context.Background(), &rec)).To(Succeed())
+		Expect(replayed.String()).To(Equal(printed.String()))
+	})
+})
+
+// panicky is a filter that panics.
+type panicky struct{}
+
+func (panicky) Filter(img image.Image) image.Image {
+	panic("filter failed")
+}
+
+var _ = Describe("RawSource", func() {
+	It("should play raw frames until the video ends", func() {
+		// A white frame, a black frame and the start of a frame cut short.
+		raw := append(bytes.Repeat([]byte{0xff}, 4*4), bytes.Repeat([]byte{0x00}, 4*4+5)...)
+		src, err := dotmatrix.NewRawSource(bytes.NewReader(raw), dotmatrix.RawGray8, 4, 4, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		var frames int
+		var out bytes.Buffer
+		player := dotmatrix.NewVideoPlayer(&out, nil, src)
+		player.OnStatus = func(s dotmatrix.PlayerStatus) {
+			if s.State == dotmatrix.Playing {
+				frames++
+			}
+		}
+		Expect(player.Play(context.Background())).To(Succeed())
+		Expect(frames).To(Equal(2))
+		Expect(out.String()).To(ContainSubstring("⣿⣿\n"))
+	})
+})
+
+var _ = Describe("Y4MSource", func() {
+	It("should read the size and frame rate from the stream", func() {
+		var y4m bytes.Buffer
+		y4m.WriteString("YUV4MPEG2 W4 H4 F25:1 Ip A1:1 C420jpeg\n")
+		for _, luma := range []byte{0xff, 0x00} {
+			y4m.WriteString("FRAME\n")
+			y4m.Write(bytes.Repeat([]byte{luma}, 4*4))
+			y4m.Write(bytes.Repeat([]byte{0x80}, 2*2*2))
+		}
+		src, err := dotmatrix.NewY4MSource(&y4m)
+		Expect(err).NotTo(HaveOccurred())
+
+		img, delay, err := src.NextFrame()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.Bounds()).To(Equal(image.Rect(0, 0, 4, 4)))
+		Expect(delay).To(Equal(40 * time.Millisecond))
+		r, _, _, _ := img.At(0, 0).RGBA()
+		Expect(r).To(BeNumerically(">", 0xf000))
+
+		img, _, err = src.NextFrame()
+		Expect(err).NotTo(HaveOccurred())
+		r, _, _, _ = img.At(0, 0).RGBA()
+		Expect(r).To(BeNumerically("<", 0x1000))
+
+		_, _, err = src.NextFrame()
+		Expect(err).To(Equal(io.EOF))
+	})
+})
+
+// stalledReader blocks reads until it is closed, like a stalled network stream.
+type stalledReader struct {
+	closed chan struct{}
+}
+
+func (r *stalledReader) Read(p []byte) (int, error) {
+	<-r.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (r *stalledReader) Close() error {
+	close(r.closed)
+	return nil
+}
@@ -0,0 +1,73 @@
+package dotmatrix
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"sync"
+)
+
+/*
+PrinterPool prints images from many goroutines at once with a shared config, such
+as from the handlers of a web server. A Printer remembers what it last printed,
+for Update and Highlight, so it must only be used by one goroutine at a time: the
+pool gives each call to Print a printer of its own, recycling them as they're
+done. Eg:
+
+	pool, err := dotmatrix.NewPrinterPool(nil, func() dotmatrix.Filter {
+		return dotmatrix.FitFilter{Cols: 80, Rows: 24}
+	})
+	...
+	http.HandleFunc("/art", func(w http.ResponseWriter, r *http.Request) {
+		img, _, err := image.Decode(r.Body)
+		...
+		pool.Print(r.Context(), w, img)
+	})
+
+Filters may keep state between images, as LevelsFilter and MotionFilter do, so
+they can't be shared between images either: the pool makes a filter for each
+instead.
+*/
+type PrinterPool struct {
+	c         Config
+	newFilter func() Filter
+	printers  sync.Pool
+}
+
+// NewPrinterPool provides a PrinterPool printing with c. If newFilter is set, it
+// is called for a fresh filter for each image printed, in place of c's filter.
+// It returns an error if c is invalid, or if c holds something that can't be
+// shared between printers: a Recorder, or a filter, which may keep state. Filters
+// must be made by newFilter instead.
+func NewPrinterPool(c *Config, newFilter func() Filter) (*PrinterPool, error) {
+	config := mergeConfig(c)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Recorder != nil {
+		return nil, errors.New("dotmatrix: a Recorder can't be shared by a PrinterPool")
+	}
+	if newFilter == nil && c != nil && c.Filter != nil {
+		return nil, errors.New("dotmatrix: filters may keep state, so can't be shared by a PrinterPool; use newFilter")
+	}
+	return &PrinterPool{c: config, newFilter: newFilter}, nil
+}
+
+// Print prints img to w as Printer.PrintContext does. It may be called from any
+// goroutine.
+func (p *PrinterPool) Print(ctx context.Context, w io.Writer, img image.Image) error {
+	printer, _ := p.printers.Get().(*Printer)
+	if printer == nil {
+		printer = &Printer{c: p.c}
+	}
+	if p.newFilter != nil {
+		printer.c.Filter = p.newFilter()
+	}
+	printer.w = lineWriter(w, p.c.LineEnding)
+	err := printer.PrintContext(ctx, img)
+	// Don't hold on to the writer or image while pooled.
+	printer.w, printer.last, printer.trailing, printer.open = nil, nil, 0, false
+	p.printers.Put(printer)
+	return err
+}
@@ -0,0 +1,60 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("PrinterPool", func() {
+	It("should print from many goroutines at once", func() {
+		var imgs []image.Image
+		for i := 0; i < 8; i++ {
+			img := image.NewGray(image.Rect(0, 0, 16, 16))
+			for x := 0; x < 16; x++ {
+				img.SetGray(x, i, color.Gray{0xff})
+			}
+			imgs = append(imgs, img)
+		}
+		pool, err := dotmatrix.NewPrinterPool(nil, func() dotmatrix.Filter {
+			return &dotmatrix.LevelsFilter{}
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		outs := make([]bytes.Buffer, len(imgs))
+		var wg sync.WaitGroup
+		for i := range imgs {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(pool.Print(context.Background(), &outs[i], imgs[i])).To(Succeed())
+			}(i)
+		}
+		wg.Wait()
+		for i, img := range imgs {
+			var want bytes.Buffer
+			printer := dotmatrix.NewPrinter(&want, &dotmatrix.Config{Filter: &dotmatrix.LevelsFilter{}})
+			Expect(printer.Print(img)).To(Succeed())
+			Expect(outs[i].String()).To(Equal(want.String()))
+		}
+	})
+
+	It("should refuse to share filters, which may keep state", func() {
+		_, err := dotmatrix.NewPrinterPool(&dotmatrix.Config{Filter: &dotmatrix.MotionFilter{}}, nil)
+		Expect(err).To(HaveOccurred())
+		_, err = dotmatrix.NewPrinterPool(&dotmatrix.Config{Filter: dotmatrix.FitFilter{Cols: 10}}, nil)
+		Expect(err).To(HaveOccurred())
+		_, err = dotmatrix.NewPrinterPool(&dotmatrix.Config{Filter: &dotmatrix.MotionFilter{}}, func() dotmatrix.Filter {
+			return &dotmatrix.MotionFilter{}
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,54 @@
+package dotmatrix
+
+import "image"
+
+// BoundsFilter is a Filter that can tell the bounds of the image it would return
+// for an image with the given bounds, without filtering one. PredictSize uses it to
+// size output without the cost, or the side effects, of filtering.
+type BoundsFilter interface {
+	Filter
+	FilterBounds(r image.Rectangle) image.Rectangle
+}
+
+func (noop) FilterBounds(r image.Rectangle) image.Rectangle {
+	return r
+}
+
+// FilterBounds returns the bounds of the carved image, which start at the origin.
+func (f SeamCarveFilter) FilterBounds(r image.Rectangle) image.Rectangle {
+	w, h := r.Dx(), r.Dy()
+	if f.Width > 0 && f.Width < w {
+		w = f.Width
+	}
+	if f.Height > 0 && f.Height < h {
+		h = f.Height
+	}
+	return image.Rect(0, 0, w, h)
+}
+
+/*
+PredictSize returns how many columns and rows of the terminal img takes up when
+printed with c, so that layouts can reserve room for it before it is printed. Any
+frame the flusher draws around the image is counted, but lines printed after it,
+such as alt text, aren't.
+
+The size of the image after filtering is asked of the config's filter if it is a
+BoundsFilter. Otherwise img is filtered to find out, and filters that keep state
+from image to image, such as a MotionFilter, see it as if it was printed.
+*/
+func PredictSize(img image.Image, c *Config) (int, int) {
+	var cfg Config
+	if c != nil {
+		cfg = *c
+	}
+	cfg = mergeConfig(&cfg)
+
+	var bounds image.Rectangle
+	if f, ok := cfg.Filter.(BoundsFilter); ok {
+		bounds = f.FilterBounds(img.Bounds())
+	} else {
+		bounds = cfg.Filter.Filter(img).Bounds()
+	}
+	_, inset := unframe(cfg.Flusher)
+	return (bounds.Dx()+1)/2 + 2*inset, screenRows(bounds, cfg.Flusher)
+}
@@ -0,0 +1,33 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("PredictSize", func() {
+	It("should match the size of what is printed", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 15, 9), color.Palette{color.Black})
+		for _, c := range []*dotmatrix.Config{
+			nil,
+			{Filter: dotmatrix.SeamCarveFilter{Width: 8}},
+			{Flusher: dotmatrix.BorderFlusher{}},
+		} {
+			var out bytes.Buffer
+			Expect(dotmatrix.NewPrinter(&out, c).Print(img)).To(Succeed())
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+			cols, rows := dotmatrix.PredictSize(img, c)
+			Expect(rows).To(Equal(len(lines)))
+			Expect(cols).To(Equal(utf8.RuneCountInString(lines[0])))
+		}
+	})
+})
@@ -0,0 +1,110 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// prerendered is a frame that has already been flushed, so it can be printed in
+// full by writing out.
+type prerendered struct {
+	*image.Paletted
+	out []byte
+}
+
+// prerenderTrack draws and flushes every frame of a track before the first is
+// played.
+type prerenderTrack struct {
+	t track
+	c *Config
+
+	frames []prerenderedFrame
+	next   int // the frame that follows the last one played
+}
+
+type prerenderedFrame struct {
+	img    *prerendered
+	damage image.Rectangle
+	delay  time.Duration
+}
+
+func (t *prerenderTrack) loops() int {
+	return t.t.loops()
+}
+
+func (t *prerenderTrack) length() int {
+	if tl, ok := t.t.(timeline); ok {
+		return tl.length()
+	}
+	return 0
+}
+
+func (t *prerenderTrack) delayOf(i int) time.Duration {
+	if tl, ok := t.t.(timeline); ok {
+		return tl.delayOf(i)
+	}
+	return 0
+}
+
+// Close closes the track being prerendered, if it needs closing.
+func (t *prerenderTrack) Close() error {
+	if c, ok := t.t.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (t *prerenderTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	if t.frames == nil {
+		if err := t.render(ctx); err != nil {
+			return nil, image.Rectangle{}, 0, err
+		}
+	}
+	if i >= len(t.frames) {
+		return nil, image.Rectangle{}, 0, io.EOF
+	}
+	f := t.frames[i]
+	// Damage is relative to the frame before, so frames played out of order are
+	// redrawn in full.
+	if i != t.next && i != 0 {
+		f.damage = f.img.Bounds()
+	}
+	t.next = i + 1
+	return f.img, f.damage, f.delay, nil
+}
+
+// render draws and flushes every frame of the track, keeping copies.
+func (t *prerenderTrack) render(ctx context.Context) error {
+	frames := []prerenderedFrame{}
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		screen, damage, delay, err := t.t.frame(ctx, i)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// The track draws each frame over the last on the same screen, so it's copied.
+		var img *image.Paletted
+		if p, ok := screen.(*image.Paletted); ok {
+			img = copyRegion(p, p.Bounds())
+		} else {
+			img = image.NewPaletted(screen.Bounds(), defaultPalette)
+			draw.Draw(img, img.Bounds(), screen, img.Bounds().Min, draw.Src)
+		}
+		var out bytes.Buffer
+		if err := flush(&out, img, t.c.Flusher); err != nil {
+			return err
+		}
+		frames = append(frames, prerenderedFrame{&prerendered{img, out.Bytes()}, damage, delay})
+	}
+	t.frames = frames
+	return nil
+}
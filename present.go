@@ -0,0 +1,396 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// presenter flushes rendered frames to a writer on behalf of the printers, and
+// resets the cursor between animated frames.
+type presenter struct {
+	w io.Writer
+	c *Config
+
+	last    Fingerprint
+	flushed bool // whether any frame has been flushed yet
+	rows    int  // rows written by the last flush, which need a reset
+	count   int  // frames presented, for stamping
+	height  int  // rows of the last frame flushed in full
+	written int  // bytes written for the last frame
+
+	// damage accumulates the regions changed since the last flush, so that changes
+	// in skipped frames are not lost.
+	damage image.Rectangle
+	// stale is set when what was flushed differs from the frame it was flushed for,
+	// so the damage doesn't cover everything that changed on screen.
+	stale bool
+	// shown is the last frame flushed.
+	shown image.Image
+	// notified is when each event was last notified.
+	notified map[Event]time.Time
+	// screen is a copy of what was last flushed, which partial flushes compare
+	// against. It is only kept for partial flushes.
+	screen *image.Paletted
+	// buf is scratch space for encoding frames, so that each is written at once.
+	buf bytes.Buffer
+	// sync is scratch space for wrapping frames in synchronized updates.
+	sync []byte
+	// untrimmed and trimmed are scratch space for trimming trailing cells.
+	untrimmed bytes.Buffer
+	trimmed   []byte
+	// overwrite is set when frames are printed over earlier ones, whose cells
+	// must be erased where they aren't printed over.
+	overwrite bool
+}
+
+func newPresenter(w io.Writer, c *Config) *presenter {
+	return &presenter{w: w, c: c}
+}
+
+// present reports the frame's fingerprint and flushes it. If the config asks for
+// identical frames to be skipped and img looks the same as the last flushed frame,
+// nothing is written.
+func (p *presenter) present(frame int, img image.Image) error {
+	return p.presentDamage(frame, img, img.Bounds())
+}
+
+// presentDamage is like present, but when the config asks for partial flushes only
+// the cells covering the damaged region of img are rewritten. The first frame is
+// always flushed in full.
+func (p *presenter) presentDamage(frame int, img image.Image, damage image.Rectangle) error {
+	p.rows = 0
+	p.damage = p.damage.Union(damage)
+	n := p.count
+	p.count++
+	var fp Fingerprint
+	if p.c.OnFingerprint != nil || p.c.SkipIdentical {
+		fp = FingerprintOf(img)
+		if p.c.OnFingerprint != nil {
+			p.c.OnFingerprint(frame, fp)
+		}
+		if p.c.SkipIdentical && p.flushed && fp == p.last {
+			return nil
+		}
+	}
+
+	// Stamp after fingerprinting, or else frames that differ only by their stamp
+	// would never be skipped.
+	img, stamped := p.c.Stamp.stamp(img, n)
+	p.damage = p.damage.Union(stamped)
+
+	var (
+		rows int
+		err  error
+	)
+	if p.c.ByteBudget > 0 {
+		var ok bool
+		if rows, ok, err = p.flushBudget(img); !ok {
+			return err
+		}
+	} else {
+		p.buf.Reset()
+		if rows, err = p.flush(&p.buf, img, false); err == nil {
+			err = p.write(&p.buf)
+		}
+	}
+	p.rows = rows
+	p.last = fp
+	p.shown = img
+	p.damage = image.Rectangle{}
+	p.flushed = true
+	if err == nil && p.c.OnFlush != nil {
+		p.c.OnFlush(p.height, p.written)
+	}
+	return err
+}
+
+// skip passes over a frame without flushing it, keeping its damaged region for the
+// next flush.
+func (p *presenter) skip(damage image.Rectangle) {
+	p.damage = p.damage.Union(damage)
+	p.count++
+}
+
+// flush writes img, or if the config asks for partial flushes, only the cells that
+// differ from what is on screen. It returns the number of rows the cursor moved
+// down.
+func (p *presenter) flush(w io.Writer, img image.Image, degraded bool) (int, error) {
+	if !p.c.PartialFlush {
+		return p.flushFull(w, img, degraded)
+	}
+	if p.screen == nil || p.screen.Bounds() != img.Bounds() {
+		rows, err := p.flushFull(w, img, degraded)
+		p.screen = copyImageInto(p.screen, img)
+		return rows, err
+	}
+	region := p.damage
+	if p.stale || degraded {
+		region = img.Bounds()
+	}
+	p.stale = degraded
+	return flushDiff(w, img, p.screen, region, p.c.Flusher)
+}
+
+// flushFull writes all of img.
+func (p *presenter) flushFull(w io.Writer, img image.Image, degraded bool) (int, error) {
+	rows := screenRows(img.Bounds(), p.c.Flusher)
+	if p.flushed && rows < p.height {
+		// Clear what the last frame left below this one.
+		if _, err := io.WriteString(w, "\033[J"); err != nil {
+			return 0, err
+		}
+	}
+	p.stale, p.height = degraded, rows
+	pre, ok := img.(*prerendered)
+	if !p.c.TrimTrailingCells {
+		if ok {
+			_, err := w.Write(pre.out)
+			return rows, err
+		}
+		return rows, flush(w, img, p.c.Flusher)
+	}
+	out := &p.untrimmed
+	out.Reset()
+	if ok {
+		out.Write(pre.out)
+	} else if err := flush(out, img, p.c.Flusher); err != nil {
+		return rows, err
+	}
+	p.trimmed = trimTrailingCells(p.trimmed[:0], out.Bytes(), p.c.Blank, p.overwrite)
+	_, err := w.Write(p.trimmed)
+	return rows, err
+}
+
+// flushBudget flushes img, degraded by the config's budget policy until its output
+// fits within the byte budget. It reports false if the frame was skipped instead.
+func (p *presenter) flushBudget(img image.Image) (int, bool, error) {
+	buf := &p.buf
+	buf.Reset()
+	stale, height := p.stale, p.height
+	var screen *image.Paletted
+	if p.screen != nil {
+		// A flush updates the copy of the screen, so it must be restored if what
+		// was flushed is thrown away.
+		screen = copyRegion(p.screen, p.screen.Bounds())
+	}
+	// The escapes around synchronized updates count against the budget too.
+	budget := p.c.ByteBudget
+	if p.c.SynchronizedOutput {
+		budget -= len(beginSynchronizedUpdate) + len(endSynchronizedUpdate)
+	}
+	rows, err := p.flush(buf, img, false)
+	for err == nil && buf.Len() > budget {
+		p.stale, p.height, p.screen = stale, height, screen
+		if budget <= 0 {
+			return 0, false, nil
+		}
+		if img = p.c.BudgetPolicy.Degrade(img, buf.Len(), budget); img == nil {
+			return 0, false, nil
+		}
+		buf.Reset()
+		rows, err = p.flush(buf, img, true)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return rows, true, p.write(buf)
+}
+
+// Synchronized updates (DEC private mode 2026) have terminals that support them
+// hold off refreshing until the whole update has been written. Others ignore them.
+const (
+	beginSynchronizedUpdate = "\033[?2026h"
+	endSynchronizedUpdate   = "\033[?2026l"
+)
+
+// write writes an encoded frame at once, as a synchronized update if the config
+// asks, so that large frames don't tear mid-refresh.
+func (p *presenter) write(buf *bytes.Buffer) error {
+	if !p.c.SynchronizedOutput {
+		n, err := buf.WriteTo(p.w)
+		p.written = int(n)
+		return err
+	}
+	p.sync = append(p.sync[:0], beginSynchronizedUpdate...)
+	p.sync = append(p.sync, buf.Bytes()...)
+	p.sync = append(p.sync, endSynchronizedUpdate...)
+	buf.Reset()
+	n, err := p.w.Write(p.sync)
+	p.written = n
+	return err
+}
+
+// status writes a line of text below the last frame, which is cleared by the next
+// reset. Partial and skipped flushes may have left the cursor within the frame, so
+// it is moved below the frame first.
+func (p *presenter) status(text string) error {
+	if below := p.height - p.rows; below > 0 {
+		if _, err := fmt.Fprintf(p.w, "\033[%dB", below); err != nil {
+			return err
+		}
+	}
+	p.rows = p.height + 1
+	_, err := fmt.Fprintf(p.w, "\033[2K%s\n", text)
+	return err
+}
+
+// reset moves the cursor back to the top of the last frame so the next one
+// overwrites it. Skipped frames left the cursor where it was, so there is nothing
+// to do for them.
+func (p *presenter) reset() {
+	if p.rows > 0 {
+		p.c.Reset(p.w, p.rows)
+		p.rows = 0
+	}
+}
+
+// cellRows returns the number of braille rows needed to print an image with the
+// given bounds.
+func cellRows(bounds image.Rectangle) int {
+	return (bounds.Dy() + 3) / 4
+}
+
+// copyImageInto copies img in the dotmatrix palette, into dst's pixels if there are
+// enough of them.
+func copyImageInto(dst *image.Paletted, img image.Image) *image.Paletted {
+	if p, ok := img.(*image.Paletted); ok {
+		return copyRegionInto(dst, p, p.Bounds())
+	}
+	dst = reusePaletted(dst, img.Bounds(), defaultPalette)
+	draw.Draw(dst, dst.Bounds(), img, dst.Bounds().Min, draw.Src)
+	return dst
+}
+
+// flushDiff rewrites the braille cells within region where img differs from
+// screen, a copy of what is on screen, and updates screen to match. The cursor
+// is assumed to be at the top left of screen. Changed cells are written in runs,
+// with cursor movements over the unchanged cells between them. It returns the
+// number of rows the cursor moved down, which need to be reset.
+func flushDiff(w io.Writer, img image.Image, screen *image.Paletted, region image.Rectangle, flusher Flusher) (int, error) {
+	bounds := img.Bounds()
+	region = region.Intersect(bounds)
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return screenRows(bounds, flusher), flush(w, img, flusher)
+	}
+	// Runs are printed within any frame, which is left as it is.
+	flusher, inset := unframe(flusher)
+
+	// Moving the cursor over a short gap costs more than rewriting it.
+	const maxGap = 2
+	row, col := 0, 0 // the cursor, in cells relative to the top left
+	run := func(cy, x0, x1 int) error {
+		var moves string
+		if sy := cy + inset; sy > row {
+			moves = fmt.Sprintf("\033[%dB", sy-row)
+		} else if sy < row {
+			moves = fmt.Sprintf("\033[%dA", row-sy)
+		}
+		if sx := x0 + inset; sx > col {
+			moves += fmt.Sprintf("\033[%dC", sx-col)
+		}
+		if _, err := io.WriteString(w, moves); err != nil {
+			return err
+		}
+		r := image.Rect(bounds.Min.X+x0*2, bounds.Min.Y+cy*4, bounds.Min.X+x1*2, bounds.Min.Y+cy*4+4).Intersect(bounds)
+		draw.Draw(screen, r, img, r.Min, draw.Src)
+		// Each flushed row ends with a line feed, which returns the cursor to the
+		// first column of the next row.
+		row, col = cy+inset+1, 0
+		return flush(w, sub.SubImage(r), flusher)
+	}
+
+	// The region, expanded to whole cells relative to the image's origin.
+	x0 := (region.Min.X - bounds.Min.X) / 2
+	y0 := (region.Min.Y - bounds.Min.Y) / 4
+	x1 := (region.Max.X - bounds.Min.X + 1) / 2
+	y1 := (region.Max.Y - bounds.Min.Y + 3) / 4
+	for cy := y0; cy < y1; cy++ {
+		start, end := -1, -1 // the run of changed cells being gathered
+		for cx := x0; cx < x1; cx++ {
+			cell := image.Rect(bounds.Min.X+cx*2, bounds.Min.Y+cy*4, bounds.Min.X+cx*2+2, bounds.Min.Y+cy*4+4).Intersect(bounds)
+			if cellChanged(img, screen, cell) {
+				if start < 0 {
+					start = cx
+				}
+				end = cx + 1
+				continue
+			}
+			if start >= 0 && cx-end >= maxGap {
+				if err := run(cy, start, end); err != nil {
+					return row, err
+				}
+				start = -1
+			}
+		}
+		if start >= 0 {
+			if err := run(cy, start, end); err != nil {
+				return row, err
+			}
+		}
+	}
+	return row, nil
+}
+
+// cellChanged reports whether any pixel of cell differs between img and screen.
+func cellChanged(img image.Image, screen *image.Paletted, cell image.Rectangle) bool {
+	for y := cell.Min.Y; y < cell.Max.Y; y++ {
+		for x := cell.Min.X; x < cell.Max.X; x++ {
+			if img.At(x, y) != screen.At(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flushRegion rewrites the braille cells of img that cover region, assuming the
+// cursor is at the top left of a previously flushed copy of img. It returns the
+// number of rows the cursor moved down, which need to be reset.
+func flushRegion(w io.Writer, img image.Image, region image.Rectangle, flusher Flusher) (int, error) {
+	bounds := img.Bounds()
+	region = region.Intersect(bounds)
+	if region.Empty() {
+		return 0, nil
+	}
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return screenRows(bounds, flusher), flush(w, img, flusher)
+	}
+	// Runs are printed within any frame, which is left as it is.
+	flusher, inset := unframe(flusher)
+
+	// Expand the region to whole cells, relative to the image's origin.
+	x0 := (region.Min.X - bounds.Min.X) / 2
+	y0 := (region.Min.Y - bounds.Min.Y) / 4
+	x1 := (region.Max.X - bounds.Min.X + 1) / 2
+	y1 := (region.Max.Y - bounds.Min.Y + 3) / 4
+
+	if y0+inset > 0 {
+		if _, err := fmt.Fprintf(w, "\033[%dB", y0+inset); err != nil {
+			return 0, err
+		}
+	}
+	// Each flushed row ends with a line feed, which returns the cursor to the first
+	// column of the next row.
+	for cy := y0; cy < y1; cy++ {
+		if x0+inset > 0 {
+			if _, err := fmt.Fprintf(w, "\033[%dC", x0+inset); err != nil {
+				return cy + inset, err
+			}
+		}
+		row := image.Rect(bounds.Min.X+x0*2, bounds.Min.Y+cy*4, bounds.Min.X+x1*2, bounds.Min.Y+cy*4+4)
+		if err := flush(w, sub.SubImage(row.Intersect(bounds)), flusher); err != nil {
+			return cy + inset, err
+		}
+	}
+	return y1 + inset, nil
+}
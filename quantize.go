@@ -0,0 +1,87 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+/*
+Quantizer decides which pixels of a filtered image are printed as dots. Printers
+quantize each image into the dotmatrix palette, in which black pixels are dots,
+and white and transparent pixels are blanks.
+
+Quantizers are called from several goroutines at once when animations are decoded
+ahead (see Config.DecodeAhead), so those that keep state must guard it.
+*/
+type Quantizer interface {
+	// Quantize draws src into dst, an image of the same size in the dotmatrix
+	// palette. Pixels of dst hold whatever they held before, so each must be set.
+	Quantize(dst *image.Paletted, src image.Image)
+}
+
+// DrawerQuantizer quantizes images by drawing them with Drawer in Palette, as
+// printers do by default with Config.Drawer and Config.Palette.
+type DrawerQuantizer struct {
+	// Drawer draws images in the palette. The default is draw.FloydSteinberg.
+	Drawer draw.Drawer
+	// Palette is drawn in, if set, in place of the dotmatrix palette. See
+	// Config.Palette.
+	Palette color.Palette
+}
+
+func (q DrawerQuantizer) Quantize(dst *image.Paletted, src image.Image) {
+	drawer := q.Drawer
+	if drawer == nil {
+		drawer = draw.FloydSteinberg
+	}
+	if rgba := fastSource(src, drawer); rgba != nil {
+		defer rgbaPool.Put(rgba)
+		src = rgba
+	}
+	if len(q.Palette) == 0 {
+		if !ditherMono(dst, src, drawer) {
+			drawer.Draw(dst, dst.Bounds(), src, src.Bounds().Min)
+		}
+		return
+	}
+	dst.Palette = q.Palette
+	drawer.Draw(dst, dst.Bounds(), src, src.Bounds().Min)
+	toDefaultPalette(dst)
+}
+
+// ThresholdQuantizer prints each pixel darker than Level as a dot, without
+// dithering, once composited over white. It keeps lines and text crisp, but flattens
+// gradients.
+type ThresholdQuantizer struct {
+	// Level is the gray level, out of 0xff, below which pixels are dots. The zero
+	// value is treated as 0x80, as IsDot does.
+	Level uint8
+}
+
+func (q ThresholdQuantizer) Quantize(dst *image.Paletted, src image.Image) {
+	level := q.Level
+	if level == 0 {
+		level = 0x80
+	}
+	bounds, sp := dst.Bounds(), src.Bounds().Min
+	for y := 0; y < bounds.Dy(); y++ {
+		pix := dst.Pix[y*dst.Stride:][:bounds.Dx()]
+		for x := range pix {
+			if luminance(src.At(sp.X+x, sp.Y+y)) < level {
+				pix[x] = 0 // black
+			} else {
+				pix[x] = 1 // white
+			}
+		}
+	}
+}
+
+// quantizer returns the config's quantizer, or one drawing with its drawer and
+// palette.
+func (c *Config) quantizer() Quantizer {
+	if c.Quantizer != nil {
+		return c.Quantizer
+	}
+	return DrawerQuantizer{Drawer: c.Drawer, Palette: c.Palette}
+}
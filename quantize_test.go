@@ -0,0 +1,42 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// allDots quantizes every pixel to a dot.
+type allDots struct{}
+
+func (allDots) Quantize(dst *image.Paletted, src image.Image) {
+	for i := range dst.Pix {
+		dst.Pix[i] = 0
+	}
+}
+
+var _ = Describe("Quantizer", func() {
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 0x40)})
+		}
+	}
+	printed := func(c *dotmatrix.Config) string {
+		var out bytes.Buffer
+		Expect(dotmatrix.NewPrinter(&out, c).Print(img)).To(Succeed())
+		return out.String()
+	}
+
+	It("should decide which pixels are dots in place of the drawer", func() {
+		Expect(printed(&dotmatrix.Config{Quantizer: allDots{}})).To(Equal("⣿⣿\n"))
+		Expect(printed(&dotmatrix.Config{Quantizer: dotmatrix.ThresholdQuantizer{}})).To(Equal("⣿⠀\n"))
+		Expect(printed(&dotmatrix.Config{Quantizer: dotmatrix.ThresholdQuantizer{Level: 0xff}})).To(Equal("⣿⣿\n"))
+		Expect(printed(&dotmatrix.Config{Quantizer: dotmatrix.DrawerQuantizer{}})).To(Equal(printed(nil)))
+	})
+})
@@ -0,0 +1,98 @@
+package dotmatrix
+
+import (
+	"errors"
+	"image"
+	"io"
+	"time"
+)
+
+// RawFormat is the pixel format of raw video.
+type RawFormat int
+
+const (
+	// RawGray8 is a byte per pixel of gray, as in ffmpeg's gray pixel format.
+	RawGray8 RawFormat = iota
+	// RawRGB24 is a byte each of red, green and blue per pixel, as in ffmpeg's
+	// rgb24 pixel format.
+	RawRGB24
+)
+
+// bytesPerPixel returns how many bytes encode each pixel.
+func (f RawFormat) bytesPerPixel() int {
+	if f == RawRGB24 {
+		return 3
+	}
+	return 1
+}
+
+var errRawSize = errors.New("dotmatrix: raw video frames must have a positive width and height")
+
+/*
+RawSource reads raw video, frames of pixels with no headers or containers between
+them, such as ffmpeg writes with "-f rawvideo". Anything ffmpeg can decode can be
+piped in:
+
+	ffmpeg -i video.mp4 -f rawvideo -pix_fmt gray - | dotmatrix --raw gray8 --size 640x360
+*/
+type RawSource struct {
+	r      io.Reader
+	format RawFormat
+	width  int
+	height int
+	delay  time.Duration
+
+	buf []byte
+	img image.Image
+}
+
+// NewRawSource returns a RawSource that reads frames of the given format and
+// size from r. If fps is greater than zero, frames are shown fps times a second.
+// Otherwise they are shown as quickly as they are read.
+func NewRawSource(r io.Reader, format RawFormat, width, height, fps int) (*RawSource, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errRawSize
+	}
+	s := &RawSource{
+		r:      r,
+		format: format,
+		width:  width,
+		height: height,
+		buf:    make([]byte, width*height*format.bytesPerPixel()),
+	}
+	if fps > 0 {
+		s.delay = time.Second / time.Duration(fps)
+	}
+	return s, nil
+}
+
+// NextFrame reads the next frame. A frame cut short by the end of the video is
+// dropped.
+func (s *RawSource) NextFrame() (image.Image, time.Duration, error) {
+	if _, err := io.ReadFull(s.r, s.buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, 0, err
+	}
+
+	switch s.format {
+	case RawRGB24:
+		img, ok := s.img.(*image.RGBA)
+		if !ok {
+			img = image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+			s.img = img
+		}
+		for i, j := 0, 0; i < len(s.buf); i, j = i+3, j+4 {
+			img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = s.buf[i], s.buf[i+1], s.buf[i+2], 0xff
+		}
+	default:
+		img, ok := s.img.(*image.Gray)
+		if !ok {
+			img = image.NewGray(image.Rect(0, 0, s.width, s.height))
+			s.img = img
+		}
+		copy(img.Pix, s.buf)
+	}
+	return s.img, s.delay, nil
+}
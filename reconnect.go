@@ -0,0 +1,165 @@
+package dotmatrix
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+/*
+ReconnectingReader reads a stream that reopens itself when it drops, such as a
+network camera's mjpeg stream, so that a network hiccup doesn't end a long-running
+display. Failed attempts to reopen are retried with exponential backoff until the
+reader is closed or its context is done.
+
+Open is passed how many bytes have been read so far, so that streams that can,
+such as files served over HTTP, resume where they dropped. Live streams just start
+again from wherever they are. Reading ends when a stream ends with io.EOF; a stream
+that ends early must say so with another error, such as io.ErrUnexpectedEOF.
+*/
+type ReconnectingReader struct {
+	// Backoff is the wait before the first attempt to reopen the stream, doubled for
+	// each attempt that fails. The default is half a second.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between attempts. The default is 30 seconds.
+	MaxBackoff time.Duration
+	// OnReconnect, if set, is called from the reading goroutine each time the stream
+	// is reopened after dropping.
+	OnReconnect func()
+	// OnDrop, if set, is called from the reading goroutine with the reason each time
+	// the stream drops, or an attempt to reopen it fails.
+	OnDrop func(error)
+
+	open   func(ctx context.Context, offset int64) (io.ReadCloser, error)
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	rc     io.ReadCloser // nil until opened, and after dropping
+	opened bool
+	offset int64
+}
+
+// NewReconnectingReader returns a ReconnectingReader that opens its stream with
+// open, the first time it is read.
+func NewReconnectingReader(ctx context.Context, open func(ctx context.Context, offset int64) (io.ReadCloser, error)) *ReconnectingReader {
+	ctx, cancel := context.WithCancel(ctx)
+	return &ReconnectingReader{open: open, ctx: ctx, cancel: cancel}
+}
+
+func (r *ReconnectingReader) Read(p []byte) (int, error) {
+	for {
+		r.mu.Lock()
+		rc := r.rc
+		r.mu.Unlock()
+		if rc == nil {
+			var err error
+			if rc, err = r.connect(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := rc.Read(p)
+		r.mu.Lock()
+		r.offset += int64(n)
+		r.mu.Unlock()
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if r.ctx.Err() != nil {
+			return n, r.ctx.Err()
+		}
+		r.drop(rc, err)
+		// The bytes read before the stream dropped are good, and the next read
+		// reconnects.
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// connect opens the stream, or reopens it with backoff if it has been opened
+// before.
+func (r *ReconnectingReader) connect() (io.ReadCloser, error) {
+	r.mu.Lock()
+	offset, reconnecting := r.offset, r.opened
+	r.mu.Unlock()
+
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 || reconnecting {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-r.ctx.Done():
+				timer.Stop()
+				return nil, r.ctx.Err()
+			case <-timer.C:
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		rc, err := r.open(r.ctx, offset)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return nil, r.ctx.Err()
+			}
+			// A stream that can't be opened in the first place is an error, rather
+			// than a hiccup to wait out.
+			if !reconnecting {
+				return nil, err
+			}
+			if r.OnDrop != nil {
+				r.OnDrop(err)
+			}
+			continue
+		}
+
+		r.mu.Lock()
+		if r.ctx.Err() != nil {
+			r.mu.Unlock()
+			rc.Close()
+			return nil, r.ctx.Err()
+		}
+		r.rc, r.opened = rc, true
+		r.mu.Unlock()
+		if reconnecting && r.OnReconnect != nil {
+			r.OnReconnect()
+		}
+		return rc, nil
+	}
+}
+
+// drop closes a stream that failed with err, so that the next read reconnects.
+func (r *ReconnectingReader) drop(rc io.ReadCloser, err error) {
+	r.mu.Lock()
+	if r.rc == rc {
+		r.rc = nil
+	}
+	r.mu.Unlock()
+	rc.Close()
+	if r.OnDrop != nil {
+		r.OnDrop(err)
+	}
+}
+
+// Close stops reading, interrupting any read or wait to reconnect in progress.
+func (r *ReconnectingReader) Close() error {
+	r.cancel()
+	r.mu.Lock()
+	rc := r.rc
+	r.rc = nil
+	r.mu.Unlock()
+	if rc != nil {
+		return rc.Close()
+	}
+	return nil
+}
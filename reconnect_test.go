@@ -0,0 +1,57 @@
+package dotmatrix_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// flaky reads at most n bytes of r, then fails as a dropped connection would.
+type flaky struct {
+	r io.Reader
+	n int
+}
+
+func (f *flaky) Read(p []byte) (int, error) {
+	if f.n == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= n
+	return n, err
+}
+
+func (f *flaky) Close() error { return nil }
+
+var _ = Describe("ReconnectingReader", func() {
+	It("should resume where the stream dropped", func() {
+		const data = "a stream that drops every few bytes"
+		attempts := 0
+		r := dotmatrix.NewReconnectingReader(context.Background(), func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+			attempts++
+			if attempts%3 == 0 {
+				return nil, errors.New("connection refused")
+			}
+			return &flaky{r: strings.NewReader(data[offset:]), n: 8}, nil
+		})
+		r.Backoff = time.Millisecond
+		reconnects := 0
+		r.OnReconnect = func() { reconnects++ }
+
+		read, err := ioutil.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(read)).To(Equal(data))
+		Expect(reconnects).To(Equal(len(data) / 8))
+	})
+})
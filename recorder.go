@@ -0,0 +1,121 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"image"
+	"io"
+	"time"
+)
+
+// recordingMagic begins every recording, followed by the format's version.
+const recordingMagic = "DMREC\x01"
+
+/*
+Recorder captures rendered frames to a compact file, so that animations can be
+rendered once on a fast machine and replayed on constrained devices, without
+decoding, scaling or dithering. Set Config.Recorder to record what printers and
+players print. Each frame is recorded in full, as the config's Flusher renders it,
+whatever partial flushing or skipping happens on screen.
+
+A recording begins with a header:
+
+	"DMREC" and a version byte of 1
+	uvarint columns and rows of the first frame, in characters
+	uvarint length of the renderer's name, and the name
+
+followed by a DEFLATE compressed stream of frames, each of which is:
+
+	uvarint delay, in milliseconds
+	uvarint length of the text, and the text
+*/
+type Recorder struct {
+	w        io.Writer
+	renderer string
+
+	fw      *flate.Writer // nil until the header is written
+	started bool
+	closed  bool
+	buf     bytes.Buffer
+	err     error
+}
+
+// NewRecorder returns a Recorder that writes to w. The renderer names what the
+// frames were rendered for, such as a profile, so that players can check they
+// can show them.
+func NewRecorder(w io.Writer, renderer string) *Recorder {
+	return &Recorder{w: w, renderer: renderer}
+}
+
+// Record adds a frame of rendered text, shown for delay, to the recording. The
+// size of the first frame is written in the header.
+func (r *Recorder) Record(text []byte, cols, rows int, delay time.Duration) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.closed {
+		return io.ErrClosedPipe
+	}
+	if r.err = r.start(cols, rows); r.err != nil {
+		return r.err
+	}
+	var n [2 * binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(n[:], uint64(delay/time.Millisecond))
+	l += binary.PutUvarint(n[l:], uint64(len(text)))
+	if _, r.err = r.fw.Write(n[:l]); r.err != nil {
+		return r.err
+	}
+	_, r.err = r.fw.Write(text)
+	return r.err
+}
+
+// record renders img with the config's flusher and records it.
+func (r *Recorder) record(img image.Image, delay time.Duration, c *Config) error {
+	r.buf.Reset()
+	if err := flush(&r.buf, img, c.Flusher); err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	_, inset := unframe(c.Flusher)
+	return r.Record(r.buf.Bytes(), (bounds.Dx()+1)/2+2*inset, screenRows(bounds, c.Flusher), delay)
+}
+
+// start writes the header, if it hasn't been written already.
+func (r *Recorder) start(cols, rows int) error {
+	if r.started {
+		return nil
+	}
+	r.started = true
+	header := []byte(recordingMagic)
+	var n [binary.MaxVarintLen64]byte
+	for _, v := range []int{cols, rows, len(r.renderer)} {
+		header = append(header, n[:binary.PutUvarint(n[:], uint64(v))]...)
+	}
+	header = append(header, r.renderer...)
+	if _, err := r.w.Write(header); err != nil {
+		return err
+	}
+	fw, err := flate.NewWriter(r.w, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	r.fw = fw
+	return nil
+}
+
+// Close finishes the recording. It does not close the underlying writer.
+func (r *Recorder) Close() error {
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if r.err != nil {
+		return r.err
+	}
+	if r.err = r.start(0, 0); r.err != nil {
+		return r.err
+	}
+	r.err = r.fw.Close()
+	return r.err
+}
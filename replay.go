@@ -0,0 +1,156 @@
+package dotmatrix
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"time"
+)
+
+// Recording is a recording read back, with every frame already rendered.
+type Recording struct {
+	// Cols and Rows are the size of the first frame, in characters.
+	Cols, Rows int
+	// Renderer names what the frames were rendered for.
+	Renderer string
+	Frames   []RecordedFrame
+}
+
+// RecordedFrame is a frame of rendered text, and how long to show it for.
+type RecordedFrame struct {
+	Text  []byte
+	Delay time.Duration
+}
+
+var errInvalidRecording = errors.New("dotmatrix: invalid recording")
+
+// ReadRecording reads a recording written by a Recorder.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != recordingMagic {
+		return nil, errInvalidRecording
+	}
+	var header [3]uint64
+	for i := range header {
+		v, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, errInvalidRecording
+		}
+		header[i] = v
+	}
+	renderer := make([]byte, header[2])
+	if _, err := io.ReadFull(br, renderer); err != nil {
+		return nil, errInvalidRecording
+	}
+	rec := &Recording{Cols: int(header[0]), Rows: int(header[1]), Renderer: string(renderer)}
+
+	frames := bufio.NewReader(flate.NewReader(br))
+	for {
+		delay, err := binary.ReadUvarint(frames)
+		if err == io.EOF {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, errInvalidRecording
+		}
+		n, err := binary.ReadUvarint(frames)
+		if err != nil {
+			return nil, errInvalidRecording
+		}
+		text := make([]byte, n)
+		if _, err := io.ReadFull(frames, text); err != nil {
+			return nil, errInvalidRecording
+		}
+		rec.Frames = append(rec.Frames, RecordedFrame{Text: text, Delay: time.Duration(delay) * time.Millisecond})
+	}
+}
+
+// ReplayPrinter plays recordings. Frames are written exactly as they were recorded,
+// so the config's filters, drawer and flusher don't apply, and neither do partial
+// flushes, skipping identical frames, byte budgets or stamps, which all need the
+// frames' dots. Timing, looping, stepping, seeking and bell notifications work as
+// for any animation.
+type ReplayPrinter struct {
+	w io.Writer
+	c Config
+}
+
+func NewReplayPrinter(w io.Writer, c *Config) *ReplayPrinter {
+	config := mergeConfig(c)
+	config.PartialFlush, config.SkipIdentical, config.ByteBudget = false, false, 0
+	config.Stamp, config.Recorder = Stamp{}, nil
+	return &ReplayPrinter{
+		w: w,
+		c: config,
+	}
+}
+
+// Print plays a recording read from r.
+func (p *ReplayPrinter) Print(ctx context.Context, r io.Reader) error {
+	rec, err := ReadRecording(r)
+	if err != nil {
+		return err
+	}
+	return newPlayer(p.w, &p.c, newReplayTrack(rec, &p.c)).Play(ctx)
+}
+
+// NewReplayPlayer provides a Player for rec, which plays it as a ReplayPrinter
+// would.
+func NewReplayPlayer(w io.Writer, c *Config, rec *Recording) *Player {
+	p := NewReplayPrinter(w, c)
+	return newPlayer(p.w, &p.c, newReplayTrack(rec, &p.c))
+}
+
+// replayTrack plays the frames of a recording as prerendered frames, whose images
+// are blank but the size of the text, so that the screen is cleared and reset by
+// the right number of rows.
+type replayTrack struct {
+	rec    *Recording
+	c      *Config
+	blanks map[image.Rectangle]*image.Paletted
+}
+
+func newReplayTrack(rec *Recording, c *Config) *replayTrack {
+	return &replayTrack{rec: rec, c: c, blanks: map[image.Rectangle]*image.Paletted{}}
+}
+
+// loops follows the config's loop count if it has one. Recordings are otherwise
+// played once.
+func (t *replayTrack) loops() int {
+	switch n := t.c.LoopCount; {
+	case n < 0:
+		return 0
+	case n > 0:
+		return n
+	default:
+		return 1
+	}
+}
+
+func (t *replayTrack) length() int {
+	return len(t.rec.Frames)
+}
+
+func (t *replayTrack) delayOf(i int) time.Duration {
+	return t.rec.Frames[i].Delay
+}
+
+func (t *replayTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	if i >= len(t.rec.Frames) {
+		return nil, image.Rectangle{}, 0, io.EOF
+	}
+	f := t.rec.Frames[i]
+	bounds := image.Rect(0, 0, t.rec.Cols*2, bytes.Count(f.Text, []byte("\n"))*4)
+	blank, ok := t.blanks[bounds]
+	if !ok {
+		blank = image.NewPaletted(bounds, defaultPalette)
+		t.blanks[bounds] = blank
+	}
+	return &prerendered{blank, f.Text}, bounds, f.Delay, nil
+}
@@ -0,0 +1,83 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"image"
+	"io"
+)
+
+// Rows prints img a row of cells at a time, passing each line to row without its
+// line ending, until row returns an error. This lets servers stream tall images
+// without holding all of their output, and lets TUIs paint rows wherever they like
+// on screen. Lines are flushed with the config's flusher, but without any frame it
+// draws around the image. Each line is only valid until row returns.
+func (p *Printer) Rows(img image.Image, row func(line []byte) error) error {
+	if err := p.c.Validate(); err != nil {
+		return err
+	}
+	r := newRowReader(redraw(img, &p.c), &p.c)
+	for {
+		line, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := row(bytes.TrimRight(line, "\r\n")); err != nil {
+			return err
+		}
+	}
+}
+
+// NewReader returns a reader of img as printed with c, which flushes a row of
+// cells at a time as it is read. See Printer.Rows.
+func NewReader(img image.Image, c *Config) io.Reader {
+	cfg := mergeConfig(c)
+	if err := cfg.Validate(); err != nil {
+		return &rowReader{err: err}
+	}
+	return newRowReader(redraw(img, &cfg), &cfg)
+}
+
+// rowReader flushes a drawn image a row of cells at a time.
+type rowReader struct {
+	img     *image.Paletted
+	flusher Flusher
+	y       int // the top of the next row to flush
+	buf     bytes.Buffer
+	err     error
+}
+
+func newRowReader(img *image.Paletted, c *Config) *rowReader {
+	flusher, _ := unframe(c.Flusher)
+	return &rowReader{img: img, flusher: flusher, y: img.Bounds().Min.Y}
+}
+
+// next flushes the next row and returns it, or io.EOF after the last.
+func (r *rowReader) next() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	bounds := r.img.Bounds()
+	if r.y >= bounds.Max.Y {
+		return nil, io.EOF
+	}
+	r.buf.Reset()
+	row := image.Rect(bounds.Min.X, r.y, bounds.Max.X, r.y+4).Intersect(bounds)
+	if err := r.flusher.Flush(&r.buf, r.img.SubImage(row)); err != nil {
+		r.err = err
+		return nil, err
+	}
+	r.y += 4
+	return r.buf.Bytes(), nil
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if _, err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	return r.buf.Read(p)
+}
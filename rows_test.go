@@ -0,0 +1,38 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Rows", func() {
+	img := image.NewPaletted(image.Rect(0, 0, 7, 13), color.Palette{color.White, color.Black})
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i % 2)
+	}
+
+	It("should print a row at a time", func() {
+		var printed bytes.Buffer
+		Expect(dotmatrix.Print(&printed, img)).To(Succeed())
+
+		var lines []string
+		Expect(dotmatrix.NewPrinter(ioutil.Discard, nil).Rows(img, func(line []byte) error {
+			lines = append(lines, string(line))
+			return nil
+		})).To(Succeed())
+		Expect(lines).To(HaveLen(4))
+		Expect(strings.Join(lines, "\n") + "\n").To(Equal(printed.String()))
+
+		read, err := ioutil.ReadAll(dotmatrix.NewReader(img, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(read)).To(Equal(printed.String()))
+	})
+})
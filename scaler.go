@@ -0,0 +1,165 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+// Scaler resamples images to a new size. Printers only scale images when fitting
+// them into a limited space, such as an Alert box or a ContactSheet tile, and
+// then with the config's Scaler. A FitFilter fits images to a terminal the same
+// way. Custom scalers can use better resampling or hand the work to the GPU or an
+// external tool.
+type Scaler interface {
+	// Scale returns img resampled to width by height pixels.
+	Scale(img image.Image, width, height int) image.Image
+}
+
+// ScalerFunc adapts a function to the Scaler interface.
+type ScalerFunc func(img image.Image, width, height int) image.Image
+
+func (f ScalerFunc) Scale(img image.Image, width, height int) image.Image {
+	return f(img, width, height)
+}
+
+// Built in scalers. NearestNeighbor is the fastest and keeps dithered and pixel art
+// crisp, while the others average pixels together and suit photographs and text.
+var (
+	NearestNeighbor Scaler = resizeScaler(resize.NearestNeighbor)
+	Bilinear        Scaler = resizeScaler(resize.Bilinear)
+	Lanczos         Scaler = resizeScaler(resize.Lanczos3)
+)
+
+func resizeScaler(interp resize.InterpolationFunction) Scaler {
+	return ScalerFunc(func(img image.Image, width, height int) image.Image {
+		return resize.Resize(uint(width), uint(height), img, interp)
+	})
+}
+
+/*
+FitFilter shrinks images with a Scaler to fit within Cols by Rows braille cells,
+such as the size of a terminal, keeping their proportions. Images are never
+enlarged. Either of Cols and Rows that is zero doesn't limit the fit, so images can
+be fit to the width of a terminal alone, however many rows that takes. Eg:
+
+	c := &dotmatrix.Config{Scaler: dotmatrix.Lanczos}
+	hint := dotmatrix.SizeHintOf(os.Stdout)
+	c.Filter = dotmatrix.FitFilter{Cols: hint.Cols, Rows: hint.Rows - 1, Scaler: c.Scaler}
+	dotmatrix.NewPrinter(os.Stdout, c).Print(img)
+*/
+type FitFilter struct {
+	Cols, Rows int
+	// DotAspect is how many times taller than wide the terminal draws each dot.
+	// Images are squashed vertically to make up for it, so that their proportions
+	// are kept on screen. Zero is taken as square dots.
+	DotAspect float64
+	// Scaler resamples the images, and should usually be the config's Scaler. The
+	// default is NearestNeighbor.
+	Scaler Scaler
+}
+
+func (f FitFilter) Filter(img image.Image) image.Image {
+	bounds := img.Bounds()
+	fitted := f.FilterBounds(bounds)
+	if fitted.Size() == bounds.Size() {
+		return img
+	}
+	scaler := f.Scaler
+	if scaler == nil {
+		scaler = NearestNeighbor
+	}
+	return scaler.Scale(img, fitted.Dx(), fitted.Dy())
+}
+
+// FilterBounds returns the bounds that images with bounds r are scaled to, without
+// scaling one.
+func (f FitFilter) FilterBounds(r image.Rectangle) image.Rectangle {
+	scaleX, scaleY := f.Scale(r.Dx(), r.Dy())
+	return image.Rect(0, 0, int(scaleX*float64(r.Dx())), int(scaleY*float64(r.Dy())))
+}
+
+// Scale returns the horizontal and vertical factors that images dx by dy pixels
+// are scaled by.
+func (f FitFilter) Scale(dx, dy int) (float64, float64) {
+	aspect := f.DotAspect
+	if aspect <= 0 {
+		aspect = 1
+	}
+	// Images are fit as they will look once squashed.
+	squashed := float64(dy) / aspect
+	scale := 1.0
+	if s := float64(f.Cols*2) / float64(dx); f.Cols > 0 && dx > 0 && s < scale {
+		scale = s
+	}
+	if s := float64(f.Rows*4) / squashed; f.Rows > 0 && dy > 0 && s < scale {
+		scale = s
+	}
+	return scale, scale / aspect
+}
+
+// CommandScaler scales images by running an external tool, such as ImageMagick.
+// The image is written to the command's standard input as a PNG, and the command
+// must write the scaled image to its standard output in any format registered
+// with the image package. The placeholders {width} and {height} in Args are
+// replaced with the size wanted, eg:
+//
+//	CommandScaler{Name: "convert", Args: []string{"-", "-resize", "{width}x{height}!", "png:-"}}
+type CommandScaler struct {
+	Name string
+	Args []string
+	// Fallback scales images when the command fails. The default is
+	// NearestNeighbor.
+	Fallback Scaler
+	// OnError, if set, is called with the reason the command failed.
+	OnError func(error)
+}
+
+func (s CommandScaler) Scale(img image.Image, width, height int) image.Image {
+	scaled, err := s.run(img, width, height)
+	if err == nil {
+		return scaled
+	}
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+	fallback := s.Fallback
+	if fallback == nil {
+		fallback = NearestNeighbor
+	}
+	return fallback.Scale(img, width, height)
+}
+
+func (s CommandScaler) run(img image.Image, width, height int) (image.Image, error) {
+	var in bytes.Buffer
+	if err := png.Encode(&in, img); err != nil {
+		return nil, err
+	}
+	size := strings.NewReplacer("{width}", strconv.Itoa(width), "{height}", strconv.Itoa(height))
+	args := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = size.Replace(arg)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.Name, args...)
+	cmd.Stdin = &in
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		return nil, fmt.Errorf("scaler: %v: %s", err, msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scaler: %v", err)
+	}
+	scaled, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("scaler: %v", err)
+	}
+	return scaled, nil
+}
@@ -0,0 +1,31 @@
+package dotmatrix_test
+
+import (
+	"image"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("FitFilter", func() {
+	It("should shrink images to fit, keeping their proportions", func() {
+		var scaled image.Point
+		scaler := dotmatrix.ScalerFunc(func(img image.Image, width, height int) image.Image {
+			scaled = image.Pt(width, height)
+			return image.NewGray(image.Rect(0, 0, width, height))
+		})
+		fit := dotmatrix.FitFilter{Cols: 10, Rows: 10, Scaler: scaler}
+		Expect(fit.Filter(image.NewGray(image.Rect(0, 0, 40, 20))).Bounds()).To(Equal(image.Rect(0, 0, 20, 10)))
+		Expect(scaled).To(Equal(image.Pt(20, 10)))
+
+		fit.DotAspect = 2
+		Expect(fit.FilterBounds(image.Rect(0, 0, 10, 160))).To(Equal(image.Rect(0, 0, 5, 40)))
+
+		// Images are never enlarged, and a zero dimension doesn't limit the fit.
+		small := image.NewGray(image.Rect(0, 0, 4, 4))
+		Expect(dotmatrix.FitFilter{Cols: 10, Rows: 10}.Filter(small)).To(BeIdenticalTo(small))
+		Expect(dotmatrix.FitFilter{Cols: 10}.FilterBounds(image.Rect(0, 0, 40, 400))).To(Equal(image.Rect(0, 0, 20, 200)))
+	})
+})
@@ -0,0 +1,152 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+)
+
+// SeamCarveFilter shrinks images to at most Width by Height pixels by repeatedly
+// removing the connected seam of pixels with the least detail, rather than scaling
+// uniformly. The subjects of an image keep their proportions while empty space is
+// squeezed out, which suits fitting wide images into narrow terminals. A zero
+// Width or Height leaves that dimension unchanged.
+type SeamCarveFilter struct {
+	Width, Height int
+}
+
+func (f SeamCarveFilter) Filter(img image.Image) image.Image {
+	return SeamCarve(img, f.Width, f.Height)
+}
+
+// SeamCarve removes vertical seams from img until it is at most width pixels wide,
+// then horizontal seams until it is at most height pixels tall. Carving costs time
+// proportional to the number of seams removed, so images much larger than the
+// target should be scaled down first. The result's bounds start at the origin.
+func SeamCarve(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if width <= 0 || width > w {
+		width = w
+	}
+	if height <= 0 || height > h {
+		height = h
+	}
+
+	pix := make([][]color.NRGBA, h)
+	for y := range pix {
+		pix[y] = make([]color.NRGBA, w)
+		for x := range pix[y] {
+			pix[y][x] = color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+		}
+	}
+
+	for len(pix) > 0 && len(pix[0]) > width {
+		pix = removeSeam(pix)
+	}
+	if len(pix) > height {
+		pix = transpose(pix)
+		for len(pix[0]) > height {
+			pix = removeSeam(pix)
+		}
+		pix = transpose(pix)
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y, row := range pix {
+		for x, c := range row {
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+// removeSeam removes the vertical seam with the least energy from pix, shortening
+// every row by one pixel.
+func removeSeam(pix [][]color.NRGBA) [][]color.NRGBA {
+	h, w := len(pix), len(pix[0])
+
+	lum := make([][]float64, h)
+	for y, row := range pix {
+		lum[y] = make([]float64, w)
+		for x, c := range row {
+			// Transparent areas count as white, and so as featureless.
+			lum[y][x] = float64(luminance(c))
+		}
+	}
+	at := func(x, y int) float64 {
+		if x < 0 {
+			x = 0
+		}
+		if x >= w {
+			x = w - 1
+		}
+		if y < 0 {
+			y = 0
+		}
+		if y >= h {
+			y = h - 1
+		}
+		return lum[y][x]
+	}
+
+	// cost[y][x] is the least total energy of any seam from the top row to (x, y).
+	cost := make([][]float64, h)
+	for y := 0; y < h; y++ {
+		cost[y] = make([]float64, w)
+		for x := 0; x < w; x++ {
+			// Differences are taken with each neighbor, rather than between them, so
+			// that lines a pixel thick count as detail.
+			c := at(x, y)
+			energy := abs(at(x-1, y)-c) + abs(c-at(x+1, y)) + abs(at(x, y-1)-c) + abs(c-at(x, y+1))
+			if y > 0 {
+				energy += cost[y-1][minNeighbor(cost[y-1], x)]
+			}
+			cost[y][x] = energy
+		}
+	}
+
+	x := 0
+	for i, c := range cost[h-1] {
+		if c < cost[h-1][x] {
+			x = i
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		pix[y] = append(pix[y][:x], pix[y][x+1:]...)
+		if y > 0 {
+			x = minNeighbor(cost[y-1], x)
+		}
+	}
+	return pix
+}
+
+// minNeighbor returns whichever of x-1, x and x+1 has the least cost in row.
+func minNeighbor(row []float64, x int) int {
+	best := x
+	if x > 0 && row[x-1] < row[best] {
+		best = x - 1
+	}
+	if x < len(row)-1 && row[x+1] < row[best] {
+		best = x + 1
+	}
+	return best
+}
+
+func transpose(pix [][]color.NRGBA) [][]color.NRGBA {
+	h, w := len(pix), len(pix[0])
+	t := make([][]color.NRGBA, w)
+	for x := range t {
+		t[x] = make([]color.NRGBA, h)
+		for y := range t[x] {
+			t[x][y] = pix[y][x]
+		}
+	}
+	return t
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
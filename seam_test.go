@@ -0,0 +1,79 @@
+package dotmatrix_test
+
+import (
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+// diagonal returns a white image with a black line running down and to the right
+// from (x, 0), one pixel per row for as long as it fits.
+func diagonal(w, h, x int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < h; y++ {
+		img.SetGray(x+y, y, color.Gray{0})
+	}
+	return img
+}
+
+// blackIn returns the columns of each row of img that are black.
+func blackIn(img image.Image) [][]int {
+	bounds := img.Bounds()
+	rows := make([][]int, bounds.Dy())
+	for y := range rows {
+		for x := 0; x < bounds.Dx(); x++ {
+			if r, _, _, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA(); r == 0 {
+				rows[y] = append(rows[y], x)
+			}
+		}
+	}
+	return rows
+}
+
+var _ = Describe("SeamCarve", func() {
+	It("should carve images down to the size asked for", func() {
+		carved := dotmatrix.SeamCarve(diagonal(12, 8, 2), 7, 5)
+		Expect(carved.Bounds()).To(Equal(image.Rect(0, 0, 7, 5)))
+	})
+
+	It("should remove the featureless seams and keep the detail", func() {
+		// The line is the only detail, so the seams carved out run through the white
+		// beside it, leftmost first, and every row keeps its black pixel.
+		carved := dotmatrix.SeamCarve(diagonal(8, 4, 2), 5, 0)
+		Expect(carved.Bounds()).To(Equal(image.Rect(0, 0, 5, 4)))
+		Expect(blackIn(carved)).To(Equal([][]int{{0}, {1}, {2}, {3}}))
+
+		// Horizontal seams are carved the same way, from the white beneath a line
+		// across the top four rows.
+		carved = dotmatrix.SeamCarve(diagonal(4, 8, 0), 0, 5)
+		Expect(carved.Bounds()).To(Equal(image.Rect(0, 0, 4, 5)))
+		var cols []int
+		for _, row := range blackIn(carved) {
+			cols = append(cols, row...)
+		}
+		Expect(cols).To(ConsistOf(0, 1, 2, 3))
+	})
+
+	It("should leave dimensions that are zero, negative or already small enough", func() {
+		img := diagonal(6, 4, 1)
+		for _, size := range [][2]int{{0, 0}, {-1, -1}, {6, 4}, {10, 10}} {
+			carved := dotmatrix.SeamCarve(img, size[0], size[1])
+			Expect(carved.Bounds()).To(Equal(img.Bounds()))
+			Expect(blackIn(carved)).To(Equal(blackIn(img)))
+		}
+	})
+
+	It("should move the result's bounds to the origin", func() {
+		img := diagonal(6, 4, 1).SubImage(image.Rect(1, 1, 6, 4))
+		carved := dotmatrix.SeamCarve(img, 0, 0)
+		Expect(carved.Bounds()).To(Equal(image.Rect(0, 0, 5, 3)))
+		Expect(blackIn(carved)).To(Equal(blackIn(img)))
+	})
+})
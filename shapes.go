@@ -0,0 +1,125 @@
+package dotmatrix
+
+import "image"
+
+// Line raises the dots of a line from p0 to p1, both included, as Bresenham's
+// algorithm rasterizes it.
+func (c *Canvas) Line(p0, p1 image.Point) {
+	dx, dy := absInt(p1.X-p0.X), -absInt(p1.Y-p0.Y)
+	sx, sy := sign(p1.X-p0.X), sign(p1.Y-p0.Y)
+	x, y := p0.X, p0.Y
+	for e := dx + dy; ; {
+		c.Set(x, y)
+		if x == p1.X && y == p1.Y {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y += sy
+		}
+	}
+}
+
+// Rect raises the dots around the edge of r. As with image.Rectangle, r.Max is just
+// outside it.
+func (c *Canvas) Rect(r image.Rectangle) {
+	r = r.Canon()
+	if r.Empty() {
+		return
+	}
+	max := r.Max.Sub(image.Pt(1, 1))
+	c.Polygon(r.Min, image.Pt(max.X, r.Min.Y), max, image.Pt(r.Min.X, max.Y))
+}
+
+// FillRect raises every dot within r.
+func (c *Canvas) FillRect(r image.Rectangle) {
+	r = r.Canon().Intersect(c.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.Set(x, y)
+		}
+	}
+}
+
+// Circle raises the dots of a circle of radius r around center.
+func (c *Canvas) Circle(center image.Point, r int) {
+	c.Ellipse(center, r, r)
+}
+
+// Ellipse raises the dots of an ellipse around center, with radii rx across and
+// ry down, as the midpoint algorithm rasterizes it.
+func (c *Canvas) Ellipse(center image.Point, rx, ry int) {
+	if rx < 0 || ry < 0 {
+		return
+	}
+	if ry == 0 {
+		c.Line(center.Sub(image.Pt(rx, 0)), center.Add(image.Pt(rx, 0)))
+		return
+	}
+	plot := func(x, y int) {
+		c.Set(center.X+x, center.Y+y)
+		c.Set(center.X-x, center.Y+y)
+		c.Set(center.X+x, center.Y-y)
+		c.Set(center.X-x, center.Y-y)
+	}
+	rx2, ry2 := rx*rx, ry*ry
+	x, y := 0, ry
+	dx, dy := 0, 2*rx2*y
+	// Where the slope is shallow, step across, and down whenever the midpoint
+	// between the candidate dots falls outside the ellipse.
+	for d := ry2 - rx2*ry + rx2/4; dx < dy; {
+		plot(x, y)
+		x++
+		dx += 2 * ry2
+		if d < 0 {
+			d += dx + ry2
+		} else {
+			y--
+			dy -= 2 * rx2
+			d += dx - dy + ry2
+		}
+	}
+	// Where it is steep, step down, and across whenever the midpoint falls inside.
+	for d := ry2*(x*x+x) + ry2/4 + rx2*(y-1)*(y-1) - rx2*ry2; y >= 0; {
+		plot(x, y)
+		y--
+		dy -= 2 * rx2
+		if d > 0 {
+			d += rx2 - dy
+		} else {
+			x++
+			dx += 2 * ry2
+			d += dx - dy + rx2
+		}
+	}
+}
+
+// Polygon raises the dots of the edges between each point and the next, and
+// between the last and the first.
+func (c *Canvas) Polygon(points ...image.Point) {
+	for i, p := range points {
+		c.Line(p, points[(i+1)%len(points)])
+	}
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
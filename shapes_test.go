@@ -0,0 +1,65 @@
+package dotmatrix_test
+
+import (
+	"image"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Shapes", func() {
+	// raised returns the dots raised on a canvas.
+	raised := func(canvas *dotmatrix.Canvas) []image.Point {
+		var dots []image.Point
+		bounds := canvas.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if canvas.Get(x, y) {
+					dots = append(dots, image.Pt(x, y))
+				}
+			}
+		}
+		return dots
+	}
+
+	It("should rasterize lines and rectangles", func() {
+		canvas := dotmatrix.NewCanvas(8, 8)
+		canvas.Line(image.Pt(4, 2), image.Pt(0, 0))
+		Expect(raised(canvas)).To(Equal([]image.Point{{0, 0}, {1, 0}, {2, 1}, {3, 1}, {4, 2}}))
+
+		canvas.Clear()
+		canvas.Rect(image.Rect(1, 1, 4, 4))
+		Expect(raised(canvas)).To(Equal([]image.Point{
+			{1, 1}, {2, 1}, {3, 1}, {1, 2}, {3, 2}, {1, 3}, {2, 3}, {3, 3},
+		}))
+		canvas.FillRect(image.Rect(1, 1, 4, 4))
+		Expect(raised(canvas)).To(HaveLen(9))
+	})
+
+	It("should rasterize circles and ellipses symmetrically", func() {
+		canvas := dotmatrix.NewCanvas(21, 21)
+		canvas.Circle(image.Pt(10, 10), 8)
+		dots := raised(canvas)
+		for _, dot := range dots {
+			Expect(canvas.Get(20-dot.X, dot.Y)).To(BeTrue())
+			Expect(canvas.Get(dot.Y, dot.X)).To(BeTrue())
+		}
+		Expect(dots).To(ContainElement(image.Pt(10, 2)))
+		Expect(dots).To(ContainElement(image.Pt(18, 10)))
+		Expect(canvas.Get(10, 10)).To(BeFalse())
+
+		canvas.Clear()
+		canvas.Ellipse(image.Pt(10, 10), 6, 0)
+		Expect(raised(canvas)).To(HaveLen(13))
+	})
+
+	It("should close polygons", func() {
+		canvas := dotmatrix.NewCanvas(8, 8)
+		canvas.Polygon(image.Pt(0, 0), image.Pt(6, 0), image.Pt(0, 6))
+		Expect(canvas.Get(3, 3)).To(BeTrue())
+		Expect(canvas.Get(0, 3)).To(BeTrue())
+		Expect(canvas.Get(1, 1)).To(BeFalse())
+	})
+})
@@ -0,0 +1,57 @@
+package dotmatrix
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// SizeSource is where a SizeHint came from.
+type SizeSource int
+
+const (
+	// SizeDefault is a small, but fairly standard, 80x25.
+	SizeDefault SizeSource = iota
+	// SizeFromTerminal is the size of the terminal written to.
+	SizeFromTerminal
+	// SizeFromEnvironment is the size given by the COLUMNS and LINES environment
+	// variables, with the default for either that isn't set.
+	SizeFromEnvironment
+)
+
+// Default size, in cells, of output that isn't written to a terminal.
+const (
+	defaultCols = 80
+	defaultRows = 25
+)
+
+// SizeHint is the size of the display that output is fit to, in cells.
+type SizeHint struct {
+	Cols, Rows int
+	Source     SizeSource
+}
+
+/*
+SizeHintOf returns the size to fit output written to w to. That is the size of
+the terminal, if w is one, or else the size given by the COLUMNS and LINES
+environment variables, if set, or else 80x25. Setting COLUMNS and LINES makes
+scripted use, such as in cron jobs and CI, produce predictable dimensions:
+
+	COLUMNS=100 LINES=40 dotmatrix image.png > image.txt
+*/
+func SizeHintOf(w io.Writer) SizeHint {
+	if f, ok := w.(*os.File); ok {
+		// Some ptys report a size of zero.
+		if cols, rows, err := terminalSize(f); err == nil && cols > 0 && rows > 1 {
+			return SizeHint{Cols: cols, Rows: rows, Source: SizeFromTerminal}
+		}
+	}
+	hint := SizeHint{Cols: defaultCols, Rows: defaultRows, Source: SizeDefault}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		hint.Cols, hint.Source = cols, SizeFromEnvironment
+	}
+	if rows, err := strconv.Atoi(os.Getenv("LINES")); err == nil && rows > 0 {
+		hint.Rows, hint.Source = rows, SizeFromEnvironment
+	}
+	return hint
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package dotmatrix
+
+import (
+	"errors"
+	"os"
+)
+
+// terminalSize fails everywhere terminals can't be measured, such as js/wasm, so
+// output is fit to the size given by the environment, or the default.
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("dotmatrix: terminal size is unsupported")
+}
@@ -0,0 +1,25 @@
+package dotmatrix_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("SizeHintOf", func() {
+	AfterEach(func() {
+		os.Unsetenv("COLUMNS")
+		os.Unsetenv("LINES")
+	})
+
+	It("should fall back to COLUMNS and LINES when not writing to a terminal", func() {
+		os.Setenv("COLUMNS", "100")
+		Expect(dotmatrix.SizeHintOf(ioutil.Discard)).To(Equal(dotmatrix.SizeHint{Cols: 100, Rows: 25, Source: dotmatrix.SizeFromEnvironment}))
+		os.Unsetenv("COLUMNS")
+		Expect(dotmatrix.SizeHintOf(ioutil.Discard)).To(Equal(dotmatrix.SizeHint{Cols: 80, Rows: 25, Source: dotmatrix.SizeDefault}))
+	})
+})
@@ -0,0 +1,16 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package dotmatrix
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// terminalSize returns the size of the terminal f writes to, in cells. It fails
+// if f isn't a terminal.
+func terminalSize(f *os.File) (int, int, error) {
+	return terminal.GetSize(int(f.Fd()))
+}
@@ -0,0 +1,39 @@
+package dotmatrix
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+type coord struct {
+	x, y int16
+}
+
+type smallRect struct {
+	left, top, right, bottom int16
+}
+
+type consoleScreenBufferInfo struct {
+	size              coord
+	cursorPosition    coord
+	attributes        uint16
+	window            smallRect
+	maximumWindowSize coord
+}
+
+// terminalSize returns the size of the console window f writes to, in cells. The
+// console's buffer is usually far taller than its window, so the window is what's
+// measured.
+func terminalSize(f *os.File) (int, int, error) {
+	var info consoleScreenBufferInfo
+	if r, _, err := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, 0, err
+	}
+	return int(info.window.right-info.window.left) + 1, int(info.window.bottom-info.window.top) + 1, nil
+}
@@ -0,0 +1,40 @@
+package dotmatrix
+
+import (
+	"image"
+	"strings"
+)
+
+// Sprint prints img with the given options and returns the result as a string,
+// for embedding braille art in log lines, chat messages or templates.
+func Sprint(img image.Image, opts ...Option) (string, error) {
+	var b strings.Builder
+	p, err := NewPrinterWith(&b, opts...)
+	if err != nil {
+		return "", err
+	}
+	if err := p.Print(img); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+/*
+Art formats an image as braille wherever a fmt.Stringer will do. Eg:
+
+	log.Printf("captured:\n%s", dotmatrix.Art{Image: frame})
+
+Invalid options format as the error they cause instead.
+*/
+type Art struct {
+	Image   image.Image
+	Options []Option
+}
+
+func (a Art) String() string {
+	s, err := Sprint(a.Image, a.Options...)
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
@@ -0,0 +1,27 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Sprint", func() {
+	It("should return what Print writes", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 4, 8), color.Palette{color.Black})
+		var printed bytes.Buffer
+		Expect(dotmatrix.Print(&printed, img)).To(Succeed())
+
+		s, err := dotmatrix.Sprint(img)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(printed.String()))
+		Expect(fmt.Sprint(dotmatrix.Art{Image: img})).To(Equal(printed.String()))
+		Expect(dotmatrix.Art{Image: img, Options: []dotmatrix.Option{dotmatrix.WithDrawer(nil)}}.String()).To(ContainSubstring("nil drawer"))
+	})
+})
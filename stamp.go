@@ -0,0 +1,47 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/draw"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stamp describes what to stamp into a corner of each printed frame, for using
+// dotmatrix as a monitoring display for webcams and capture pipelines. The zero
+// value stamps nothing.
+type Stamp struct {
+	// TimeLayout formats the wall-clock time at which each frame is printed, as for
+	// time.Format. If empty, the time is not stamped.
+	TimeLayout string
+	// Frame stamps the number of frames before this one, e.g. #0, #1, including any
+	// skipped as identical. Looping gifs keep counting up.
+	Frame  bool
+	Corner Corner
+}
+
+// text returns the stamp for the nth frame printed at now, or "" if there is none.
+func (s Stamp) text(n int, now time.Time) string {
+	var parts []string
+	if s.TimeLayout != "" {
+		parts = append(parts, now.Format(s.TimeLayout))
+	}
+	if s.Frame {
+		parts = append(parts, "#"+strconv.Itoa(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// stamp returns a copy of img, which is drawn in the dotmatrix palette, with the
+// stamp for the nth frame drawn into it, along with the region it covers.
+func (s Stamp) stamp(img image.Image, n int) (image.Image, image.Rectangle) {
+	text := s.text(n, time.Now())
+	if text == "" {
+		return img, image.Rectangle{}
+	}
+	bounds := img.Bounds()
+	dst := image.NewPaletted(bounds, defaultPalette)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst, drawText(dst, text, s.Corner)
+}
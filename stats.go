@@ -0,0 +1,110 @@
+package dotmatrix
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// statsWindow is how long playback is measured for each update of its stats.
+const statsWindow = time.Second
+
+// Stats measures how well playback is keeping up. Rates are measured over about
+// the last second, and counts since playback started.
+type Stats struct {
+	// FPS is how many frames per second are being shown.
+	FPS float64
+	// BytesPerSecond is how many bytes per second are being written.
+	BytesPerSecond float64
+	// Frames is how many frames have been shown.
+	Frames int
+	// Skipped is how many frames have been skipped for being late.
+	Skipped int
+	// Dropped is how many frames of a live stream have been dropped for arriving
+	// faster than they could be printed.
+	Dropped int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%.1f fps, %d skipped, %d dropped, %.1f KB/s", s.FPS, s.Skipped, s.Dropped, s.BytesPerSecond/1024)
+}
+
+// statsMeter measures playback as it happens. It is updated by the playing
+// goroutine, and may be read from any other.
+type statsMeter struct {
+	mu    sync.Mutex
+	stats Stats
+	// The frames shown and bytes written since the current window started.
+	start  time.Time
+	frames int
+	bytes  int64
+	// measured is whether a whole window has been measured yet. Until it has, rates
+	// are measured over the window so far.
+	measured bool
+}
+
+// reset starts measuring afresh.
+func (m *statsMeter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = Stats{}
+	m.start, m.frames, m.bytes, m.measured = time.Now(), 0, 0, false
+}
+
+// shown counts a frame shown, and reports whether a window just ended, so that
+// the rates were updated.
+func (m *statsMeter) shown(dropped int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Frames++
+	m.stats.Dropped = dropped
+	m.frames++
+	elapsed := time.Since(m.start)
+	if elapsed < statsWindow {
+		return false
+	}
+	m.measure(elapsed)
+	m.start, m.frames, m.bytes, m.measured = time.Now(), 0, 0, true
+	return true
+}
+
+func (m *statsMeter) skipped() {
+	m.mu.Lock()
+	m.stats.Skipped++
+	m.mu.Unlock()
+}
+
+func (m *statsMeter) wrote(n int) {
+	m.mu.Lock()
+	m.bytes += int64(n)
+	m.mu.Unlock()
+}
+
+func (m *statsMeter) measure(elapsed time.Duration) {
+	m.stats.FPS = float64(m.frames) / elapsed.Seconds()
+	m.stats.BytesPerSecond = float64(m.bytes) / elapsed.Seconds()
+}
+
+func (m *statsMeter) snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.measured {
+		if elapsed := time.Since(m.start); elapsed > 0 {
+			m.measure(elapsed)
+		}
+	}
+	return m.stats
+}
+
+// meteredWriter counts the bytes written through it.
+type meteredWriter struct {
+	w io.Writer
+	m *statsMeter
+}
+
+func (w meteredWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.m.wrote(n)
+	return n, err
+}
@@ -0,0 +1,115 @@
+package dotmatrix
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// textScreen is a minimal virtual terminal for writers that display text somewhere
+// other than a terminal, such as a web page. It understands the cursor movement
+// printers use to animate, and discards other escape sequences.
+type textScreen struct {
+	lines    [][]rune
+	row, col int
+	pending  []byte // an incomplete escape sequence or rune from the last write
+}
+
+func (s *textScreen) Write(b []byte) (int, error) {
+	n := len(b)
+	b = append(s.pending, b...)
+	s.pending = nil
+	for len(b) > 0 {
+		if b[0] == '\033' {
+			size, ok := s.escape(b)
+			if !ok {
+				s.pending = append([]byte(nil), b...)
+				break
+			}
+			b = b[size:]
+			continue
+		}
+		if !utf8.FullRune(b) {
+			s.pending = append([]byte(nil), b...)
+			break
+		}
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		switch r {
+		case '\n':
+			s.row, s.col = s.row+1, 0
+		case '\r':
+			s.col = 0
+		default:
+			s.put(r)
+		}
+	}
+	return n, nil
+}
+
+// put writes r at the cursor, growing the screen as needed.
+func (s *textScreen) put(r rune) {
+	for len(s.lines) <= s.row {
+		s.lines = append(s.lines, nil)
+	}
+	line := s.lines[s.row]
+	for len(line) <= s.col {
+		line = append(line, ' ')
+	}
+	line[s.col] = r
+	s.lines[s.row] = line
+	s.col++
+}
+
+// escape applies the escape sequence at the start of b and returns its length, or
+// false if b ends before the sequence does.
+func (s *textScreen) escape(b []byte) (int, bool) {
+	if len(b) < 2 {
+		return 0, false
+	}
+	if b[1] != '[' {
+		return 2, true
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] < '@' || b[i] > '~' {
+			continue
+		}
+		n, err := strconv.Atoi(string(b[2:i]))
+		if err != nil || n < 1 {
+			n = 1
+		}
+		switch b[i] {
+		case 'A':
+			if s.row -= n; s.row < 0 {
+				s.row = 0
+			}
+		case 'B':
+			s.row += n
+		case 'C':
+			s.col += n
+		case 'D':
+			if s.col -= n; s.col < 0 {
+				s.col = 0
+			}
+		case 'J':
+			if s.row < len(s.lines) {
+				if s.col < len(s.lines[s.row]) {
+					s.lines[s.row] = s.lines[s.row][:s.col]
+				}
+				s.lines = s.lines[:s.row+1]
+			}
+		}
+		return i + 1, true
+	}
+	return 0, false
+}
+
+// String returns the text on the screen.
+func (s *textScreen) String() string {
+	var b strings.Builder
+	for _, line := range s.lines {
+		b.WriteString(string(line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
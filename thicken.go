@@ -0,0 +1,101 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+)
+
+// ThickenFilter preserves thin lines through downscaling, so that circuit diagrams,
+// sheet music and wireframes don't lose strokes when shrunk to terminal resolution.
+// It finds dark lines only a pixel or two wide and thickens them by Radius pixels on
+// each side, leaving larger dark areas as they are. It should be applied before
+// downscaling, with a radius of about half the downscaling factor: e.g. 2 when
+// shrinking to a quarter of the size.
+type ThickenFilter struct {
+	// Radius is the number of pixels to thicken lines by on each side. Values less
+	// than 1 are treated as 1.
+	Radius int
+}
+
+func (f ThickenFilter) Filter(img image.Image) image.Image {
+	radius := f.Radius
+	if radius < 1 {
+		radius = 1
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return img
+	}
+	lum := luminanceMap(img)
+
+	// A morphological opening erases dark strokes narrower than its window while
+	// restoring larger dark areas, so pixels much darker than the opening are lines.
+	opened := minFilter(maxFilter(lum, w, h, 1), w, h, 1)
+	const contrast = 0x40
+	lines := make([]float64, len(lum))
+	for i := range lum {
+		lines[i] = 0xff
+		if opened[i]-lum[i] > contrast {
+			lines[i] = lum[i]
+		}
+	}
+	thick := minFilter(lines, w, h, radius)
+
+	dst := image.NewNRGBA(bounds)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if thick[i] < lum[i] {
+				v := uint8(thick[i])
+				dst.SetNRGBA(bounds.Min.X+x, bounds.Min.Y+y, color.NRGBA{v, v, v, 0xff})
+			} else {
+				dst.Set(bounds.Min.X+x, bounds.Min.Y+y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+	}
+	return dst
+}
+
+// minFilter replaces each value of a w by h grid with the least value within radius
+// of it, in a square window.
+func minFilter(src []float64, w, h, radius int) []float64 {
+	return rankFilter(src, w, h, radius, func(a, b float64) bool { return a < b })
+}
+
+// maxFilter replaces each value of a w by h grid with the greatest value within
+// radius of it, in a square window.
+func maxFilter(src []float64, w, h, radius int) []float64 {
+	return rankFilter(src, w, h, radius, func(a, b float64) bool { return a > b })
+}
+
+// rankFilter keeps the best value within radius of each value, where better reports
+// whether a is better than b. Square windows are separable, so rows and columns are
+// filtered in turn.
+func rankFilter(src []float64, w, h, radius int, better func(a, b float64) bool) []float64 {
+	tmp := make([]float64, len(src))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			best := src[y*w+x]
+			for k := x - radius; k <= x+radius; k++ {
+				if k >= 0 && k < w && better(src[y*w+k], best) {
+					best = src[y*w+k]
+				}
+			}
+			tmp[y*w+x] = best
+		}
+	}
+	dst := make([]float64, len(src))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			best := tmp[y*w+x]
+			for k := y - radius; k <= y+radius; k++ {
+				if k >= 0 && k < h && better(tmp[k*w+x], best) {
+					best = tmp[k*w+x]
+				}
+			}
+			dst[y*w+x] = best
+		}
+	}
+	return dst
+}
@@ -0,0 +1,40 @@
+package dotmatrix
+
+import "bytes"
+
+// eraseLine erases from the cursor to the end of the line.
+const eraseLine = "\033[K"
+
+// trimTrailingCells appends out to dst without the blank braille cells at the end
+// of each line, which print as nothing anyway. Cells printed as blank, besides the
+// blank braille pattern, are trimmed too. If erase is set, trimmed lines end by
+// erasing the rest of the line instead, so that cells printed there before, such
+// as by an earlier frame, don't show through.
+func trimTrailingCells(dst, out []byte, blank rune, erase bool) []byte {
+	blanks := [][]byte{[]byte("⠀")}
+	if blank != 0 {
+		blanks = append(blanks, []byte(string(blank)))
+	}
+	for len(out) > 0 {
+		line := out
+		if i := bytes.IndexByte(out, '\n'); i >= 0 {
+			line = out[:i+1]
+		}
+		out = out[len(line):]
+		// Line endings, such as the CRLFs of telnet, are kept.
+		text := bytes.TrimRight(line, "\r\n")
+		trimmed := text
+		for n := -1; n != len(trimmed); {
+			n = len(trimmed)
+			for _, b := range blanks {
+				trimmed = bytes.TrimSuffix(trimmed, b)
+			}
+		}
+		dst = append(dst, trimmed...)
+		if erase && len(trimmed) < len(text) {
+			dst = append(dst, eraseLine...)
+		}
+		dst = append(dst, line[len(text):]...)
+	}
+	return dst
+}
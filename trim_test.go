@@ -0,0 +1,42 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("TrimTrailingCells", func() {
+	palette := color.Palette{color.White, color.Black}
+
+	It("should stop each row at its last cell with dots", func() {
+		img := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+		img.SetColorIndex(2, 0, 1)
+		var out bytes.Buffer
+		c := &dotmatrix.Config{TrimTrailingCells: true}
+		Expect(dotmatrix.NewPrinter(&out, c).Print(img)).To(Succeed())
+		Expect(out.String()).To(Equal("⠀⠁\n\n"))
+	})
+
+	It("should erase the rest of trimmed rows of animations", func() {
+		giff := &gif.GIF{LoopCount: -1, Config: image.Config{Width: 4, Height: 4}, Delay: []int{0, 0}}
+		for i := 0; i < 2; i++ {
+			img := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+			img.SetColorIndex(2-2*i, 0, 1)
+			giff.Image = append(giff.Image, img)
+		}
+		var out bytes.Buffer
+		c := &dotmatrix.Config{TrimTrailingCells: true, KeepCursor: true}
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)).To(Succeed())
+		Expect(out.String()).To(HavePrefix("⠀⠁\n"))
+		Expect(strings.Count(out.String(), "⠁\033[K\n")).To(Equal(1))
+	})
+})
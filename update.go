@@ -0,0 +1,65 @@
+package dotmatrix
+
+import (
+	"image"
+	"io"
+	"math"
+)
+
+/*
+Update re-renders img, a changed version of the last printed image, but reprints
+only the cells covering dirty, the region of img that changed. This spares TUIs
+that embed live images, such as plots or maps, the cost of reprinting every cell on
+each update. As with Highlight, the cursor must be where Print left it.
+
+If img renders to a different size than the last image, it is reprinted in full.
+*/
+func (p *Printer) Update(img image.Image, dirty image.Rectangle) error {
+	if p.last == nil {
+		return p.Print(img)
+	}
+	src := img.Bounds()
+	next := redraw(img, &p.c)
+	if next.Bounds().Size() != p.last.Bounds().Size() {
+		p.resetCursor()
+		// Erase the last image, in case the new one is smaller.
+		if _, err := io.WriteString(p.w, "\033[J"); err != nil {
+			return err
+		}
+		return p.Print(img)
+	}
+
+	// Map the dirty region onto the rendered image, rounding outwards.
+	dirty = dirty.Intersect(src)
+	if dirty.Empty() {
+		return nil
+	}
+	bounds := next.Bounds()
+	scaleX := float64(bounds.Dx()) / float64(src.Dx())
+	scaleY := float64(bounds.Dy()) / float64(src.Dy())
+	region := image.Rect(
+		bounds.Min.X+int(float64(dirty.Min.X-src.Min.X)*scaleX),
+		bounds.Min.Y+int(float64(dirty.Min.Y-src.Min.Y)*scaleY),
+		bounds.Min.X+int(math.Ceil(float64(dirty.Max.X-src.Min.X)*scaleX)),
+		bounds.Min.Y+int(math.Ceil(float64(dirty.Max.Y-src.Min.Y)*scaleY)),
+	)
+
+	// Only the region is reprinted, so only the region changes on screen.
+	next.Rect = p.last.Rect
+	p.drawRegion(next, region)
+	return p.reflush(region)
+}
+
+// drawRegion copies the cells of src covering region into the last printed image.
+func (p *Printer) drawRegion(src *image.Paletted, region image.Rectangle) {
+	bounds := p.last.Bounds()
+	region = image.Rect(
+		bounds.Min.X+floorDiv(region.Min.X-bounds.Min.X, 2)*2,
+		bounds.Min.Y+floorDiv(region.Min.Y-bounds.Min.Y, 4)*4,
+		bounds.Min.X+(region.Max.X-bounds.Min.X+1)/2*2,
+		bounds.Min.Y+(region.Max.Y-bounds.Min.Y+3)/4*4,
+	).Intersect(bounds)
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		copy(p.last.Pix[p.last.PixOffset(region.Min.X, y):p.last.PixOffset(region.Max.X, y)], src.Pix[src.PixOffset(region.Min.X, y):src.PixOffset(region.Max.X, y)])
+	}
+}
@@ -0,0 +1,68 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Printer.Update", func() {
+	palette := color.Palette{color.White, color.Black}
+
+	It("should reprint only the dirty cells and leave the cursor below the image", func() {
+		var out bytes.Buffer
+		printer := dotmatrix.NewPrinter(&out, nil)
+		Expect(printer.Print(image.NewPaletted(image.Rect(0, 0, 8, 8), palette))).To(Succeed())
+		Expect(out.String()).To(Equal("⠀⠀⠀⠀\n⠀⠀⠀⠀\n"))
+
+		next := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+		next.SetColorIndex(5, 5, 1)
+		// Outside the dirty region, so not reprinted.
+		next.SetColorIndex(0, 0, 1)
+		out.Reset()
+		Expect(printer.Update(next, image.Rect(5, 5, 6, 6))).To(Succeed())
+		// Up to the image, down a row and right two cells, then the cell, whose line
+		// feed leaves the cursor below the image.
+		Expect(out.String()).To(Equal("\033[999D\033[2A\033[1B\033[2C⠐\n"))
+
+		out.Reset()
+		Expect(printer.Update(next, image.Rect(0, 0, 1, 1))).To(Succeed())
+		// The cell is on the first row, so the cursor moves back down past the second.
+		Expect(out.String()).To(Equal("\033[999D\033[2A⠁\n\033[1B"))
+	})
+})
+
+var _ = Describe("PartialFlush", func() {
+	It("should rewrite only changed cells, moving the cursor over the rest", func() {
+		palette := color.Palette{color.White, color.Black}
+		giff := &gif.GIF{
+			Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 12, 8), palette), image.NewPaletted(image.Rect(0, 0, 12, 8), palette)},
+			Delay:    []int{0, 0},
+			Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+			Config:   image.Config{Width: 12, Height: 8},
+		}
+		giff.Image[1].SetColorIndex(0, 0, 1)
+		giff.Image[1].SetColorIndex(3, 4, 1)
+		giff.Image[1].SetColorIndex(11, 7, 1)
+
+		var out bytes.Buffer
+		c := &dotmatrix.Config{LoopCount: 1, PartialFlush: true, KeepCursor: true}
+		Expect(dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)).To(Succeed())
+		Expect(out.String()).To(Equal("" +
+			"⠀⠀⠀⠀⠀⠀\n⠀⠀⠀⠀⠀⠀\n\033[999D\033[2A" +
+			// The first cell of the first row.
+			"⠁\n" +
+			// The second cell of the second row, then the sixth, which is too far
+			// along to rewrite the cells between.
+			"\033[1C⠈\n" +
+			"\033[1A\033[5C⢀\n" +
+			"\033[999D\033[2A"))
+	})
+})
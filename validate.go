@@ -0,0 +1,74 @@
+package dotmatrix
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"unicode"
+)
+
+// Validate reports the first setting of the config that can't work, such as a
+// negative ByteBudget, so that mistakes surface when printing is set up instead of
+// as garbled output. Unset settings are valid, as they take their defaults. A nil
+// config is valid too. Printers and players validate their config before they
+// print anything.
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch {
+	case c.ByteBudget < 0:
+		return fmt.Errorf("dotmatrix: ByteBudget %d is negative; 0 means no budget", c.ByteBudget)
+	case c.LoopCount < LoopForever:
+		return fmt.Errorf("dotmatrix: LoopCount %d is negative; use LoopForever to loop forever", c.LoopCount)
+	case c.DecodeAhead < 0:
+		return fmt.Errorf("dotmatrix: DecodeAhead %d is negative", c.DecodeAhead)
+	case c.Interpolate < 0:
+		return fmt.Errorf("dotmatrix: Interpolate %d is negative", c.Interpolate)
+	case c.Alpha.Mode > AlphaThreshold:
+		return fmt.Errorf("dotmatrix: unknown alpha mode %d", c.Alpha.Mode)
+	case c.Stamp.Corner > BottomRight:
+		return fmt.Errorf("dotmatrix: unknown stamp corner %d", c.Stamp.Corner)
+	case c.DropPolicy > DropNone:
+		return fmt.Errorf("dotmatrix: unknown drop policy %d", c.DropPolicy)
+	case c.LineEnding != "" && c.LineEnding != "\n" && c.LineEnding != "\r\n":
+		return fmt.Errorf("dotmatrix: line ending %q is neither LF nor CRLF", c.LineEnding)
+	case c.Blank != 0 && !unicode.IsPrint(c.Blank):
+		return fmt.Errorf("dotmatrix: blank %q isn't printable", c.Blank)
+	}
+	if f, ok := c.Filter.(FitFilter); ok && (f.Cols < 0 || f.Rows < 0 || f.DotAspect < 0) {
+		return fmt.Errorf("dotmatrix: can't fit images to %dx%d cells with a dot aspect of %g", f.Cols, f.Rows, f.DotAspect)
+	}
+	for event, how := range c.Notifications {
+		if how&^(Bell|Flash) != 0 {
+			return fmt.Errorf("dotmatrix: unknown notification %d for event %d", how, event)
+		}
+	}
+	if c.Palette != nil {
+		return checkPalette(c.Palette)
+	}
+	return nil
+}
+
+// checkWriter reports whether w can be printed to. Printers and players check
+// their writer along with their config.
+func checkWriter(w io.Writer) error {
+	if w == nil {
+		return errors.New("dotmatrix: nil writer")
+	}
+	return nil
+}
+
+// checkPalette reports whether p can be drawn in. See Config.Palette.
+func checkPalette(p color.Palette) error {
+	if len(p) < 2 || len(p) > 256 {
+		return fmt.Errorf("dotmatrix: palettes must have 2 to 256 colors, not %d", len(p))
+	}
+	for _, c := range p {
+		if c == color.Black {
+			return nil
+		}
+	}
+	return errors.New("dotmatrix: palettes must include color.Black, which is printed as dots")
+}
@@ -0,0 +1,57 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("Config.Validate", func() {
+	It("should accept defaults", func() {
+		var c *dotmatrix.Config
+		Expect(c.Validate()).To(Succeed())
+		Expect((&dotmatrix.Config{LoopCount: dotmatrix.LoopForever}).Validate()).To(Succeed())
+	})
+
+	It("should stop printers and players before they print anything", func() {
+		c := &dotmatrix.Config{ByteBudget: -1}
+		var out bytes.Buffer
+		err := dotmatrix.NewPrinter(&out, c).Print(image.NewGray(image.Rect(0, 0, 2, 4)))
+		Expect(err).To(MatchError(ContainSubstring("ByteBudget")))
+		giff := &gif.GIF{
+			Image:  []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 4), color.Palette{color.Black})},
+			Delay:  []int{0},
+			Config: image.Config{Width: 2, Height: 4},
+		}
+		err = dotmatrix.NewGIFPrinter(&out, c).Print(context.Background(), giff)
+		Expect(err).To(MatchError(ContainSubstring("ByteBudget")))
+		Expect(out.Len()).To(BeZero())
+
+		_, err = dotmatrix.NewConfig(dotmatrix.WithDrawer(nil))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject nil writers and impossible dimensions", func() {
+		img := image.NewGray(image.Rect(0, 0, 2, 4))
+		Expect(dotmatrix.NewPrinter(nil, nil).Print(img)).To(MatchError(ContainSubstring("nil writer")))
+		Expect(dotmatrix.NewPrinter(nil, &dotmatrix.Config{LineEnding: "\r\n"}).Print(img)).To(MatchError(ContainSubstring("nil writer")))
+		_, err := dotmatrix.NewPrinterWith(nil)
+		Expect(err).To(MatchError(ContainSubstring("nil writer")))
+		giff := &gif.GIF{
+			Image:  []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 4), color.Palette{color.Black})},
+			Delay:  []int{0},
+			Config: image.Config{Width: 2, Height: 4},
+		}
+		Expect(dotmatrix.NewGIFPrinter(nil, nil).Print(context.Background(), giff)).To(MatchError(ContainSubstring("nil writer")))
+
+		c := &dotmatrix.Config{Filter: dotmatrix.FitFilter{Cols: -1, Rows: 10}}
+		Expect(c.Validate()).To(MatchError(ContainSubstring("-1x10")))
+	})
+})
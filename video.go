@@ -0,0 +1,76 @@
+package dotmatrix
+
+import (
+	"context"
+	"image"
+	"io"
+	"time"
+)
+
+// FrameSource is a source of video frames, such as a pipe of raw video from
+// ffmpeg. Sources that are also io.Closers are closed when playback ends.
+type FrameSource interface {
+	// NextFrame returns the next frame, and how long to show it for, or io.EOF once
+	// there are no more. Frames may be reused by the next call. A delay of zero
+	// shows the next frame as soon as it is ready.
+	NextFrame() (image.Image, time.Duration, error)
+}
+
+// VideoPrinter plays frame sources.
+type VideoPrinter struct {
+	w io.Writer
+	c Config
+}
+
+func NewVideoPrinter(w io.Writer, c *Config) *VideoPrinter {
+	return &VideoPrinter{
+		w: w,
+		c: mergeConfig(c),
+	}
+}
+
+// Print plays the frames of src until it runs out. Print can't interrupt a frame
+// being read, so close whatever src reads from to stop promptly on cancellation.
+func (p *VideoPrinter) Print(ctx context.Context, src FrameSource) error {
+	return newPlayer(p.w, &p.c, &videoTrack{c: &p.c, src: src}).Play(ctx)
+}
+
+// NewVideoPlayer provides a Player for src, which plays it as a VideoPrinter
+// would. Frames of video can't be sought.
+func NewVideoPlayer(w io.Writer, c *Config, src FrameSource) *Player {
+	p := NewVideoPrinter(w, c)
+	return newPlayer(p.w, &p.c, &videoTrack{c: &p.c, src: src})
+}
+
+// videoTrack plays the frames of a source in the order they come.
+type videoTrack struct {
+	c   *Config
+	src FrameSource
+
+	blend streamBlender
+	// screen and last are swapped from frame to frame, as in animationTrack.
+	screen, last *image.Paletted
+}
+
+func (t *videoTrack) loops() int {
+	return 1
+}
+
+func (t *videoTrack) frame(ctx context.Context, i int) (image.Image, image.Rectangle, time.Duration, error) {
+	img, delay, err := t.src.NextFrame()
+	if err != nil {
+		return nil, image.Rectangle{}, 0, err
+	}
+	t.blend.n = t.c.Interpolate
+	t.last = redrawInto(t.last, t.blend.next(img), t.c)
+	t.last, t.screen = t.screen, t.last
+	return t.screen, changed(t.last, t.screen), delay, nil
+}
+
+// Close closes the source, if it can be closed.
+func (t *videoTrack) Close() error {
+	if closer, ok := t.src.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// WatermarkFilter composites a second image, such as a logo, into one corner of the
+// image, for branding streams and recordings. The watermark's own transparency is
+// respected. It is drawn at the image's current resolution, one pixel per dot, so
+// the filter should be applied after any resizing and the watermark should be small.
+type WatermarkFilter struct {
+	Image  image.Image
+	Corner Corner
+	// Opacity scales the watermark's alpha, from 0 (invisible) to 1. The zero value
+	// is treated as 1, so watermarks are opaque by default.
+	Opacity float64
+}
+
+func (f WatermarkFilter) Filter(img image.Image) image.Image {
+	if f.Image == nil {
+		return img
+	}
+	opacity := f.Opacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = 1
+	}
+
+	bounds := img.Bounds()
+	mark := f.Image.Bounds()
+	r := f.Corner.place(mark.Sub(mark.Min), bounds)
+
+	dst := image.NewNRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	mask := image.NewUniform(color.Alpha{uint8(opacity * 0xff)})
+	draw.DrawMask(dst, r, f.Image, mark.Min, mask, image.ZP, draw.Over)
+	return dst
+}
@@ -0,0 +1,277 @@
+package dotmatrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// WebP is a WebP image, which may be animated. Still images have a single frame.
+type WebP struct {
+	// Width and Height are the size of the canvas frames are drawn on.
+	Width, Height int
+	// LoopCount is how many times to play the animation, or 0 to loop forever.
+	LoopCount int
+	// Background is the canvas color the file suggests, which players may ignore.
+	// Frames are drawn and disposed of over a transparent canvas.
+	Background color.Color
+	Frames     []WebPFrame
+}
+
+// WebPFrame is a frame of an animated WebP.
+type WebPFrame struct {
+	// Image is the frame, with its bounds where it is drawn on the canvas.
+	Image image.Image
+	Delay time.Duration
+	// Blend draws the frame over the canvas. Otherwise the frame replaces what is
+	// under it, including with transparent pixels.
+	Blend bool
+	// Dispose clears the frame from the canvas once it has been shown.
+	Dispose bool
+}
+
+var errInvalidWebP = errors.New("webp: invalid format")
+
+// DecodeWebP decodes a WebP image, including every frame of an animated one.
+func DecodeWebP(r io.Reader) (*WebP, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidWebP
+	}
+	chunks, err := riffChunks(data[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WebP{LoopCount: 1, Background: color.Transparent}
+	var still []webpChunk // the chunks of a still image
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "VP8X":
+			if len(chunk.data) < 10 {
+				return nil, errInvalidWebP
+			}
+			w.Width, w.Height = int(uint24(chunk.data[4:]))+1, int(uint24(chunk.data[7:]))+1
+		case "ANIM":
+			if len(chunk.data) < 6 {
+				return nil, errInvalidWebP
+			}
+			b := chunk.data
+			w.Background = color.NRGBA{R: b[2], G: b[1], B: b[0], A: b[3]}
+			w.LoopCount = int(binary.LittleEndian.Uint16(b[4:]))
+		case "ANMF":
+			frame, err := decodeWebPFrame(chunk.data)
+			if err != nil {
+				return nil, err
+			}
+			w.Frames = append(w.Frames, frame)
+		case "ALPH", "VP8 ", "VP8L":
+			still = append(still, chunk)
+		}
+		// Metadata, such as ICCP, EXIF and XMP chunks, is ignored.
+	}
+	if len(w.Frames) == 0 {
+		img, err := decodeWebPImage(still, w.Width, w.Height)
+		if err != nil {
+			return nil, err
+		}
+		w.Frames = []WebPFrame{{Image: img}}
+	}
+	if w.Width == 0 || w.Height == 0 {
+		bounds := w.Frames[0].Image.Bounds()
+		w.Width, w.Height = bounds.Max.X, bounds.Max.Y
+	}
+	return w, nil
+}
+
+type webpChunk struct {
+	id   string
+	data []byte
+}
+
+// riffChunks splits data into RIFF chunks, which are padded to even lengths.
+func riffChunks(data []byte) ([]webpChunk, error) {
+	var chunks []webpChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errInvalidWebP
+		}
+		n := binary.LittleEndian.Uint32(data[4:])
+		if uint64(n) > uint64(len(data)-8) {
+			return nil, errInvalidWebP
+		}
+		chunks = append(chunks, webpChunk{string(data[:4]), data[8 : 8+n]})
+		data = data[8+n:]
+		if n%2 == 1 && len(data) > 0 {
+			data = data[1:]
+		}
+	}
+	return chunks, nil
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// decodeWebPFrame decodes the payload of an ANMF chunk: where the frame goes, for
+// how long, and how, followed by the chunks of its image.
+func decodeWebPFrame(data []byte) (WebPFrame, error) {
+	if len(data) < 16 {
+		return WebPFrame{}, errInvalidWebP
+	}
+	x, y := int(uint24(data[0:]))*2, int(uint24(data[3:]))*2
+	width, height := int(uint24(data[6:]))+1, int(uint24(data[9:]))+1
+	flags := data[15]
+	chunks, err := riffChunks(data[16:])
+	if err != nil {
+		return WebPFrame{}, err
+	}
+	img, err := decodeWebPImage(chunks, width, height)
+	if err != nil {
+		return WebPFrame{}, err
+	}
+
+	// Move the frame to its place on the canvas.
+	bounds := img.Bounds()
+	moved := image.NewNRGBA(bounds.Sub(bounds.Min).Add(image.Pt(x, y)))
+	draw.Draw(moved, moved.Rect, img, bounds.Min, draw.Src)
+	return WebPFrame{
+		Image:   moved,
+		Delay:   time.Duration(uint24(data[12:])) * time.Millisecond,
+		Blend:   flags&0x02 == 0,
+		Dispose: flags&0x01 != 0,
+	}, nil
+}
+
+// decodeWebPImage decodes a bitstream chunk, with any alpha chunk before it, by
+// wrapping them in a file of their own for the webp package.
+func decodeWebPImage(chunks []webpChunk, width, height int) (image.Image, error) {
+	var body bytes.Buffer
+	for _, chunk := range chunks {
+		if chunk.id == "ALPH" && (width == 0 || height == 0) {
+			return nil, errInvalidWebP
+		}
+		if chunk.id == "ALPH" {
+			// Alpha is only read after a VP8X chunk announcing it.
+			vp8x := make([]byte, 10)
+			vp8x[0] = 0x10
+			putUint24(vp8x[4:], uint32(width-1))
+			putUint24(vp8x[7:], uint32(height-1))
+			writeRIFFChunk(&body, "VP8X", vp8x)
+		}
+		writeRIFFChunk(&body, chunk.id, chunk.data)
+	}
+	if body.Len() == 0 {
+		return nil, errInvalidWebP
+	}
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+body.Len()))
+	file.WriteString("WEBP")
+	body.WriteTo(&file)
+	return webp.Decode(&file)
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func writeRIFFChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+type WebPPrinter struct {
+	w io.Writer
+	c Config
+}
+
+func NewWebPPrinter(w io.Writer, c *Config) *WebPPrinter {
+	return &WebPPrinter{
+		w: w,
+		c: mergeConfig(c),
+	}
+}
+
+// Print animates an animated WebP, or prints a still one.
+func (p *WebPPrinter) Print(ctx context.Context, w *WebP) error {
+	return newPlayer(p.w, &p.c, newAnimationTrack(&p.c, newWebPCanvas(w))).Play(ctx)
+}
+
+// NewWebPPlayer provides a Player for an animated WebP, which plays it as a
+// WebPPrinter would.
+func NewWebPPlayer(w io.Writer, c *Config, anim *WebP) *Player {
+	p := NewWebPPrinter(w, c)
+	return newPlayer(p.w, &p.c, newAnimationTrack(&p.c, newWebPCanvas(anim)))
+}
+
+// webpCanvas composites the frames of an animated WebP onto its canvas.
+type webpCanvas struct {
+	w *WebP
+
+	rgba     *image.RGBA
+	next     int             // the frame that follows the last one drawn
+	disposed image.Rectangle // cleared before the next frame is drawn
+}
+
+func newWebPCanvas(w *WebP) *webpCanvas {
+	return &webpCanvas{w: w}
+}
+
+func (c *webpCanvas) frames() int {
+	return len(c.w.Frames)
+}
+
+func (c *webpCanvas) delay(i int) time.Duration {
+	return c.w.Frames[i].Delay
+}
+
+func (c *webpCanvas) loops() int {
+	return c.w.LoopCount
+}
+
+func (c *webpCanvas) composite(i int) *image.RGBA {
+	// Frames build on one another, so seeking replays the animation from the start.
+	if c.rgba == nil || (i != c.next && !(i == 0 && c.next == len(c.w.Frames))) {
+		c.rgba = image.NewRGBA(image.Rect(0, 0, c.w.Width, c.w.Height))
+		c.disposed = image.Rectangle{}
+		for j := 0; j < i; j++ {
+			c.draw(j)
+		}
+	}
+	c.draw(i)
+	return c.rgba
+}
+
+// draw disposes of the last frame and draws frame i over the canvas.
+func (c *webpCanvas) draw(i int) {
+	draw.Draw(c.rgba, c.disposed, image.Transparent, image.ZP, draw.Src)
+	frame := c.w.Frames[i]
+	r := frame.Image.Bounds().Intersect(c.rgba.Rect)
+	op := draw.Src
+	if frame.Blend {
+		op = draw.Over
+	}
+	draw.Draw(c.rgba, r, frame.Image, r.Min, op)
+	c.disposed = image.Rectangle{}
+	if frame.Dispose {
+		c.disposed = r
+	}
+	c.next = i + 1
+}
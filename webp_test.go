@@ -0,0 +1,103 @@
+package dotmatrix_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kevin-cantwell/dotmatrix"
+)
+
+var _ = Describe("WebP", func() {
+	black, white := color.NRGBA{0, 0, 0, 0xff}, color.NRGBA{0xff, 0xff, 0xff, 0xff}
+
+	// A black 4x4 frame, and then a white one over its right half.
+	var anim bytes.Buffer
+	anim.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	writeChunk(&anim, "VP8X", []byte{0x02, 0, 0, 0, 3, 0, 0, 3, 0, 0})
+	writeChunk(&anim, "ANIM", []byte{0, 0, 0, 0, 1, 0})
+	writeChunk(&anim, "ANMF", anmf(0, 0, 4, 4, 10, solidVP8L(4, 4, black)))
+	writeChunk(&anim, "ANMF", anmf(2, 0, 2, 4, 10, solidVP8L(2, 4, white)))
+	binary.LittleEndian.PutUint32(anim.Bytes()[4:], uint32(anim.Len()-8))
+
+	It("should decode every frame of an animation", func() {
+		w, err := dotmatrix.DecodeWebP(bytes.NewReader(anim.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Width).To(Equal(4))
+		Expect(w.LoopCount).To(Equal(1))
+		Expect(w.Frames).To(HaveLen(2))
+		Expect(w.Frames[1].Image.Bounds().Min.X).To(Equal(2))
+		Expect(color.NRGBAModel.Convert(w.Frames[1].Image.At(2, 0))).To(Equal(white))
+	})
+
+	It("should play frames over one another", func() {
+		w, err := dotmatrix.DecodeWebP(bytes.NewReader(anim.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		var out bytes.Buffer
+		Expect(dotmatrix.NewWebPPrinter(&out, nil).Print(context.Background(), w)).To(Succeed())
+		Expect(strings.Count(out.String(), "\n")).To(Equal(2))
+		Expect(out.String()).To(ContainSubstring("⣿⠀\n"))
+	})
+})
+
+func writeChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+// anmf returns the payload of an ANMF chunk which blends a VP8L frame over the
+// canvas at x, y.
+func anmf(x, y, w, h, ms int, vp8l []byte) []byte {
+	var b bytes.Buffer
+	for _, v := range []int{x / 2, y / 2, w - 1, h - 1, ms} {
+		b.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16)})
+	}
+	b.WriteByte(0)
+	writeChunk(&b, "VP8L", vp8l)
+	return b.Bytes()
+}
+
+// solidVP8L encodes a w by h image of c as a lossless WebP bitstream. Each prefix
+// code has a single symbol, which takes no bits to write, so there is no pixel
+// data at all.
+func solidVP8L(w, h int, c color.NRGBA) []byte {
+	var bits []bool
+	write := func(v uint32, n int) {
+		for i := 0; i < n; i++ {
+			bits = append(bits, v>>uint(i)&1 != 0)
+		}
+	}
+	write(uint32(w-1), 14)
+	write(uint32(h-1), 14)
+	write(1, 1) // alpha is used
+	write(0, 3) // version
+	write(0, 1) // no transforms
+	write(0, 1) // no color cache
+	write(0, 1) // no meta prefix codes
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		write(1, 1) // simple code
+		write(0, 1) // of one symbol
+		write(1, 1) // of 8 bits
+		write(uint32(symbol), 8)
+	}
+	out := []byte{0x2f}
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8 && i+j < len(bits); j++ {
+			if bits[i+j] {
+				b |= 1 << uint(j)
+			}
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package dotmatrix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+/*
+WeightedDrawer is an alternative to thresholding for undithered output. Rather than
+turning each pixel black or white on its own, it fills the darkest dots of each
+2x4 braille cell, as many as the cell's average darkness calls for. A cell that is
+a quarter dark gets two dots, placed where the cell is darkest. This reduces
+aliasing on smooth gradients and keeps thin lines from breaking up or vanishing.
+
+Cells are aligned to the top left of the drawn rectangle. Pixels that are more than
+half transparent are drawn transparent, and don't count towards the cell.
+*/
+type WeightedDrawer struct{}
+
+func (WeightedDrawer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	r = r.Intersect(dst.Bounds())
+	_ = ForEachCell(r, 2, 4, func(_, _ int, cell image.Rectangle) error {
+		var (
+			points   [8]image.Point
+			darkness [8]float64
+			n        int
+			total    float64
+		)
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			for x := cell.Min.X; x < cell.Max.X; x++ {
+				c := src.At(sp.X+x-r.Min.X, sp.Y+y-r.Min.Y)
+				if _, _, _, a := c.RGBA(); a < 0x8000 {
+					dst.Set(x, y, color.Transparent)
+					continue
+				}
+				// Insert in order of darkness, darkest first.
+				d := 1 - float64(luminance(c))/0xff
+				i := n
+				for ; i > 0 && darkness[i-1] < d; i-- {
+					points[i], darkness[i] = points[i-1], darkness[i-1]
+				}
+				points[i], darkness[i] = image.Pt(x, y), d
+				n++
+				total += d
+			}
+		}
+		dots := int(math.Floor(total + 0.5))
+		for i := 0; i < n; i++ {
+			if i < dots {
+				dst.Set(points[i].X, points[i].Y, color.Black)
+			} else {
+				dst.Set(points[i].X, points[i].Y, color.White)
+			}
+		}
+		return nil
+	})
+}
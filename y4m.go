@@ -0,0 +1,132 @@
+package dotmatrix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"time"
+)
+
+// y4mMagic begins every YUV4MPEG2 stream.
+const y4mMagic = "YUV4MPEG2"
+
+var errInvalidY4M = errors.New("dotmatrix: invalid y4m stream")
+
+/*
+Y4MSource reads YUV4MPEG2 video, which carries its size, frame rate and frame
+boundaries in-band, such as ffmpeg writes with "-f yuv4mpegpipe":
+
+	ffmpeg -i video.mp4 -f yuv4mpegpipe - | dotmatrix
+
+Frames are played at the stream's frame rate. The 420, 422, 444 and mono
+colorspaces are supported; interlacing and pixel aspect ratios are ignored.
+*/
+type Y4MSource struct {
+	r      *bufio.Reader
+	width  int
+	height int
+	delay  time.Duration
+	ratio  image.YCbCrSubsampleRatio
+	mono   bool
+
+	img image.Image
+}
+
+// NewY4MSource reads the stream header from r, and returns a Y4MSource that reads
+// the frames after it.
+func NewY4MSource(r io.Reader) (*Y4MSource, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errInvalidY4M
+	}
+	fields := bytes.Fields([]byte(header))
+	if len(fields) == 0 || string(fields[0]) != y4mMagic {
+		return nil, errInvalidY4M
+	}
+
+	s := &Y4MSource{r: br, ratio: image.YCbCrSubsampleRatio420}
+	for _, field := range fields[1:] {
+		value := string(field[1:])
+		switch field[0] {
+		case 'W':
+			s.width, err = strconv.Atoi(value)
+		case 'H':
+			s.height, err = strconv.Atoi(value)
+		case 'F':
+			var num, den int
+			if _, err = fmt.Sscanf(value, "%d:%d", &num, &den); err == nil && num > 0 && den > 0 {
+				s.delay = time.Duration(den) * time.Second / time.Duration(num)
+			}
+		case 'C':
+			switch {
+			case value == "mono":
+				s.mono = true
+			case len(value) >= 3 && value[:3] == "420":
+				s.ratio = image.YCbCrSubsampleRatio420
+			case value == "422":
+				s.ratio = image.YCbCrSubsampleRatio422
+			case value == "444":
+				s.ratio = image.YCbCrSubsampleRatio444
+			default:
+				return nil, fmt.Errorf("dotmatrix: unsupported y4m colorspace: %q", value)
+			}
+		}
+		if err != nil {
+			return nil, errInvalidY4M
+		}
+	}
+	if s.width <= 0 || s.height <= 0 {
+		return nil, errInvalidY4M
+	}
+	return s, nil
+}
+
+// NextFrame reads the next frame. A frame cut short by the end of the stream is
+// dropped.
+func (s *Y4MSource) NextFrame() (image.Image, time.Duration, error) {
+	// Each frame begins with a line of its own, of FRAME and any parameters.
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+	if len(line) < 5 || line[:5] != "FRAME" {
+		return nil, 0, errInvalidY4M
+	}
+
+	for _, plane := range s.planes() {
+		if _, err := io.ReadFull(s.r, plane); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, 0, err
+		}
+	}
+	return s.img, s.delay, nil
+}
+
+// planes returns the planes of the frame's image to read into, in the order they
+// are stored.
+func (s *Y4MSource) planes() [][]byte {
+	if s.mono {
+		img, ok := s.img.(*image.Gray)
+		if !ok {
+			img = image.NewGray(image.Rect(0, 0, s.width, s.height))
+			s.img = img
+		}
+		return [][]byte{img.Pix}
+	}
+	img, ok := s.img.(*image.YCbCr)
+	if !ok {
+		img = image.NewYCbCr(image.Rect(0, 0, s.width, s.height), s.ratio)
+		s.img = img
+	}
+	return [][]byte{img.Y, img.Cb, img.Cr}
+}